@@ -1,13 +1,17 @@
 package app
 
 import (
+	"bytes"
+	"context"
 	"face-recognition-svc/gateway/app/config"
 	"face-recognition-svc/gateway/app/connection"
 	"face-recognition-svc/gateway/app/model"
 	"face-recognition-svc/gateway/app/router"
 	"face-recognition-svc/gateway/app/utils"
+	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
@@ -44,13 +48,23 @@ func Start() {
 
 	connection.InitConnection(*cfg)
 	connection.MigrateDatabase(&cfg.DatabaseProfile.Database)
-	router.InitFactory(cfg, connection.Db, connection.Storage, connection.Redis, connection.Mq)
+	router.InitFactory(cfg, connection.Db, connection.Storage, connection.Redis, connection.Bus)
+	router.GetFactory().Worker.Scheduler.Start(context.Background())
+	router.GetFactory().Worker.TrainingResult.Start(context.Background())
+	router.GetFactory().Worker.TrainingDeadLetter.Start(context.Background())
+	router.GetFactory().Worker.TrainingEmbedding.Start(context.Background())
 
 	host := cfg.Listener.Host
 	port := cfg.Listener.Port
 
 	e := echo.New()
 
+	ipExtractor, err := utils.NewIPExtractor(cfg.Listener.TrustedProxies)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to configure trusted proxies")
+	}
+	e.IPExtractor = ipExtractor
+
 	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{
 		LogErrorFunc: utils.LogError,
 	}))
@@ -66,15 +80,32 @@ func Start() {
 			return new(model.JwtCustomClaims)
 		},
 		SigningKey: []byte(cfg.Auth.AccessSecret),
+		// Requests carrying a valid X-Device-Key were already authenticated as a device.
+		Skipper: utils.IsDeviceRequest,
 	}
 
+	// EventSource cannot send headers, so streams also accept the access token in the token query parameter.
+	streamAuth := auth
+	streamAuth.TokenLookup = "header:Authorization:Bearer ,query:token"
+
 	public := e.Group("api")
 	api := public.Group("/service")
+	stream := public.Group("/stream")
 
+	api.Use(router.GetFactory().Middleware.Device.Authenticate())
 	api.Use(echojwt.WithConfig(auth))
 	api.Use(router.GetFactory().Middleware.Auth.IsAuthorized())
 
-	e.Use(middleware.Logger())
+	stream.Use(echojwt.WithConfig(streamAuth))
+	stream.Use(router.GetFactory().Middleware.Auth.IsAuthorized())
+
+	// Logged like middleware.Logger, except that the uri field hides the stream token query parameter.
+	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Format: strings.Replace(middleware.DefaultLoggerConfig.Format, "${uri}", "${custom}", 1),
+		CustomTagFunc: func(c echo.Context, buf *bytes.Buffer) (int, error) {
+			return buf.WriteString(utils.RedactedURI(c.Request().RequestURI))
+		},
+	}))
 	router.InitPublicRoute("", public)
 	router.InitUserRoute("/user", api)
 	router.InitDatasetRoute("/dataset", api)
@@ -83,6 +114,30 @@ func Start() {
 	router.InitFeatureRoute("/feature", api)
 	router.InitParamRoute("/param", api)
 	router.InitInstitutionRoute("/institution", api)
+	router.InitEnrollmentRoute("/enrollment", api)
+	router.InitModelRoute("/model", api)
+	router.InitOutboxRoute("/outbox", api)
+	router.InitTrainingScheduleRoute("/training-schedule", api)
+	router.InitRecognitionRoute("/recognition", api)
+	router.InitDeviceRoute("/device", api)
+	router.InitTrainingEventRoute("/training", stream)
+
+	if cfg.Grpc.Enabled {
+		server, err := router.NewGrpcServer(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to configure the gRPC server")
+		}
+		listener, err := net.Listen("tcp", cfg.Grpc.Address())
+		if err != nil {
+			log.Fatal().Err(err).Str("address", cfg.Grpc.Address()).Msg("Failed to listen for gRPC")
+		}
+		go func() {
+			log.Info().Str("address", cfg.Grpc.Address()).Msg("gRPC server started")
+			if err := server.Serve(listener); err != nil {
+				log.Fatal().Err(err).Msg("gRPC server stopped")
+			}
+		}()
+	}
 
 	e.Logger.Fatal(e.Start(host + ":" + strconv.Itoa(port)))
 }
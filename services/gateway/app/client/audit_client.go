@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceAuditClient interface {
+	InsertAudit(ctx context.Context, tx *gorm.DB, audit *model.AuditLog) error
+}
+
+type AuditClient struct {
+	db *gorm.DB
+}
+
+func NewAuditClient(db *gorm.DB) *AuditClient {
+	return &AuditClient{db: db}
+}
+
+// InsertAudit writes an audit entry, inside tx when given so it commits with the change it describes. Empty
+// strings are stored as NULL.
+func (c *AuditClient) InsertAudit(ctx context.Context, tx *gorm.DB, audit *model.AuditLog) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertAudit")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", audit)
+
+	if tx == nil {
+		tx = c.db
+	}
+
+	metadata := audit.Metadata
+	if metadata == "" {
+		metadata = "{}"
+	}
+
+	query := `
+		INSERT INTO audit_log (id, actor_user_id, institution_id, permission_name, action, entity_type, entity_id, request_id, ip_address,
+			user_agent, metadata, created_at)
+		VALUES (?, CAST(NULLIF(?, '') AS UUID), CAST(? AS UUID), ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), CAST(? AS JSONB), ?)`
+
+	err := tx.Debug().WithContext(ctx).Exec(query,
+		audit.ID,
+		stringValue(audit.ActorUserID),
+		audit.InstitutionID,
+		audit.PermissionName,
+		audit.Action,
+		audit.EntityType,
+		audit.EntityID,
+		audit.RequestID,
+		audit.IPAddress,
+		audit.UserAgent,
+		metadata,
+		audit.CreatedAt,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -3,33 +3,58 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/connection"
 	"face-recognition-svc/gateway/app/model"
 	"face-recognition-svc/gateway/app/utils"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
-	amqp "github.com/rabbitmq/amqp091-go"
-
 	"gorm.io/gorm"
 )
 
 type InterfaceDatasetClient interface {
 	GetDatasetList(ctx context.Context, user string) ([]*model.Dataset, error)
+	GetInstitutionDatasets(ctx context.Context, institutionID string) ([]*model.Dataset, error)
+	TouchDataset(ctx context.Context, tx *gorm.DB, username string) error
+	GetStaleDatasets(ctx context.Context) ([]*model.Dataset, error)
 	TrainModel(ctx context.Context, request *model.RequestAPITrainModel) (res *model.ResponseAPITrainModel, err error)
 	GetLastTrainModel(ctx context.Context, institutionID string) (string, error)
 	GetModelTrainingHistory(ctx context.Context, req *model.FilterModelTraining) ([]*model.ModelTraining, error)
 	InsertTrainedModel(ctx context.Context, req *model.ModelTraining, tx *gorm.DB) error
+	GetMaxFencingToken(ctx context.Context, institutionID string) (int64, error)
+	GetTrainingByID(ctx context.Context, id string) (*model.ModelTraining, error)
+	UpdateTrainingStatus(ctx context.Context, tx *gorm.DB, id string, status string, fromStatuses []string, updatedBy string) (int64, error)
+	UpdateTrainingResult(ctx context.Context, tx *gorm.DB, result *model.TrainModelResult, fromStatuses []string) (int64, error)
+	PublishTrainingCancel(ctx context.Context, request *model.TrainModelCancel) error
+	PublishTrainingRetry(ctx context.Context, payload []byte, attempt int) error
+	ResetTrainingForReplay(ctx context.Context, tx *gorm.DB, id string, fencingToken int64, updatedBy string) (int64, error)
+	GetLastSucceededTraining(ctx context.Context, institutionID string) (*model.ModelTraining, error)
+	CountNewDatasetUsers(ctx context.Context, institutionID string, since time.Time) (int64, error)
+	InsertTrainingManifest(ctx context.Context, tx *gorm.DB, manifest *model.TrainingManifest) error
+	GetTrainingManifest(ctx context.Context, trainingID string) (*model.TrainingManifest, error)
+	GetLatestTrainingManifest(ctx context.Context, institutionID string) (*model.TrainingManifest, error)
+	GetUnfinishedTrainings(ctx context.Context, institutionID string) ([]*model.ModelTraining, error)
+	CountInFlightTrainings(ctx context.Context, tx *gorm.DB) (int64, error)
+	CountInFlightTrainingsByInstitution(ctx context.Context, tx *gorm.DB, institutionIDs []string) (map[string]int64, error)
+	GetStaleTrainings(ctx context.Context, updatedBefore time.Time) ([]*model.ModelTraining, error)
+	MarkTrainingDispatched(ctx context.Context, tx *gorm.DB, id string) error
+	ClearTrainingDispatched(ctx context.Context, tx *gorm.DB, id string) error
+	GetQueuePosition(ctx context.Context, training *model.ModelTraining) (int, error)
 }
 
+const trainModelCancelExchange = "TrainModelCancel"
+
 type DatasetClient struct {
 	db  *gorm.DB
 	cfg *config.Config
-	mq  *amqp.Channel
+	mq  connection.InterfaceMessageBus
 }
 
-func NewDatasetClient(db *gorm.DB, cfg *config.Config, mq *amqp.Channel) *DatasetClient {
+func NewDatasetClient(db *gorm.DB, cfg *config.Config, mq connection.InterfaceMessageBus) *DatasetClient {
 	return &DatasetClient{
 		db:  db,
 		cfg: cfg,
@@ -63,43 +88,93 @@ func (c *DatasetClient) GetDatasetList(ctx context.Context, user string) ([]*mod
 	return result, nil
 }
 
+// GetInstitutionDatasets returns the dataset rows of every user of the institution with their last upload time.
+func (c *DatasetClient) GetInstitutionDatasets(ctx context.Context, institutionID string) ([]*model.Dataset, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetInstitutionDatasets")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var result []*model.Dataset
+
+	query := "SELECT username, dataset, created_at, updated_at FROM face_datasets WHERE dataset LIKE ?"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, institutionID+"/%").Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(result))
+
+	return result, nil
+}
+
+// TouchDataset records an upload to an existing dataset, so the next training manifest lists the user's images again.
+func (c *DatasetClient) TouchDataset(ctx context.Context, tx *gorm.DB, username string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: TouchDataset")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE face_datasets SET updated_at = NOW() WHERE username = ?", username).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetStaleDatasets returns dataset rows whose user no longer exists or no longer belongs to the dataset's institution.
+func (c *DatasetClient) GetStaleDatasets(ctx context.Context) ([]*model.Dataset, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetStaleDatasets")
+	defer span.Finish()
+
+	var result []*model.Dataset
+
+	query := `
+		SELECT fd.username, fd.dataset, fd.created_at
+		FROM face_datasets fd
+		LEFT JOIN "user" u ON u.username = fd.username
+		LEFT JOIN user_institution ui ON ui.user_id = u.id AND ui.institution_id::text = split_part(fd.dataset, '/', 1)
+		WHERE u.id IS NULL OR ui.id IS NULL`
+
+	err := c.db.Debug().WithContext(ctx).Raw(query).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return result, nil
+}
+
 func (d *DatasetClient) TrainModel(ctx context.Context, request *model.RequestAPITrainModel) (res *model.ResponseAPITrainModel, err error) {
 	span, ctx := utils.SpanFromContext(ctx, "Client: Processing TrainModel")
 	defer span.Finish()
 
 	utils.LogEvent(span, "Request", request)
 
-	// Declare a queue
-	_, err = d.mq.QueueDeclare(
-		"TrainModel", // Queue name
-		true,         // Durable
-		true,         // Delete when unused
-		false,        // Exclusive
-		false,        // No-wait
-		nil,          // Arguments
-	)
-
+	err = d.declareTrainingTopology(ctx)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
 	}
 
-	messageJSON, err := json.Marshal(request)
+	message, err := utils.NewMessage(ctx, request.Type, request.Prefix, request)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
 	}
+	message.Priority = uint8(request.Priority)
 
 	err = d.mq.Publish(
-		"",           // Exchange (default)
-		"TrainModel", // Routing key (queue name)
-		false,        // Mandatory
-		false,        // Immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			Body:         messageJSON,
-			DeliveryMode: amqp.Persistent, // Make message persistent
-		},
+		ctx,
+		"",                         // Exchange (default)
+		d.cfg.Training.QueueName(), // Routing key (queue name)
+		message,
 	)
 	if err != nil {
 		utils.LogEventError(span, err)
@@ -146,6 +221,19 @@ func (d *DatasetClient) GetLastTrainModel(ctx context.Context, institutionID str
 	return res, nil
 }
 
+// modelTrainingHistoryOrder maps the sortable order_by values of the training history to their columns.
+var modelTrainingHistoryOrder = map[string]string{
+	"created_at":          "mt.created_at",
+	"finished_at":         "mt.finished_at",
+	"version":             "mt.version",
+	"status":              "mt.status",
+	"identities":          "m.identities",
+	"images":              "m.images",
+	"validation_accuracy": "m.validation_accuracy",
+	"duration_seconds":    "m.duration_seconds",
+	"artifact_size_bytes": "m.artifact_size_bytes",
+}
+
 func (d *DatasetClient) GetModelTrainingHistory(ctx context.Context, req *model.FilterModelTraining) ([]*model.ModelTraining, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Client: GetModelTrainingHistory")
 	defer span.Finish()
@@ -154,26 +242,64 @@ func (d *DatasetClient) GetModelTrainingHistory(ctx context.Context, req *model.
 
 	var res []*model.ModelTraining
 
-	sb := strings.Builder{}
+	var conditions []string
+	var args []interface{}
 	if req.InstitutionID != "" {
-		sb.WriteString(fmt.Sprintf(" WHERE institution_id = '%s'", req.InstitutionID))
-		if req.Status != "" {
-			sb.WriteString(fmt.Sprintf(" AND status = '%s'", req.Status))
-		}
-		if req.IsUsed != "" {
-			sb.WriteString(fmt.Sprintf(" AND is_used = '%s'", req.IsUsed))
-		}
+		conditions = append(conditions, "mt.institution_id = ?")
+		args = append(args, req.InstitutionID)
+	}
+	if req.Status != "" {
+		conditions = append(conditions, "mt.status = ?")
+		args = append(args, req.Status)
+	}
+	if req.IsUsed != "" {
+		conditions = append(conditions, "mt.is_used = ?")
+		args = append(args, req.IsUsed)
+	}
+	if req.MinValidationAccuracy != nil {
+		conditions = append(conditions, "m.validation_accuracy >= ?")
+		args = append(args, *req.MinValidationAccuracy)
+	}
+	if req.MinIdentities != nil {
+		conditions = append(conditions, "m.identities >= ?")
+		args = append(args, *req.MinIdentities)
+	}
+	if req.MinImages != nil {
+		conditions = append(conditions, "m.images >= ?")
+		args = append(args, *req.MinImages)
+	}
+	if req.MaxDurationSeconds != nil {
+		conditions = append(conditions, "m.duration_seconds <= ?")
+		args = append(args, *req.MaxDurationSeconds)
+	}
+	if req.MaxArtifactSizeBytes != nil {
+		conditions = append(conditions, "m.artifact_size_bytes <= ?")
+		args = append(args, *req.MaxArtifactSizeBytes)
+	}
+
+	sb := strings.Builder{}
+	sb.WriteString("SELECT mt.* FROM model_training mt LEFT JOIN model_training_metric m ON m.model_training_id = mt.id")
+	if len(conditions) > 0 {
+		sb.WriteString(" WHERE ")
+		sb.WriteString(strings.Join(conditions, " AND "))
 	}
 
 	if req.OrderBy != "" {
-		sb.WriteString(fmt.Sprintf(" ORDER BY %s %s", req.OrderBy, req.SortType))
+		column, ok := modelTrainingHistoryOrder[req.OrderBy]
+		if !ok {
+			utils.LogEventError(span, errors.New("invalid order_by"))
+			return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("cannot order training history by %q", req.OrderBy))
+		}
+		sortType := "DESC"
+		if strings.EqualFold(req.SortType, "ASC") {
+			sortType = "ASC"
+		}
+		sb.WriteString(fmt.Sprintf(" ORDER BY %s %s NULLS LAST, mt.created_at DESC", column, sortType))
 	} else {
-		sb.WriteString(" ORDER BY created_at DESC")
+		sb.WriteString(" ORDER BY mt.created_at DESC")
 	}
 
-	query := "SELECT * FROM model_training"
-
-	err := d.db.Debug().Raw(query + sb.String()).Scan(&res).Error
+	err := d.db.Debug().WithContext(ctx).Raw(sb.String(), args...).Scan(&res).Error
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
@@ -184,14 +310,67 @@ func (d *DatasetClient) GetModelTrainingHistory(ctx context.Context, req *model.
 	return res, nil
 }
 
+// GetMaxFencingToken returns the highest fencing token stored for the institution's trainings, or 0 when none has
+// one. Deleted trainings count too, since the InsertTrainedModel guard does not skip them.
+func (d *DatasetClient) GetMaxFencingToken(ctx context.Context, institutionID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetMaxFencingToken")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var res int64
+
+	query := "SELECT COALESCE(MAX(fencing_token), 0) FROM model_training WHERE institution_id = ?"
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
 func (d *DatasetClient) InsertTrainedModel(ctx context.Context, req *model.ModelTraining, tx *gorm.DB) error {
 	span, ctx := utils.SpanFromContext(ctx, "Client: InsertTrainedModel")
 	defer span.Finish()
 
+	// Inserts of one institution are serialized for the rest of the transaction, so the fencing token guard and the
+	// next version cannot race another insert; uq_model_training_version backs the version up.
+	err := tx.Debug().WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "model_training:"+req.InstitutionID).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// The fencing token guard rejects a holder whose training lock expired and was taken over by a newer request.
+	if req.FencingToken != nil {
+		var newer int64
+		err := tx.Debug().Raw("SELECT COUNT(1) FROM model_training WHERE institution_id = ? AND fencing_token >= ?", req.InstitutionID, *req.FencingToken).Scan(&newer).Error
+		if err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+
+		if newer > 0 {
+			utils.LogEventError(span, errors.New("stale training lock"))
+			return model.ThrowError(http.StatusConflict, errors.New("training lock expired, please retry"))
+		}
+	}
+
 	var args []interface{}
 
-	args = append(args, req.ID, req.InstitutionID, req.Status, time.Now(), req.CreatedBy)
-	query := "INSERT INTO model_training (id, institution_id, status, created_at, created_by) VALUES (?, ?, ?, ?, ?)"
+	mode := req.Mode
+	if mode == "" {
+		mode = model.TrainingModeFull
+	}
+
+	args = append(args, req.ID, req.InstitutionID, req.Status, time.Now(), req.CreatedBy, req.FencingToken, mode, req.BaseModelID, req.Priority, req.InstitutionID)
+	query := `
+		INSERT INTO model_training (id, institution_id, status, created_at, created_by, fencing_token, training_mode, base_model_id, priority, version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT COALESCE(MAX(version), 0) + 1 FROM model_training WHERE institution_id = ?))`
 	result := tx.Debug().Exec(query, args...)
 
 	if result.Error != nil {
@@ -201,3 +380,528 @@ func (d *DatasetClient) InsertTrainedModel(ctx context.Context, req *model.Model
 
 	return nil
 }
+
+func (d *DatasetClient) GetTrainingByID(ctx context.Context, id string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetTrainingByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var res []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE id = ? AND deleted_at IS NULL"
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, id).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("training not found"))
+	}
+
+	utils.LogEvent(span, "Response", res[0])
+
+	return res[0], nil
+}
+
+// UpdateTrainingStatus moves a job to status only while it is still in one of fromStatuses and reports how many rows changed.
+func (d *DatasetClient) UpdateTrainingStatus(ctx context.Context, tx *gorm.DB, id string, status string, fromStatuses []string, updatedBy string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateTrainingStatus")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"id": id, "status": status, "from": fromStatuses})
+
+	query := `
+		UPDATE model_training
+		SET status = ?, updated_at = NOW(), updated_by = ?,
+			finished_at = CASE WHEN ? IN ('SUCCEEDED', 'FAILED', 'CANCELLED') THEN NOW() ELSE finished_at END
+		WHERE id = ? AND status IN ?`
+
+	result := tx.Debug().WithContext(ctx).Exec(query, status, updatedBy, status, id, fromStatuses)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (d *DatasetClient) UpdateTrainingResult(ctx context.Context, tx *gorm.DB, result *model.TrainModelResult, fromStatuses []string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateTrainingResult")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", result)
+
+	var metadata interface{}
+	if len(result.Metadata) > 0 {
+		metadata = string(result.Metadata)
+	}
+
+	query := `
+		UPDATE model_training
+		SET status = ?, status_message = ?, updated_at = NOW(), updated_by = 'system',
+			model_path = COALESCE(?, model_path),
+			metadata = COALESCE(CAST(? AS JSONB), metadata),
+			progress = CASE WHEN ? = 'SUCCEEDED' THEN 100 ELSE COALESCE(CAST(? AS NUMERIC), progress) END,
+			started_at = CASE WHEN ? = 'RUNNING' THEN COALESCE(started_at, NOW()) ELSE started_at END,
+			finished_at = CASE WHEN ? IN ('SUCCEEDED', 'FAILED', 'CANCELLED') THEN NOW() ELSE finished_at END
+		WHERE id = ? AND status IN ?`
+
+	res := tx.Debug().WithContext(ctx).Exec(query,
+		result.Status,
+		result.Message,
+		result.ModelPath,
+		metadata,
+		result.Status,
+		result.Progress,
+		result.Status,
+		result.Status,
+		result.ID,
+		fromStatuses,
+	)
+	if res.Error != nil {
+		utils.LogEventError(span, res.Error)
+		return 0, res.Error
+	}
+
+	return res.RowsAffected, nil
+}
+
+// PublishTrainingCancel broadcasts the cancel so whichever worker holds, or later dequeues, the job can drop it.
+func (d *DatasetClient) PublishTrainingCancel(ctx context.Context, request *model.TrainModelCancel) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: PublishTrainingCancel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	err := d.mq.Declare(ctx, connection.Topology{
+		Exchanges: []connection.Exchange{{Name: trainModelCancelExchange, Kind: connection.ExchangeFanout}},
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	message, err := utils.NewMessage(ctx, model.TrainModelCancelMessage, request.InstitutionID, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// Not routable means no worker is listening for cancellations, which is not an error for the caller.
+	err = d.mq.Publish(
+		ctx,
+		trainModelCancelExchange, // Exchange
+		"",                       // Routing key (ignored by fanout)
+		message,
+	)
+	if err != nil && !errors.Is(err, connection.ErrMessageReturned) {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// PublishTrainingRetry parks a rejected training message in the retry queue for attempt; the queue's TTL
+// dead-letters it back to the main queue once the delay has passed.
+func (d *DatasetClient) PublishTrainingRetry(ctx context.Context, payload []byte, attempt int) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: PublishTrainingRetry")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", attempt)
+
+	err := d.declareTrainingTopology(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// The retry queue dead-letters the message back to the training queue with its properties, so keep its priority.
+	var request model.RequestAPITrainModel
+	err = json.Unmarshal(payload, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	message, err := utils.NewMessage(ctx, request.Type, request.Prefix, json.RawMessage(payload))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+	message.Priority = uint8(request.Priority)
+	message.Headers[model.TrainModelRetryCountHeader] = int32(attempt)
+
+	err = d.mq.Publish(
+		ctx,
+		"", // Exchange (default)
+		fmt.Sprintf(model.TrainModelRetryQueue, attempt), // Routing key (queue name)
+		message,
+	)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (d *DatasetClient) ResetTrainingForReplay(ctx context.Context, tx *gorm.DB, id string, fencingToken int64, updatedBy string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ResetTrainingForReplay")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	query := `
+		UPDATE model_training
+		SET status = 'STARTED', fencing_token = ?, status_message = NULL, started_at = NULL, finished_at = NULL, dispatched_at = NULL, progress = NULL,
+			updated_at = NOW(), updated_by = ?
+		WHERE id = ? AND status = 'FAILED'`
+
+	result := tx.Debug().WithContext(ctx).Exec(query, fencingToken, updatedBy, id)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// declareTrainingTopology declares the main training queue, one retry queue per configured delay and the dead-letter
+// queue. Only a priority queue gets the dead-letter exchange; the legacy queue is declared the way the processing
+// service declares it. Declarations are idempotent, so it is safe to run before every publish.
+func (d *DatasetClient) declareTrainingTopology(ctx context.Context) error {
+	mainQueue := connection.Queue{Name: d.cfg.Training.QueueName(), AutoDelete: true}
+	if d.cfg.Training.PriorityQueue {
+		mainQueue = connection.Queue{
+			Name:        d.cfg.Training.QueueName(),
+			MaxPriority: config.TrainingQueueMaxPriority,
+			DeadLetter: &connection.DeadLetter{
+				Exchange:   model.TrainModelDeadLetterExchange,
+				RoutingKey: model.TrainModelDeadLetterQueue,
+			},
+		}
+	}
+
+	topology := connection.Topology{
+		Exchanges: []connection.Exchange{
+			{Name: model.TrainModelDeadLetterExchange, Kind: connection.ExchangeDirect},
+		},
+		Queues: []connection.Queue{mainQueue},
+		Bindings: []connection.Binding{
+			{Queue: model.TrainModelDeadLetterQueue, Exchange: model.TrainModelDeadLetterExchange, Key: model.TrainModelDeadLetterQueue},
+		},
+	}
+
+	for i, delay := range d.cfg.Training.RetryDurations() {
+		topology.Queues = append(topology.Queues, connection.Queue{
+			Name:       fmt.Sprintf(model.TrainModelRetryQueue, i+1),
+			MessageTTL: delay,
+			DeadLetter: &connection.DeadLetter{RoutingKey: d.cfg.Training.QueueName()},
+		})
+	}
+
+	topology.Queues = append(topology.Queues, connection.Queue{Name: model.TrainModelDeadLetterQueue})
+
+	return d.mq.Declare(ctx, topology)
+}
+
+// GetLastSucceededTraining returns nil when the institution has no successful training yet.
+func (d *DatasetClient) GetLastSucceededTraining(ctx context.Context, institutionID string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetLastSucceededTraining")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var res []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE institution_id = ? AND status = 'SUCCEEDED' AND deleted_at IS NULL ORDER BY created_at DESC LIMIT 1"
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	return res[0], nil
+}
+
+func (d *DatasetClient) CountNewDatasetUsers(ctx context.Context, institutionID string, since time.Time) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountNewDatasetUsers")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var count int64
+
+	query := "SELECT COUNT(DISTINCT username) FROM face_datasets WHERE dataset LIKE ? AND created_at > ?"
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, institutionID+"/%", since).Scan(&count).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (d *DatasetClient) InsertTrainingManifest(ctx context.Context, tx *gorm.DB, manifest *model.TrainingManifest) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertTrainingManifest")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"model_training_id": manifest.ModelTrainingID, "object_count": manifest.ObjectCount})
+
+	query := "INSERT INTO model_training_manifest (model_training_id, objects, object_count, created_at) VALUES (?, CAST(? AS JSONB), ?, ?)"
+
+	err := tx.Debug().WithContext(ctx).Exec(query, manifest.ModelTrainingID, string(manifest.Objects), manifest.ObjectCount, manifest.CreatedAt).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetTrainingManifest returns nil when the training was queued without a manifest.
+func (d *DatasetClient) GetTrainingManifest(ctx context.Context, trainingID string) (*model.TrainingManifest, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetTrainingManifest")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", trainingID)
+
+	var res []*model.TrainingManifest
+
+	err := d.db.Debug().WithContext(ctx).Raw("SELECT * FROM model_training_manifest WHERE model_training_id = ?", trainingID).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	return res[0], nil
+}
+
+// GetLatestTrainingManifest returns the institution's most recent manifest, or nil when no training has one.
+func (d *DatasetClient) GetLatestTrainingManifest(ctx context.Context, institutionID string) (*model.TrainingManifest, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetLatestTrainingManifest")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var res []*model.TrainingManifest
+
+	query := `
+		SELECT tm.* FROM model_training_manifest tm
+		JOIN model_training mt ON mt.id = tm.model_training_id
+		WHERE mt.institution_id = ?
+		ORDER BY tm.created_at DESC
+		LIMIT 1`
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	return res[0], nil
+}
+
+// GetUnfinishedTrainings returns the institution's trainings that are queued, running or being cancelled.
+func (d *DatasetClient) GetUnfinishedTrainings(ctx context.Context, institutionID string) ([]*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetUnfinishedTrainings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var res []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE institution_id = ? AND status IN ? AND deleted_at IS NULL ORDER BY created_at"
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, institutionID, []string{
+		model.ModelTrainingStatusStarted,
+		model.ModelTrainingStatusRunning,
+		model.ModelTrainingStatusCancelling,
+	}).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// CountInFlightTrainings counts trainings published to the broker that have not finished yet. It holds a
+// transaction-scoped advisory lock so concurrent relays cannot both fill the same free slots.
+func (d *DatasetClient) CountInFlightTrainings(ctx context.Context, tx *gorm.DB) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountInFlightTrainings")
+	defer span.Finish()
+
+	err := tx.Debug().WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "training_dispatch").Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	var count int64
+
+	query := "SELECT COUNT(1) FROM model_training WHERE dispatched_at IS NOT NULL AND status IN ? AND deleted_at IS NULL"
+
+	err = tx.Debug().WithContext(ctx).Raw(query, []string{
+		model.ModelTrainingStatusStarted,
+		model.ModelTrainingStatusRunning,
+		model.ModelTrainingStatusCancelling,
+	}).Scan(&count).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	utils.LogEvent(span, "Response", count)
+
+	return count, nil
+}
+
+// CountInFlightTrainingsByInstitution counts dispatched, unfinished trainings per institution under the same
+// dispatch lock as CountInFlightTrainings. Institutions without any are missing from the map.
+func (d *DatasetClient) CountInFlightTrainingsByInstitution(ctx context.Context, tx *gorm.DB, institutionIDs []string) (map[string]int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountInFlightTrainingsByInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionIDs)
+
+	err := tx.Debug().WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "training_dispatch").Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var rows []struct {
+		InstitutionID string
+		Count         int64
+	}
+
+	query := `
+		SELECT institution_id, COUNT(1) AS count FROM model_training
+		WHERE dispatched_at IS NOT NULL AND status IN ? AND deleted_at IS NULL AND institution_id IN ?
+		GROUP BY institution_id`
+
+	err = tx.Debug().WithContext(ctx).Raw(query, []string{
+		model.ModelTrainingStatusStarted,
+		model.ModelTrainingStatusRunning,
+		model.ModelTrainingStatusCancelling,
+	}, institutionIDs).Scan(&rows).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.InstitutionID] = row.Count
+	}
+
+	utils.LogEvent(span, "Response", counts)
+
+	return counts, nil
+}
+
+// GetStaleTrainings returns unfinished trainings that were neither dispatched nor updated since updatedBefore.
+func (d *DatasetClient) GetStaleTrainings(ctx context.Context, updatedBefore time.Time) ([]*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetStaleTrainings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", updatedBefore)
+
+	var res []*model.ModelTraining
+
+	query := `
+		SELECT * FROM model_training
+		WHERE dispatched_at IS NOT NULL AND status IN ? AND GREATEST(updated_at, dispatched_at) < ? AND deleted_at IS NULL
+		ORDER BY dispatched_at`
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, []string{
+		model.ModelTrainingStatusStarted,
+		model.ModelTrainingStatusRunning,
+		model.ModelTrainingStatusCancelling,
+	}, updatedBefore).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(res))
+
+	return res, nil
+}
+
+func (d *DatasetClient) MarkTrainingDispatched(ctx context.Context, tx *gorm.DB, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: MarkTrainingDispatched")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE model_training SET dispatched_at = NOW() WHERE id = ?", id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// ClearTrainingDispatched frees the in-flight slot of a training whose message could not be published.
+func (d *DatasetClient) ClearTrainingDispatched(ctx context.Context, tx *gorm.DB, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ClearTrainingDispatched")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE model_training SET dispatched_at = NULL WHERE id = ?", id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetQueuePosition returns the 1-based position of a queued training among all queued trainings, ordered the way
+// they are dispatched: higher priority first, then oldest first. Priority aging is not taken into account, so
+// the position is an estimate.
+func (d *DatasetClient) GetQueuePosition(ctx context.Context, training *model.ModelTraining) (int, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetQueuePosition")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", training.ID)
+
+	var ahead int
+
+	query := `
+		SELECT COUNT(1) FROM model_training
+		WHERE status = 'STARTED' AND deleted_at IS NULL AND id <> ?
+			AND (priority > ? OR (priority = ? AND created_at < ?))`
+
+	err := d.db.Debug().WithContext(ctx).Raw(query, training.ID, training.Priority, training.Priority, training.CreatedAt).Scan(&ahead).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	return ahead + 1, nil
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceDeadLetterClient interface {
+	InsertDeadLetter(ctx context.Context, tx *gorm.DB, deadLetter *model.TrainingDeadLetter) error
+	GetDeadLetters(ctx context.Context, institutionID string, status string) ([]*model.TrainingDeadLetter, error)
+	GetDeadLetterByID(ctx context.Context, id string) (*model.TrainingDeadLetter, error)
+	ResolveDeadLetter(ctx context.Context, tx *gorm.DB, id string, status string, resolvedBy string) (int64, error)
+}
+
+type DeadLetterClient struct {
+	db *gorm.DB
+}
+
+func NewDeadLetterClient(db *gorm.DB) *DeadLetterClient {
+	return &DeadLetterClient{db: db}
+}
+
+func (c *DeadLetterClient) InsertDeadLetter(ctx context.Context, tx *gorm.DB, deadLetter *model.TrainingDeadLetter) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertDeadLetter")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", deadLetter)
+
+	query := `
+		INSERT INTO training_dead_letter (id, model_training_id, institution_id, payload, reason, retry_count, status, created_at)
+		VALUES (?, ?, ?, CAST(? AS JSONB), ?, ?, ?, ?)`
+
+	err := tx.Debug().WithContext(ctx).Exec(query,
+		deadLetter.ID,
+		deadLetter.ModelTrainingID,
+		deadLetter.InstitutionID,
+		string(deadLetter.Payload),
+		deadLetter.Reason,
+		deadLetter.RetryCount,
+		deadLetter.Status,
+		deadLetter.CreatedAt,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *DeadLetterClient) GetDeadLetters(ctx context.Context, institutionID string, status string) ([]*model.TrainingDeadLetter, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetDeadLetters")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"institution_id": institutionID, "status": status})
+
+	var result []*model.TrainingDeadLetter
+
+	query := "SELECT * FROM training_dead_letter WHERE 1 = 1"
+	var args []interface{}
+	if institutionID != "" {
+		query += " AND institution_id = ?"
+		args = append(args, institutionID)
+	}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, args...).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(result))
+
+	return result, nil
+}
+
+func (c *DeadLetterClient) GetDeadLetterByID(ctx context.Context, id string) (*model.TrainingDeadLetter, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetDeadLetterByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var result []*model.TrainingDeadLetter
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM training_dead_letter WHERE id = ?", id).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("dead letter not found"))
+	}
+
+	return result[0], nil
+}
+
+// ResolveDeadLetter closes a PENDING dead letter and reports how many rows changed, so concurrent admins cannot
+// both replay the same message.
+func (c *DeadLetterClient) ResolveDeadLetter(ctx context.Context, tx *gorm.DB, id string, status string, resolvedBy string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ResolveDeadLetter")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"id": id, "status": status})
+
+	result := tx.Debug().WithContext(ctx).Exec(
+		"UPDATE training_dead_letter SET status = ?, resolved_at = NOW(), resolved_by = ? WHERE id = ? AND status = 'PENDING'",
+		status, resolvedBy, id,
+	)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
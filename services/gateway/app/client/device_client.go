@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceDeviceClient interface {
+	InsertDevice(ctx context.Context, tx *gorm.DB, device *model.Device) error
+	GetDevices(ctx context.Context, filter *model.FilterDevice, pagination *model.Pagination) ([]*model.Device, *model.Pagination, error)
+	GetDeviceByID(ctx context.Context, id string) (*model.Device, error)
+	GetDeviceByName(ctx context.Context, institutionID string, name string) (*model.Device, error)
+	UpdateDevice(ctx context.Context, tx *gorm.DB, device *model.Device) error
+	UpdateDeviceKey(ctx context.Context, tx *gorm.DB, id string, keyPrefix string, keyHash string, updatedBy string) error
+	UpdateHeartbeat(ctx context.Context, id string, ipAddress string, metadata []byte) error
+	DeleteDevice(ctx context.Context, tx *gorm.DB, id string) (int64, error)
+}
+
+type DeviceClient struct {
+	db *gorm.DB
+}
+
+func NewDeviceClient(db *gorm.DB) *DeviceClient {
+	return &DeviceClient{db: db}
+}
+
+func (c *DeviceClient) InsertDevice(ctx context.Context, tx *gorm.DB, device *model.Device) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertDevice")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", device)
+
+	query := `
+		INSERT INTO device (id, institution_id, name, location, status, key_prefix, key_hash, key_rotated_at, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	err := tx.Debug().WithContext(ctx).Exec(query,
+		device.ID,
+		device.InstitutionID,
+		device.Name,
+		device.Location,
+		device.Status,
+		device.KeyPrefix,
+		device.KeyHash,
+		device.KeyRotatedAt,
+		device.CreatedAt,
+		device.CreatedBy,
+		device.UpdatedAt,
+		device.UpdatedBy,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *DeviceClient) GetDevices(ctx context.Context, filter *model.FilterDevice, pagination *model.Pagination) ([]*model.Device, *model.Pagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetDevices")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	var conditions []string
+	var args []interface{}
+	if filter.InstitutionID != "" {
+		conditions = append(conditions, "institution_id = ?")
+		args = append(args, filter.InstitutionID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var totalCount int64
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT COUNT(*) FROM device"+whereClause, args...).Scan(&totalCount).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	pagination.Total = int(totalCount)
+	pagination.TotalPages = (pagination.Total + pagination.Limit - 1) / pagination.Limit
+
+	res := []*model.Device{}
+
+	query := fmt.Sprintf("SELECT * FROM device%s ORDER BY name LIMIT %d OFFSET %d",
+		whereClause, pagination.Limit, (pagination.Page-1)*pagination.Limit)
+
+	err = c.db.Debug().WithContext(ctx).Raw(query, args...).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	utils.LogEvent(span, "Pagination", pagination)
+
+	return res, pagination, nil
+}
+
+// GetDeviceByID returns nil when the device does not exist.
+func (c *DeviceClient) GetDeviceByID(ctx context.Context, id string) (*model.Device, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetDeviceByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var result []*model.Device
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM device WHERE id = ?", id).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result[0], nil
+}
+
+// GetDeviceByName returns nil when the institution has no device with that name.
+func (c *DeviceClient) GetDeviceByName(ctx context.Context, institutionID string, name string) (*model.Device, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetDeviceByName")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"institution_id": institutionID, "name": name})
+
+	var result []*model.Device
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM device WHERE institution_id = ? AND name = ?", institutionID, name).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result[0], nil
+}
+
+func (c *DeviceClient) UpdateDevice(ctx context.Context, tx *gorm.DB, device *model.Device) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateDevice")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", device)
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE device SET name = ?, location = ?, status = ?, updated_by = ? WHERE id = ?",
+		device.Name,
+		device.Location,
+		device.Status,
+		device.UpdatedBy,
+		device.ID,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDeviceKey replaces the device's key; the previous key stops working immediately.
+func (c *DeviceClient) UpdateDeviceKey(ctx context.Context, tx *gorm.DB, id string, keyPrefix string, keyHash string, updatedBy string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateDeviceKey")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"id": id, "key_prefix": keyPrefix})
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE device SET key_prefix = ?, key_hash = ?, key_rotated_at = ?, updated_by = ? WHERE id = ?",
+		keyPrefix, keyHash, time.Now(), updatedBy, id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateHeartbeat records the device's last heartbeat. Metadata is kept when the heartbeat carries none.
+func (c *DeviceClient) UpdateHeartbeat(ctx context.Context, id string, ipAddress string, metadata []byte) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateDeviceHeartbeat")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var rawMetadata *string
+	if len(metadata) > 0 {
+		value := string(metadata)
+		rawMetadata = &value
+	}
+
+	query := `
+		UPDATE device SET
+			last_heartbeat_at = ?,
+			last_ip_address = NULLIF(?, ''),
+			metadata = COALESCE(CAST(? AS JSONB), metadata)
+		WHERE id = ?`
+
+	err := c.db.Debug().WithContext(ctx).Exec(query, time.Now(), ipAddress, rawMetadata, id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *DeviceClient) DeleteDevice(ctx context.Context, tx *gorm.DB, id string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteDevice")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	result := tx.Debug().WithContext(ctx).Exec("DELETE FROM device WHERE id = ?", id)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
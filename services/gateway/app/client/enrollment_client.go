@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceEnrollmentClient interface {
+	CreateSession(ctx context.Context, session *model.EnrollmentSession) error
+	GetSessionByID(ctx context.Context, id string) (*model.EnrollmentSession, error)
+	GetSessions(ctx context.Context, institutionID string, status string) ([]*model.EnrollmentSession, error)
+	GetOpenSessionByUser(ctx context.Context, institutionID string, userID string) (*model.EnrollmentSession, error)
+	UpdateSessionStatus(ctx context.Context, tx *gorm.DB, id string, status string, updatedBy string) error
+	ExpireSessions(ctx context.Context, institutionID string, maxAge time.Duration) error
+	InsertCapture(ctx context.Context, tx *gorm.DB, capture *model.EnrollmentCapture) error
+	GetCaptures(ctx context.Context, sessionID string) ([]*model.EnrollmentCapture, error)
+}
+
+type EnrollmentClient struct {
+	db *gorm.DB
+}
+
+func NewEnrollmentClient(db *gorm.DB) *EnrollmentClient {
+	return &EnrollmentClient{db: db}
+}
+
+func (c *EnrollmentClient) CreateSession(ctx context.Context, session *model.EnrollmentSession) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CreateEnrollmentSession")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", session)
+
+	query := `
+		INSERT INTO enrollment_session (id, institution_id, user_id, username, status, required_count, required_frontal, required_left, required_right,
+			require_glasses_off, expires_at, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	args := []interface{}{
+		session.ID,
+		session.InstitutionID,
+		session.UserID,
+		session.Username,
+		session.Status,
+		session.RequiredCount,
+		session.RequiredFrontal,
+		session.RequiredLeft,
+		session.RequiredRight,
+		session.RequireGlassesOff,
+		session.ExpiresAt,
+		session.CreatedAt,
+		session.CreatedBy,
+		session.UpdatedAt,
+		session.UpdatedBy,
+	}
+
+	if err := c.db.Debug().WithContext(ctx).Exec(query, args...).Error; err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func (c *EnrollmentClient) GetSessionByID(ctx context.Context, id string) (*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetEnrollmentSessionByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var session model.EnrollmentSession
+	result := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM enrollment_session WHERE id = ?", id).Scan(&session)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		utils.LogEventError(span, errors.New("enrollment session not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("enrollment session not found"))
+	}
+
+	utils.LogEvent(span, "Response", session)
+
+	return &session, nil
+}
+
+func (c *EnrollmentClient) GetSessions(ctx context.Context, institutionID string, status string) ([]*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetEnrollmentSessions")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var conditions []string
+	var args []interface{}
+
+	conditions = append(conditions, "institution_id = ?")
+	args = append(args, institutionID)
+
+	if status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, status)
+	}
+
+	var response []*model.EnrollmentSession
+	query := "SELECT * FROM enrollment_session WHERE " + strings.Join(conditions, " AND ") + " ORDER BY created_at DESC"
+	if err := c.db.Debug().WithContext(ctx).Raw(query, args...).Scan(&response).Error; err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", response)
+
+	return response, nil
+}
+
+func (c *EnrollmentClient) GetOpenSessionByUser(ctx context.Context, institutionID string, userID string) (*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetOpenEnrollmentSessionByUser")
+	defer span.Finish()
+
+	var session model.EnrollmentSession
+	query := "SELECT * FROM enrollment_session WHERE institution_id = ? AND user_id = ? AND status = ?"
+	result := c.db.Debug().WithContext(ctx).Raw(query, institutionID, userID, model.EnrollmentStatusOpen).Scan(&session)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return nil, model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &session, nil
+}
+
+func (c *EnrollmentClient) UpdateSessionStatus(ctx context.Context, tx *gorm.DB, id string, status string, updatedBy string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpdateEnrollmentSessionStatus")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", status)
+
+	db := c.db
+	if tx != nil {
+		db = tx
+	}
+
+	now := utils.LocalTime()
+	query := "UPDATE enrollment_session SET status = ?, updated_at = ?, updated_by = ? WHERE id = ?"
+	args := []interface{}{status, now, updatedBy, id}
+	if status == model.EnrollmentStatusCompleted {
+		query = "UPDATE enrollment_session SET status = ?, updated_at = ?, updated_by = ?, completed_at = ? WHERE id = ?"
+		args = []interface{}{status, now, updatedBy, now, id}
+	}
+
+	if err := db.Debug().WithContext(ctx).Exec(query, args...).Error; err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+// ExpireSessions moves open sessions past their expiry to EXPIRED so they no longer block training. Sessions created
+// without an expiry expire after maxAge.
+func (c *EnrollmentClient) ExpireSessions(ctx context.Context, institutionID string, maxAge time.Duration) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ExpireEnrollmentSessions")
+	defer span.Finish()
+
+	query := `
+		UPDATE enrollment_session SET status = ?, updated_at = ?
+		WHERE institution_id = ? AND status = ?
+		AND (expires_at < ? OR (expires_at IS NULL AND created_at < ?))`
+	now := utils.LocalTime()
+	result := c.db.Debug().WithContext(ctx).Exec(query, model.EnrollmentStatusExpired, now, institutionID, model.EnrollmentStatusOpen, now, now.Add(-maxAge))
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return model.ThrowError(http.StatusInternalServerError, result.Error)
+	}
+
+	return nil
+}
+
+func (c *EnrollmentClient) InsertCapture(ctx context.Context, tx *gorm.DB, capture *model.EnrollmentCapture) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertEnrollmentCapture")
+	defer span.Finish()
+
+	db := c.db
+	if tx != nil {
+		db = tx
+	}
+
+	query := "INSERT INTO enrollment_capture (id, session_id, pose, glasses_off, file_name, created_at, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	args := []interface{}{capture.ID, capture.SessionID, capture.Pose, capture.GlassesOff, capture.FileName, time.Now(), capture.CreatedBy}
+	if err := db.Debug().WithContext(ctx).Exec(query, args...).Error; err != nil {
+		utils.LogEventError(span, err)
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return nil
+}
+
+func (c *EnrollmentClient) GetCaptures(ctx context.Context, sessionID string) ([]*model.EnrollmentCapture, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetEnrollmentCaptures")
+	defer span.Finish()
+
+	var response []*model.EnrollmentCapture
+	query := "SELECT * FROM enrollment_capture WHERE session_id = ? ORDER BY created_at ASC"
+	if err := c.db.Debug().WithContext(ctx).Raw(query, sessionID).Scan(&response).Error; err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// faceDetectionMaxSide bounds the image the cascade searches; larger photos are downsampled first.
+	faceDetectionMaxSide = 1600
+	// faceCropMargin widens each crop by this fraction of the face on every side, so it includes the hairline
+	// and chin the recognizer was trained with.
+	faceCropMargin = 0.25
+	// faceMaxPixels rejects images whose header claims more pixels than is reasonable to decode.
+	faceMaxPixels = 50_000_000
+)
+
+type InterfaceFaceDetectorClient interface {
+	DetectFaces(ctx context.Context, file *model.File) ([]*model.DetectedFace, error)
+}
+
+type FaceDetectorClient struct {
+	cfg     *config.Config
+	cascade *utils.Cascade
+}
+
+func NewFaceDetectorClient(cfg *config.Config) *FaceDetectorClient {
+	c := &FaceDetectorClient{cfg: cfg}
+
+	if !cfg.Recognition.Batch.Enabled {
+		return c
+	}
+
+	path := cfg.Recognition.Batch.CascadePath
+	if path == "" {
+		log.Error().Msg("Batch recognition is enabled without a face detection cascade, group photos will fail")
+		return c
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		c.cascade, err = utils.UnpackCascade(data)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("path", path).Msg("Failed to load face detection cascade, group photos will fail")
+	}
+
+	return c
+}
+
+// DetectFaces finds the faces of a photo and crops each one to a JPEG probe. Faces are ordered from left to
+// right, then top to bottom.
+func (c *FaceDetectorClient) DetectFaces(ctx context.Context, file *model.File) ([]*model.DetectedFace, error) {
+	span, _ := utils.SpanFromContext(ctx, "Client: DetectFaces")
+	defer span.Finish()
+
+	if c.cascade == nil {
+		utils.LogEventError(span, errors.New("face detection cascade is not loaded"))
+		return nil, model.ThrowError(http.StatusServiceUnavailable, errors.New("face detection is not available"))
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(file.BytesObject))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("image %s cannot be decoded", file.FileName))
+	}
+
+	if header.Width*header.Height > faceMaxPixels {
+		utils.LogEventError(span, errors.New("image too large"))
+		return nil, model.ThrowError(http.StatusRequestEntityTooLarge, fmt.Errorf("image %s exceeds %d pixels", file.FileName, faceMaxPixels))
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(file.BytesObject))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("image %s cannot be decoded", file.FileName))
+	}
+
+	pixels, rows, cols, factor := grayscale(img, faceDetectionMaxSide)
+
+	batch := c.cfg.Recognition.Batch
+	minSize := max(int(float64(batch.MinFaceSizePixels())/factor), 20)
+	detections := c.cascade.Detect(pixels, rows, cols, utils.CascadeParams{
+		MinSize:     minSize,
+		ShiftFactor: 0.1,
+		ScaleFactor: 1.1,
+	})
+
+	bounds := img.Bounds()
+	var faces []*model.DetectedFace
+	for _, detection := range detections {
+		if detection.Score < batch.MinDetectionScore() {
+			continue
+		}
+
+		side := int(float64(detection.Scale) * factor)
+		box := model.FaceBox{
+			X:      bounds.Min.X + int(float64(detection.Col)*factor) - side/2,
+			Y:      bounds.Min.Y + int(float64(detection.Row)*factor) - side/2,
+			Width:  side,
+			Height: side,
+		}
+		box = clampBox(box, bounds)
+
+		crop, err := cropFace(img, box)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		faces = append(faces, &model.DetectedFace{
+			Box:        box,
+			Confidence: detection.Score,
+			Image: &model.File{
+				FileName:    fmt.Sprintf("face-%d-%d.jpg", box.X, box.Y),
+				BytesObject: crop,
+				Extension:   "jpg",
+			},
+		})
+	}
+
+	sort.Slice(faces, func(i, j int) bool {
+		if faces[i].Box.X != faces[j].Box.X {
+			return faces[i].Box.X < faces[j].Box.X
+		}
+		return faces[i].Box.Y < faces[j].Box.Y
+	})
+
+	utils.LogEvent(span, "Response", faces)
+
+	return faces, nil
+}
+
+// grayscale converts img to 8-bit luma, downsampling by nearest neighbour so neither side exceeds maxSide. It
+// returns the factor that maps searched pixels back to the original image.
+func grayscale(img image.Image, maxSide int) ([]uint8, int, int, float64) {
+	bounds := img.Bounds()
+	factor := 1.0
+	if side := max(bounds.Dx(), bounds.Dy()); side > maxSide {
+		factor = float64(side) / float64(maxSide)
+	}
+
+	cols := int(float64(bounds.Dx()) / factor)
+	rows := int(float64(bounds.Dy()) / factor)
+	pixels := make([]uint8, rows*cols)
+	for row := 0; row < rows; row++ {
+		y := bounds.Min.Y + int(float64(row)*factor)
+		for col := 0; col < cols; col++ {
+			x := bounds.Min.X + int(float64(col)*factor)
+			r, g, b, _ := img.At(x, y).RGBA()
+			pixels[row*cols+col] = uint8((299*r + 587*g + 114*b) / 1000 >> 8)
+		}
+	}
+
+	return pixels, rows, cols, factor
+}
+
+func clampBox(box model.FaceBox, bounds image.Rectangle) model.FaceBox {
+	x0, y0 := max(box.X, bounds.Min.X), max(box.Y, bounds.Min.Y)
+	x1, y1 := min(box.X+box.Width, bounds.Max.X), min(box.Y+box.Height, bounds.Max.Y)
+
+	return model.FaceBox{X: x0, Y: y0, Width: x1 - x0, Height: y1 - y0}
+}
+
+// cropFace encodes the face region, widened by faceCropMargin, as a JPEG.
+func cropFace(img image.Image, box model.FaceBox) ([]byte, error) {
+	margin := int(float64(box.Width) * faceCropMargin)
+	region := image.Rect(box.X-margin, box.Y-margin, box.X+box.Width+margin, box.Y+box.Height+margin).Intersect(img.Bounds())
+
+	crop := image.NewRGBA(image.Rect(0, 0, region.Dx(), region.Dy()))
+	for y := 0; y < region.Dy(); y++ {
+		for x := 0; x < region.Dx(); x++ {
+			crop.Set(x, y, img.At(region.Min.X+x, region.Min.Y+y))
+		}
+	}
+
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, crop, &jpeg.Options{Quality: 90})
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,464 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// faceEmbeddingIndexMaxDimension is the largest vector pgvector can index with HNSW.
+	faceEmbeddingIndexMaxDimension = 2000
+	// faceEmbeddingCandidatesPerUser is how many nearest templates are read per requested user before keeping the
+	// best template of each user, so a user with many close templates rarely crowds out the others.
+	faceEmbeddingCandidatesPerUser = 10
+	// faceEmbeddingMaxCandidates is the most nearest templates an indexed search reads, pgvector's ef_search limit.
+	faceEmbeddingMaxCandidates = 1000
+)
+
+type InterfaceFaceEmbeddingClient interface {
+	UpsertEmbeddings(ctx context.Context, tx *gorm.DB, embeddings []*model.FaceEmbedding) error
+	ClaimDimension(ctx context.Context, tx *gorm.DB, modelTrainingID string, dimension int) (int, bool, error)
+	CopyBaseEmbeddings(ctx context.Context, tx *gorm.DB, training *model.ModelTraining, dimension int) (int64, error)
+	EnsureVectorIndex(ctx context.Context, dimension int) error
+	GetDimension(ctx context.Context, modelTrainingID string) (int, error)
+	SearchNearest(ctx context.Context, modelTrainingID string, embedding []float64, topK int) ([]*model.EmbeddingMatch, error)
+	GetDimensionMismatches(ctx context.Context) ([]*model.EmbeddingDimensionMismatch, error)
+	DeleteDimensionMismatches(ctx context.Context, tx *gorm.DB, modelTrainingID string, dimension int) (int64, error)
+}
+
+type FaceEmbeddingClient struct {
+	db *gorm.DB
+
+	mu      sync.Mutex
+	vector  *bool
+	indexed map[int]bool
+}
+
+func NewFaceEmbeddingClient(db *gorm.DB) *FaceEmbeddingClient {
+	return &FaceEmbeddingClient{
+		db:      db,
+		indexed: map[int]bool{},
+	}
+}
+
+// UpsertEmbeddings replaces the vector of an image that was already reported for the same training.
+func (c *FaceEmbeddingClient) UpsertEmbeddings(ctx context.Context, tx *gorm.DB, embeddings []*model.FaceEmbedding) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpsertFaceEmbeddings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", len(embeddings))
+
+	vector, err := c.vectorEnabled(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	query := `
+		INSERT INTO face_embedding (id, institution_id, model_training_id, model_version, username, image_key, dimension, embedding, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CAST(? AS REAL[]), ?)
+		ON CONFLICT (model_training_id, username, image_key) DO UPDATE SET
+			dimension = EXCLUDED.dimension,
+			embedding = EXCLUDED.embedding,
+			created_at = EXCLUDED.created_at`
+	if vector {
+		query = `
+		INSERT INTO face_embedding (id, institution_id, model_training_id, model_version, username, image_key, dimension, embedding, created_at, embedding_vector)
+		VALUES (?, ?, ?, ?, ?, ?, ?, CAST(? AS REAL[]), ?, CAST(? AS vector))
+		ON CONFLICT (model_training_id, username, image_key) DO UPDATE SET
+			dimension = EXCLUDED.dimension,
+			embedding = EXCLUDED.embedding,
+			embedding_vector = EXCLUDED.embedding_vector,
+			created_at = EXCLUDED.created_at`
+	}
+
+	for _, embedding := range embeddings {
+		args := []any{
+			embedding.ID,
+			embedding.InstitutionID,
+			embedding.ModelTrainingID,
+			embedding.ModelVersion,
+			embedding.Username,
+			embedding.ImageKey,
+			embedding.Dimension,
+			vectorLiteral(embedding.Vector),
+			embedding.CreatedAt,
+		}
+		if vector {
+			args = append(args, pgvectorLiteral(embedding.Vector))
+		}
+
+		err := tx.Debug().WithContext(ctx).Exec(query, args...).Error
+		if err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimDimension locks the training row until tx ends and returns the training's embedding dimension, setting it
+// to dimension when the training has none yet. The bool reports whether this call set it.
+func (c *FaceEmbeddingClient) ClaimDimension(ctx context.Context, tx *gorm.DB, modelTrainingID string, dimension int) (int, bool, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ClaimFaceEmbeddingDimension")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"model_training_id": modelTrainingID, "dimension": dimension})
+
+	var current []*int
+
+	err := tx.Debug().WithContext(ctx).Raw("SELECT embedding_dimension FROM model_training WHERE id = ? FOR UPDATE", modelTrainingID).Scan(&current).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, false, err
+	}
+
+	if len(current) == 0 {
+		return 0, false, nil
+	}
+
+	if current[0] != nil {
+		return *current[0], false, nil
+	}
+
+	err = tx.Debug().WithContext(ctx).Exec("UPDATE model_training SET embedding_dimension = ? WHERE id = ?", dimension, modelTrainingID).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, false, err
+	}
+
+	return dimension, true, nil
+}
+
+// CopyBaseEmbeddings gives an incremental training the templates of its base model for every image it was trained
+// on unchanged, so that searching it also finds users the incremental training did not embed again. An image
+// whose ETag differs between the two manifests is left to the training's own embedding.
+func (c *FaceEmbeddingClient) CopyBaseEmbeddings(ctx context.Context, tx *gorm.DB, training *model.ModelTraining, dimension int) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CopyBaseFaceEmbeddings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"model_training_id": training.ID, "base_model_id": training.BaseModelID})
+
+	if training.BaseModelID == nil {
+		return 0, nil
+	}
+
+	vector, err := c.vectorEnabled(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	vectorColumn := ""
+	if vector {
+		vectorColumn = ", embedding_vector"
+	}
+
+	query := fmt.Sprintf(`
+		WITH current_objects AS (
+			SELECT o->>'key' AS key, o->>'etag' AS etag
+			FROM model_training_manifest m, jsonb_array_elements(m.objects) o
+			WHERE m.model_training_id = ?
+		), base_objects AS (
+			SELECT o->>'key' AS key, o->>'etag' AS etag
+			FROM model_training_manifest m, jsonb_array_elements(m.objects) o
+			WHERE m.model_training_id = ?
+		)
+		INSERT INTO face_embedding (id, institution_id, model_training_id, model_version, username, image_key, dimension, embedding, created_at%[1]s)
+		SELECT gen_random_uuid(), e.institution_id, ?, ?, e.username, e.image_key, e.dimension, e.embedding, ?%[1]s
+		FROM face_embedding e
+		JOIN current_objects co ON co.key = e.image_key
+		LEFT JOIN base_objects bo ON bo.key = e.image_key
+		WHERE e.model_training_id = ? AND e.dimension = ? AND (bo.key IS NULL OR bo.etag = co.etag)
+		ON CONFLICT (model_training_id, username, image_key) DO NOTHING`, vectorColumn)
+
+	result := tx.Debug().WithContext(ctx).Exec(query,
+		training.ID,
+		*training.BaseModelID,
+		training.ID,
+		training.Version,
+		time.Now(),
+		*training.BaseModelID,
+		dimension,
+	)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	utils.LogEvent(span, "Response", result.RowsAffected)
+
+	return result.RowsAffected, nil
+}
+
+// EnsureVectorIndex creates the HNSW index searches of dimension use. It does nothing without pgvector or for
+// dimensions pgvector cannot index, which are searched exactly.
+func (c *FaceEmbeddingClient) EnsureVectorIndex(ctx context.Context, dimension int) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: EnsureFaceEmbeddingVectorIndex")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", dimension)
+
+	vector, err := c.vectorEnabled(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if !vector || dimension > faceEmbeddingIndexMaxDimension {
+		return nil
+	}
+
+	c.mu.Lock()
+	done := c.indexed[dimension]
+	c.mu.Unlock()
+	if done {
+		return nil
+	}
+
+	// CONCURRENTLY keeps ingestion of other trainings running while the index is built.
+	query := fmt.Sprintf(
+		"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_face_embedding_vector_%[1]d ON face_embedding USING hnsw ((CAST(embedding_vector AS vector(%[1]d))) vector_ip_ops) WHERE dimension = %[1]d",
+		dimension)
+
+	err = c.db.Debug().WithContext(ctx).Exec(query).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	c.mu.Lock()
+	c.indexed[dimension] = true
+	c.mu.Unlock()
+
+	return nil
+}
+
+// GetDimension returns 0 when the training has no stored embeddings.
+func (c *FaceEmbeddingClient) GetDimension(ctx context.Context, modelTrainingID string) (int, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetFaceEmbeddingDimension")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", modelTrainingID)
+
+	var result []*int
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT embedding_dimension FROM model_training WHERE id = ?", modelTrainingID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	if len(result) == 0 || result[0] == nil {
+		return 0, nil
+	}
+
+	return *result[0], nil
+}
+
+// GetDimensionMismatches groups the templates whose dimension differs from their training's by training and
+// dimension. Searches skip them.
+func (c *FaceEmbeddingClient) GetDimensionMismatches(ctx context.Context) ([]*model.EmbeddingDimensionMismatch, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetFaceEmbeddingDimensionMismatches")
+	defer span.Finish()
+
+	var result []*model.EmbeddingDimensionMismatch
+
+	query := `
+		SELECT e.model_training_id, t.institution_id, t.embedding_dimension AS training_dimension, e.dimension, COUNT(*) AS embeddings
+		FROM face_embedding e
+		JOIN model_training t ON t.id = e.model_training_id
+		WHERE t.embedding_dimension IS NOT NULL AND e.dimension <> t.embedding_dimension
+		GROUP BY e.model_training_id, t.institution_id, t.embedding_dimension, e.dimension
+		ORDER BY e.model_training_id, e.dimension`
+
+	err := c.db.Debug().WithContext(ctx).Raw(query).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(result))
+
+	return result, nil
+}
+
+// DeleteDimensionMismatches deletes the templates of the training stored with dimension, unless dimension is the
+// training's own.
+func (c *FaceEmbeddingClient) DeleteDimensionMismatches(ctx context.Context, tx *gorm.DB, modelTrainingID string, dimension int) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteFaceEmbeddingDimensionMismatches")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"model_training_id": modelTrainingID, "dimension": dimension})
+
+	if tx == nil {
+		tx = c.db
+	}
+
+	query := `
+		DELETE FROM face_embedding e
+		USING model_training t
+		WHERE e.model_training_id = ? AND e.dimension = ?
+		AND t.id = e.model_training_id AND t.embedding_dimension IS NOT NULL AND e.dimension <> t.embedding_dimension`
+
+	result := tx.Debug().WithContext(ctx).Exec(query, modelTrainingID, dimension)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	utils.LogEvent(span, "Response", result.RowsAffected)
+
+	return result.RowsAffected, nil
+}
+
+// SearchNearest returns the best template of each of the topK users closest to embedding. Stored vectors and
+// embedding are L2 normalised, so their dot product is the cosine similarity. With pgvector the nearest templates
+// come from the HNSW index of the dimension, which is approximate; otherwise every template of the training is
+// scored.
+func (c *FaceEmbeddingClient) SearchNearest(ctx context.Context, modelTrainingID string, embedding []float64, topK int) ([]*model.EmbeddingMatch, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: SearchNearestFaceEmbeddings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"model_training_id": modelTrainingID, "top_k": topK})
+
+	vector, err := c.vectorEnabled(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var result []*model.EmbeddingMatch
+
+	if vector && len(embedding) <= faceEmbeddingIndexMaxDimension {
+		result, err = c.searchVector(ctx, modelTrainingID, embedding, topK)
+	} else {
+		result, err = c.searchArray(ctx, modelTrainingID, embedding, topK)
+	}
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return result, nil
+}
+
+func (c *FaceEmbeddingClient) searchArray(ctx context.Context, modelTrainingID string, embedding []float64, topK int) ([]*model.EmbeddingMatch, error) {
+	var result []*model.EmbeddingMatch
+
+	query := `
+		SELECT username, image_key, score FROM (
+			SELECT DISTINCT ON (username) username, image_key, score FROM (
+				SELECT e.username, e.image_key,
+					(SELECT SUM(a * b) FROM unnest(e.embedding, CAST(? AS REAL[])) AS v(a, b)) AS score
+				FROM face_embedding e
+				WHERE e.model_training_id = ? AND e.dimension = ?
+			) scored
+			ORDER BY username, score DESC
+		) best
+		ORDER BY score DESC
+		LIMIT ?`
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, vectorLiteral(embedding), modelTrainingID, len(embedding), topK).Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// searchVector reads the nearest templates through the dimension's partial HNSW index, whose expression the
+// query repeats, and keeps the best template of each user.
+func (c *FaceEmbeddingClient) searchVector(ctx context.Context, modelTrainingID string, embedding []float64, topK int) ([]*model.EmbeddingMatch, error) {
+	var result []*model.EmbeddingMatch
+
+	candidates := min(topK*faceEmbeddingCandidatesPerUser, faceEmbeddingMaxCandidates)
+
+	query := fmt.Sprintf(`
+		SELECT username, image_key, score FROM (
+			SELECT DISTINCT ON (username) username, image_key, score FROM (
+				SELECT e.username, e.image_key,
+					-(CAST(e.embedding_vector AS vector(%[1]d)) <#> CAST(? AS vector(%[1]d))) AS score
+				FROM face_embedding e
+				WHERE e.model_training_id = ? AND e.dimension = %[1]d
+				ORDER BY CAST(e.embedding_vector AS vector(%[1]d)) <#> CAST(? AS vector(%[1]d))
+				LIMIT ?
+			) nearest
+			ORDER BY username, score DESC
+		) best
+		ORDER BY score DESC
+		LIMIT ?`, len(embedding))
+
+	literal := pgvectorLiteral(embedding)
+
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// ef_search bounds how many templates the index returns, so it must cover the candidates read.
+		err := tx.Exec(fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", max(candidates, 40))).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Debug().Raw(query, literal, modelTrainingID, literal, candidates, topK).Scan(&result).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// vectorEnabled reports whether migrations found pgvector and added the vector column. A failed check is retried
+// on the next call.
+func (c *FaceEmbeddingClient) vectorEnabled(ctx context.Context) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.vector != nil {
+		return *c.vector, nil
+	}
+
+	var exists bool
+
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'face_embedding' AND column_name = 'embedding_vector'
+		)`
+
+	err := c.db.WithContext(ctx).Raw(query).Scan(&exists).Error
+	if err != nil {
+		return false, err
+	}
+
+	c.vector = &exists
+
+	return exists, nil
+}
+
+// vectorLiteral formats a vector as a Postgres array literal.
+func vectorLiteral(vector []float64) string {
+	parts := make([]string, len(vector))
+	for i, value := range vector {
+		parts[i] = strconv.FormatFloat(value, 'g', -1, 32)
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// pgvectorLiteral formats a vector in pgvector's text format.
+func pgvectorLiteral(vector []float64) string {
+	literal := vectorLiteral(vector)
+
+	return "[" + literal[1:len(literal)-1] + "]"
+}
@@ -19,6 +19,7 @@ type InterfaceFeatureClient interface {
 	UpdateFeature(ctx context.Context, feature *model.Feature) error
 	SetInstitutionFeature(ctx context.Context, request *model.InstitutionFeatureRequest) error
 	GetInstitutionFeatures(ctx context.Context, institutionID string) ([]*model.InstitutionFeature, error)
+	IsFeatureEnabled(ctx context.Context, institutionID string, featureKey string) (bool, error)
 }
 
 type FeatureClient struct {
@@ -170,3 +171,25 @@ func (c *FeatureClient) GetInstitutionFeatures(ctx context.Context, institutionI
 	}
 	return response, nil
 }
+
+// IsFeatureEnabled reports whether the feature is enabled for the institution: its institution setting if there is
+// one, otherwise the feature's default. An unknown feature is disabled.
+func (c *FeatureClient) IsFeatureEnabled(ctx context.Context, institutionID string, featureKey string) (bool, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: IsFeatureEnabled")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"institution_id": institutionID, "feature_key": featureKey})
+
+	var enabled []bool
+	query := `
+		SELECT COALESCE(inf.is_enabled, f.default_enabled)
+		FROM feature f
+		LEFT JOIN institution_feature inf ON inf.feature_key = f.feature_key AND inf.institution_id = ?
+		WHERE f.feature_key = ?`
+	if err := c.db.Debug().WithContext(ctx).Raw(query, institutionID, featureKey).Scan(&enabled).Error; err != nil {
+		utils.LogEventError(span, err)
+		return false, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	return len(enabled) > 0 && enabled[0], nil
+}
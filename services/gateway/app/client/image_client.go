@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+type InterfaceImageClient interface {
+	FetchImage(ctx context.Context, rawURL string) (*model.File, error)
+}
+
+type ImageClient struct {
+	cfg        *config.Config
+	httpClient *http.Client
+}
+
+func NewImageClient(cfg *config.Config) *ImageClient {
+	timeout, err := time.ParseDuration(cfg.Dataset.FetchTimeout)
+	if err != nil || timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	c := &ImageClient{cfg: cfg}
+	c.httpClient = &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 3 {
+				return errors.New("too many redirects")
+			}
+			return c.checkURL(req.URL)
+		},
+	}
+
+	return c
+}
+
+// FetchImage downloads an image from an allowlisted HTTPS host, enforcing the configured size cap.
+func (c *ImageClient) FetchImage(ctx context.Context, rawURL string) (*model.File, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: FetchImage")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", rawURL)
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("invalid image url %s", rawURL))
+	}
+
+	if err := c.checkURL(target); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadGateway, fmt.Errorf("failed to fetch image %s: %s", rawURL, err.Error()))
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		utils.LogEventError(span, errors.New(res.Status))
+		return nil, model.ThrowError(http.StatusBadGateway, fmt.Errorf("failed to fetch image %s: %s", rawURL, res.Status))
+	}
+
+	maxBytes := c.cfg.Dataset.MaxImageBytes
+	if maxBytes > 0 && res.ContentLength > maxBytes {
+		utils.LogEventError(span, errors.New("image too large"))
+		return nil, model.ThrowError(http.StatusRequestEntityTooLarge, fmt.Errorf("image %s exceeds %d bytes", rawURL, maxBytes))
+	}
+
+	var reader io.Reader = res.Body
+	if maxBytes > 0 {
+		reader = io.LimitReader(res.Body, maxBytes+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadGateway, fmt.Errorf("failed to read image %s: %s", rawURL, err.Error()))
+	}
+
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		utils.LogEventError(span, errors.New("image too large"))
+		return nil, model.ThrowError(http.StatusRequestEntityTooLarge, fmt.Errorf("image %s exceeds %d bytes", rawURL, maxBytes))
+	}
+
+	utils.LogEvent(span, "Response", fmt.Sprintf("fetched %d bytes", len(body)))
+
+	return &model.File{
+		FileName:    path.Base(target.Path),
+		BytesObject: body,
+	}, nil
+}
+
+func (c *ImageClient) checkURL(target *url.URL) error {
+	if target.Scheme != "https" {
+		return fmt.Errorf("image url %s must use https", target.String())
+	}
+
+	host := strings.ToLower(target.Hostname())
+	for _, allowed := range c.cfg.Dataset.AllowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == host {
+			return nil
+		}
+		if strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:]) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("image host %s is not allowed", host)
+}
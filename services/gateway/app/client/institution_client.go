@@ -121,8 +121,8 @@ func (c *InstitutionClient) CreateNewInstitution(ctx context.Context, institutio
 
 	var args []interface{}
 
-	args = append(args, institution.ID, institution.Name, institution.Code, institution.Address, institution.PhoneNumber, institution.Email, institution.IsActive, institution.CreatedAt, institution.UpdatedAt)
-	err := c.db.Debug().WithContext(ctx).Exec("INSERT INTO institution (id, name, code, address, phone_number, email, is_active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", args...).Error
+	args = append(args, institution.ID, institution.Name, institution.Code, institution.Address, institution.PhoneNumber, institution.Email, institution.IsActive, institution.TrainingTier, institution.CreatedAt, institution.UpdatedAt)
+	err := c.db.Debug().WithContext(ctx).Exec("INSERT INTO institution (id, name, code, address, phone_number, email, is_active, training_tier, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'standard'), ?, ?)", args...).Error
 	if err != nil {
 		utils.LogEventError(span, err)
 		return err
@@ -139,8 +139,8 @@ func (c *InstitutionClient) UpdateInstitution(ctx context.Context, institution *
 	utils.LogEvent(span, "Request", institution)
 
 	var args []interface{}
-	args = append(args, institution.Name, institution.Code, institution.Address, institution.PhoneNumber, institution.Email, institution.IsActive, institution.UpdatedAt, institution.ID)
-	err := c.db.Debug().WithContext(ctx).Exec("UPDATE institution SET name = ?, code = ?, address = ?, phone_number = ?, email = ?, is_active = ?, updated_at = ? WHERE id = ?", args...).Error
+	args = append(args, institution.Name, institution.Code, institution.Address, institution.PhoneNumber, institution.Email, institution.IsActive, institution.TrainingTier, institution.UpdatedAt, institution.ID)
+	err := c.db.Debug().WithContext(ctx).Exec("UPDATE institution SET name = ?, code = ?, address = ?, phone_number = ?, email = ?, is_active = ?, training_tier = COALESCE(NULLIF(?, ''), training_tier), updated_at = ? WHERE id = ?", args...).Error
 	if err != nil {
 		utils.LogEventError(span, err)
 		return err
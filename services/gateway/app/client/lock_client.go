@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// acquireLockScript bumps the fencing counter and takes the lock in one round trip, so a token is only consumed
+// by a successful acquisition. A counter below ARGV[3] is raised past it first, so a counter lost with the Redis
+// data cannot hand out tokens older than the ones already stored elsewhere.
+var acquireLockScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return 0
+end
+local token = redis.call("INCR", KEYS[2])
+local floor = tonumber(ARGV[3])
+if token <= floor then
+	token = floor + 1
+	redis.call("SET", KEYS[2], token)
+end
+redis.call("SET", KEYS[1], token .. ":" .. ARGV[1], "PX", ARGV[2])
+return token
+`)
+
+var releaseLockScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value and string.sub(value, 1, string.len(ARGV[1]) + 1) == ARGV[1] .. ":" then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var extendLockScript = redis.NewScript(`
+local value = redis.call("GET", KEYS[1])
+if value and string.sub(value, 1, string.len(ARGV[1]) + 1) == ARGV[1] .. ":" then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+type InterfaceLockClient interface {
+	Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (*model.Lock, error)
+	AcquireAbove(ctx context.Context, key string, owner string, ttl time.Duration, floor int64) (*model.Lock, error)
+	Get(ctx context.Context, key string) (*model.Lock, error)
+	Release(ctx context.Context, key string, token int64) error
+	Extend(ctx context.Context, key string, token int64, ttl time.Duration) (bool, error)
+}
+
+type LockClient struct {
+	redis *redis.Client
+}
+
+func NewLockClient(redis *redis.Client) *LockClient {
+	return &LockClient{redis: redis}
+}
+
+// Acquire returns nil without error when the lock is held by someone else.
+func (c *LockClient) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (*model.Lock, error) {
+	return c.AcquireAbove(ctx, key, owner, ttl, 0)
+}
+
+// AcquireAbove is Acquire with a fencing token greater than floor, for locks whose tokens are also persisted.
+func (c *LockClient) AcquireAbove(ctx context.Context, key string, owner string, ttl time.Duration, floor int64) (*model.Lock, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: AcquireLock")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"key": key, "floor": floor})
+
+	token, err := acquireLockScript.Run(ctx, c.redis, []string{key, key + ":fence"}, owner, ttl.Milliseconds(), floor).Int64()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if token == 0 {
+		return nil, nil
+	}
+
+	lock := &model.Lock{
+		Key:   key,
+		Token: token,
+		Owner: owner,
+	}
+
+	utils.LogEvent(span, "Response", lock)
+
+	return lock, nil
+}
+
+// Get returns the current holder of the lock, or nil when it is free.
+func (c *LockClient) Get(ctx context.Context, key string) (*model.Lock, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetLock")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", key)
+
+	pipe := c.redis.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	_, err := pipe.Exec(ctx)
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	value := get.Val()
+
+	tokenStr, owner, found := strings.Cut(value, ":")
+	if !found {
+		return nil, fmt.Errorf("malformed lock value for %s", key)
+	}
+
+	token, err := strconv.ParseInt(tokenStr, 10, 64)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	lock := &model.Lock{
+		Key:   key,
+		Token: token,
+		Owner: owner,
+		TTL:   pttl.Val(),
+	}
+
+	utils.LogEvent(span, "Response", lock)
+
+	return lock, nil
+}
+
+// Release deletes the lock only if it is still held with token, so an expired holder cannot free a newer lock.
+func (c *LockClient) Release(ctx context.Context, key string, token int64) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ReleaseLock")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"key": key, "token": token})
+
+	err := releaseLockScript.Run(ctx, c.redis, []string{key}, strconv.FormatInt(token, 10)).Err()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Extend renews the lock's TTL and reports false when it is no longer held with token.
+func (c *LockClient) Extend(ctx context.Context, key string, token int64, ttl time.Duration) (bool, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ExtendLock")
+	defer span.Finish()
+
+	extended, err := extendLockScript.Run(ctx, c.redis, []string{key}, strconv.FormatInt(token, 10), ttl.Milliseconds()).Int64()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return false, err
+	}
+
+	return extended == 1, nil
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestLockClient(t *testing.T) (*LockClient, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	return NewLockClient(rdb), server
+}
+
+func TestLockClientFencingTokens(t *testing.T) {
+	ctx := context.Background()
+	locks, server := newTestLockClient(t)
+
+	first, err := locks.Acquire(ctx, "training:lock:inst", "job-1", time.Hour)
+	if err != nil || first == nil {
+		t.Fatalf("Acquire() = %v, %v", first, err)
+	}
+	if first.Token != 1 {
+		t.Errorf("first token = %d, want 1", first.Token)
+	}
+
+	held, err := locks.Acquire(ctx, "training:lock:inst", "job-2", time.Hour)
+	if err != nil || held != nil {
+		t.Fatalf("Acquire() while held = %v, %v; want nil, nil", held, err)
+	}
+
+	if err := locks.Release(ctx, "training:lock:inst", first.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := locks.Acquire(ctx, "training:lock:inst", "job-2", time.Hour)
+	if err != nil || second == nil {
+		t.Fatalf("Acquire() after release = %v, %v", second, err)
+	}
+	if second.Token != 2 {
+		t.Errorf("second token = %d, want 2", second.Token)
+	}
+	locks.Release(ctx, "training:lock:inst", second.Token)
+
+	// Losing the Redis data resets the counter; the floor keeps new tokens above the ones stored with trainings.
+	server.FlushAll()
+
+	third, err := locks.AcquireAbove(ctx, "training:lock:inst", "job-3", time.Hour, 41)
+	if err != nil || third == nil {
+		t.Fatalf("AcquireAbove() = %v, %v", third, err)
+	}
+	if third.Token != 42 {
+		t.Errorf("token after the floor = %d, want 42", third.Token)
+	}
+	locks.Release(ctx, "training:lock:inst", third.Token)
+
+	// A floor below the counter does not lower it.
+	fourth, err := locks.AcquireAbove(ctx, "training:lock:inst", "job-4", time.Hour, 5)
+	if err != nil || fourth == nil {
+		t.Fatalf("AcquireAbove() = %v, %v", fourth, err)
+	}
+	if fourth.Token != 43 {
+		t.Errorf("token with a lower floor = %d, want 43", fourth.Token)
+	}
+}
+
+func TestLockClientRefusesStaleHolder(t *testing.T) {
+	ctx := context.Background()
+	locks, server := newTestLockClient(t)
+
+	stale, err := locks.Acquire(ctx, "dataset:reconcile:lock", "run-1", time.Minute)
+	if err != nil || stale == nil {
+		t.Fatalf("Acquire() = %v, %v", stale, err)
+	}
+
+	// The first run outlives its TTL and another run takes the lock.
+	server.FastForward(2 * time.Minute)
+
+	current, err := locks.Acquire(ctx, "dataset:reconcile:lock", "run-2", time.Minute)
+	if err != nil || current == nil {
+		t.Fatalf("Acquire() after expiry = %v, %v", current, err)
+	}
+
+	extended, err := locks.Extend(ctx, "dataset:reconcile:lock", stale.Token, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extended {
+		t.Error("Extend() with the stale token succeeded")
+	}
+
+	if err := locks.Release(ctx, "dataset:reconcile:lock", stale.Token); err != nil {
+		t.Fatal(err)
+	}
+
+	holder, err := locks.Get(ctx, "dataset:reconcile:lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder == nil || holder.Token != current.Token || holder.Owner != "run-2" {
+		t.Fatalf("holder after the stale release = %+v, want token %d of run-2", holder, current.Token)
+	}
+
+	// Token 1 must not match a lock held with token 10 or more through a prefix.
+	server.Set("dataset:reconcile:lock", "12:run-3")
+	if err := locks.Release(ctx, "dataset:reconcile:lock", 1); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("dataset:reconcile:lock") {
+		t.Error("Release() with token 1 deleted the lock held with token 12")
+	}
+
+	extended, err = locks.Extend(ctx, "dataset:reconcile:lock", 12, time.Hour)
+	if err != nil || !extended {
+		t.Errorf("Extend() by the holder = %v, %v; want true", extended, err)
+	}
+	if ttl := server.TTL("dataset:reconcile:lock"); ttl != time.Hour {
+		t.Errorf("TTL after Extend() = %v, want 1h", ttl)
+	}
+
+	if err := locks.Release(ctx, "dataset:reconcile:lock", 12); err != nil {
+		t.Fatal(err)
+	}
+	if server.Exists("dataset:reconcile:lock") {
+		t.Error("Release() by the holder kept the lock")
+	}
+}
@@ -0,0 +1,254 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+const modelActivationExchange = "ModelActivation"
+
+type InterfaceModelClient interface {
+	GetModels(ctx context.Context, institutionID string) ([]*model.ModelTraining, error)
+	GetModelByID(ctx context.Context, id string) (*model.ModelTraining, error)
+	GetActiveModel(ctx context.Context, institutionID string) (*model.ModelTraining, error)
+	LockActiveModel(ctx context.Context, tx *gorm.DB, institutionID string) (*model.ModelTraining, error)
+	ActivateModel(ctx context.Context, tx *gorm.DB, institutionID string, id string, activatedBy string) error
+	InsertActivation(ctx context.Context, tx *gorm.DB, activation *model.ModelActivation) error
+	GetActivations(ctx context.Context, institutionID string) ([]*model.ModelActivation, error)
+	PublishActivation(ctx context.Context, event *model.ModelActivationEvent) error
+}
+
+type ModelClient struct {
+	db *gorm.DB
+	mq connection.InterfaceMessageBus
+}
+
+func NewModelClient(db *gorm.DB, mq connection.InterfaceMessageBus) *ModelClient {
+	return &ModelClient{
+		db: db,
+		mq: mq,
+	}
+}
+
+func (c *ModelClient) GetModels(ctx context.Context, institutionID string) ([]*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetModels")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var result []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE institution_id = ? AND deleted_at IS NULL ORDER BY version DESC"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return result, nil
+}
+
+func (c *ModelClient) GetModelByID(ctx context.Context, id string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetModelByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var result []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE id = ? AND deleted_at IS NULL"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, id).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("model not found"))
+	}
+
+	utils.LogEvent(span, "Response", result[0])
+
+	return result[0], nil
+}
+
+// GetActiveModel returns the model currently served for the institution, or nil when none is active.
+func (c *ModelClient) GetActiveModel(ctx context.Context, institutionID string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetActiveModel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var result []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE institution_id = ? AND is_used = 'Y' AND deleted_at IS NULL LIMIT 1"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	utils.LogEvent(span, "Response", result[0])
+
+	return result[0], nil
+}
+
+// LockActiveModel serialises concurrent activations of the same institution until tx ends and returns the model active at that point, or nil.
+func (c *ModelClient) LockActiveModel(ctx context.Context, tx *gorm.DB, institutionID string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: LockActiveModel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := tx.Debug().WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "model_activation:"+institutionID).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var result []*model.ModelTraining
+
+	query := "SELECT * FROM model_training WHERE institution_id = ? AND is_used = 'Y' AND deleted_at IS NULL LIMIT 1"
+
+	err = tx.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result[0], nil
+}
+
+func (c *ModelClient) ActivateModel(ctx context.Context, tx *gorm.DB, institutionID string, id string, activatedBy string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ActivateModel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	err := tx.Debug().WithContext(ctx).Exec(
+		"UPDATE model_training SET is_used = 'N', updated_at = NOW(), updated_by = ? WHERE institution_id = ? AND is_used = 'Y'",
+		activatedBy, institutionID,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	result := tx.Debug().WithContext(ctx).Exec(
+		"UPDATE model_training SET is_used = 'Y', activated_at = NOW(), activated_by = ?, updated_at = NOW(), updated_by = ? WHERE id = ? AND institution_id = ?",
+		activatedBy, activatedBy, id, institutionID,
+	)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return model.ThrowError(http.StatusNotFound, errors.New("model not found"))
+	}
+
+	return nil
+}
+
+func (c *ModelClient) InsertActivation(ctx context.Context, tx *gorm.DB, activation *model.ModelActivation) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertModelActivation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", activation)
+
+	query := `
+		INSERT INTO model_activation (id, institution_id, model_training_id, previous_model_training_id, action, created_at, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	err := tx.Debug().WithContext(ctx).Exec(query,
+		activation.ID,
+		activation.InstitutionID,
+		activation.ModelTrainingID,
+		activation.PreviousModelTrainingID,
+		activation.Action,
+		activation.CreatedAt,
+		activation.CreatedBy,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *ModelClient) GetActivations(ctx context.Context, institutionID string) ([]*model.ModelActivation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetModelActivations")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var result []*model.ModelActivation
+
+	query := "SELECT * FROM model_activation WHERE institution_id = ? ORDER BY created_at DESC"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query, institutionID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return result, nil
+}
+
+// PublishActivation broadcasts on a fanout exchange so every recognition worker receives the event on its own queue.
+func (c *ModelClient) PublishActivation(ctx context.Context, event *model.ModelActivationEvent) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: PublishModelActivation")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", event)
+
+	err := c.mq.Declare(ctx, connection.Topology{
+		Exchanges: []connection.Exchange{{Name: modelActivationExchange, Kind: connection.ExchangeFanout}},
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	message, err := utils.NewMessage(ctx, model.ModelActivationMessage, event.InstitutionID, event)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// Not routable means no recognition worker is bound yet; they read the active model on startup instead.
+	err = c.mq.Publish(
+		ctx,
+		modelActivationExchange, // Exchange
+		"",                      // Routing key (ignored by fanout)
+		message,
+	)
+	if err != nil && !errors.Is(err, connection.ErrMessageReturned) {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
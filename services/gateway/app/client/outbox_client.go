@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceOutboxClient interface {
+	Insert(ctx context.Context, tx *gorm.DB, message *model.OutboxMessage) error
+	FetchPending(ctx context.Context, tx *gorm.DB, limit int, aging time.Duration, maxPriority int) ([]*model.OutboxMessage, error)
+	Claim(ctx context.Context, tx *gorm.DB, ids []string, until time.Time) error
+	MarkSent(ctx context.Context, tx *gorm.DB, id string) error
+	MarkRetry(ctx context.Context, tx *gorm.DB, id string, status string, lastError string, nextAttemptAt time.Time) error
+	DiscardPending(ctx context.Context, tx *gorm.DB, topic string, aggregateID string) (int64, error)
+	GetMetrics(ctx context.Context) (*model.OutboxMetrics, error)
+}
+
+type OutboxClient struct {
+	db *gorm.DB
+}
+
+func NewOutboxClient(db *gorm.DB) *OutboxClient {
+	return &OutboxClient{db: db}
+}
+
+func (c *OutboxClient) Insert(ctx context.Context, tx *gorm.DB, message *model.OutboxMessage) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertOutbox")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", message)
+
+	query := `
+		INSERT INTO outbox (id, topic, aggregate_id, payload, status, attempts, next_attempt_at, created_at)
+		VALUES (?, ?, ?, CAST(? AS JSONB), ?, 0, ?, ?)`
+
+	err := tx.Debug().WithContext(ctx).Exec(query,
+		message.ID,
+		message.Topic,
+		message.AggregateID,
+		string(message.Payload),
+		model.OutboxStatusPending,
+		message.CreatedAt,
+		message.CreatedAt,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// FetchPending locks due, unclaimed rows with SKIP LOCKED so several relays (one per gateway replica) never pick
+// the same row. Training messages come first by their priority, raised by one level per aging they have waited
+// and capped at maxPriority, the way the relay publishes them; ties and other topics go oldest first.
+func (c *OutboxClient) FetchPending(ctx context.Context, tx *gorm.DB, limit int, aging time.Duration, maxPriority int) ([]*model.OutboxMessage, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: FetchPendingOutbox")
+	defer span.Finish()
+
+	var result []*model.OutboxMessage
+
+	query := `
+		SELECT * FROM outbox
+		WHERE status = 'PENDING' AND next_attempt_at <= NOW() AND (claimed_until IS NULL OR claimed_until <= NOW())
+		ORDER BY
+			CASE WHEN topic = ? THEN LEAST(
+				COALESCE((payload->>'priority')::INT, 0)
+					+ COALESCE(FLOOR(GREATEST(EXTRACT(EPOCH FROM NOW() - created_at), 0) / NULLIF(?, 0))::INT, 0),
+				?) ELSE 0 END DESC,
+			created_at
+		LIMIT ?
+		FOR UPDATE SKIP LOCKED`
+
+	err := tx.Debug().WithContext(ctx).Raw(query, model.OutboxTopicTrainModel, aging.Seconds(), maxPriority, limit).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(result))
+
+	return result, nil
+}
+
+// Claim hides the messages from FetchPending and DiscardPending until the relay publishing them marks them sent
+// or for retry. A claim left by a crashed relay expires at until and the messages are published again.
+func (c *OutboxClient) Claim(ctx context.Context, tx *gorm.DB, ids []string, until time.Time) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ClaimOutbox")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", ids)
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE outbox SET claimed_until = ? WHERE id IN ?", until, ids).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// MarkSent leaves a message discarded after its claim expired as it is.
+func (c *OutboxClient) MarkSent(ctx context.Context, tx *gorm.DB, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: MarkOutboxSent")
+	defer span.Finish()
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE outbox SET status = 'SENT', attempts = attempts + 1, sent_at = NOW(), last_error = NULL, claimed_until = NULL WHERE id = ? AND status = 'PENDING'", id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *OutboxClient) MarkRetry(ctx context.Context, tx *gorm.DB, id string, status string, lastError string, nextAttemptAt time.Time) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: MarkOutboxRetry")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]interface{}{"id": id, "status": status, "error": lastError})
+
+	err := tx.Debug().WithContext(ctx).Exec(
+		"UPDATE outbox SET status = ?, attempts = attempts + 1, last_error = ?, next_attempt_at = ?, claimed_until = NULL WHERE id = ? AND status = 'PENDING'",
+		status, lastError, nextAttemptAt, id,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// DiscardPending drops messages that were not relayed yet and reports how many were dropped. Claimed messages are
+// being published and are not dropped.
+func (c *OutboxClient) DiscardPending(ctx context.Context, tx *gorm.DB, topic string, aggregateID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DiscardPendingOutbox")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", aggregateID)
+
+	result := tx.Debug().WithContext(ctx).Exec(
+		"UPDATE outbox SET status = 'DISCARDED' WHERE topic = ? AND aggregate_id = ? AND status = 'PENDING' AND (claimed_until IS NULL OR claimed_until <= NOW())",
+		topic, aggregateID,
+	)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+func (c *OutboxClient) GetMetrics(ctx context.Context) (*model.OutboxMetrics, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetOutboxMetrics")
+	defer span.Finish()
+
+	var result model.OutboxMetrics
+
+	query := `
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'PENDING') AS pending,
+			COUNT(*) FILTER (WHERE status = 'FAILED') AS failed,
+			COALESCE(EXTRACT(EPOCH FROM NOW() - MIN(created_at) FILTER (WHERE status = 'PENDING')), 0) AS oldest_pending_seconds,
+			COUNT(*) FILTER (WHERE status = 'SENT' AND sent_at >= NOW() - INTERVAL '1 hour') AS sent_last_hour,
+			COALESCE(AVG(EXTRACT(EPOCH FROM sent_at - created_at)) FILTER (WHERE status = 'SENT' AND sent_at >= NOW() - INTERVAL '1 hour'), 0) AS avg_lag_seconds
+		FROM outbox`
+
+	err := c.db.Debug().WithContext(ctx).Raw(query).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return &result, nil
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"sync/atomic"
+)
+
+type InterfaceRecognitionClient interface {
+	Recognize(ctx context.Context, messageType string, request *model.RecognitionRequest) (*model.RecognitionReply, error)
+}
+
+type RecognitionClient struct {
+	mq       connection.InterfaceMessageBus
+	cfg      *config.Config
+	declared atomic.Bool
+}
+
+func NewRecognitionClient(mq connection.InterfaceMessageBus, cfg *config.Config) *RecognitionClient {
+	return &RecognitionClient{
+		mq:  mq,
+		cfg: cfg,
+	}
+}
+
+// Recognize sends an identify or verify request to the processing service and waits for its reply. A request
+// nobody answers in time is reported as 504; it expires from the queue when the caller's deadline passes.
+func (c *RecognitionClient) Recognize(ctx context.Context, messageType string, request *model.RecognitionRequest) (*model.RecognitionReply, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: Recognize")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"type": messageType, "institution_id": request.InstitutionID, "model_id": request.ModelID})
+
+	err := c.declare(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	message, err := utils.NewMessage(ctx, messageType, request.InstitutionID, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	reply, err := c.mq.Request(ctx, "", model.RecognitionQueue, message)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, model.ThrowError(http.StatusGatewayTimeout, errors.New("recognition service did not answer in time"))
+		}
+		return nil, model.ThrowError(http.StatusServiceUnavailable, err)
+	}
+
+	envelope, err := utils.ReadMessage(*reply, model.RecognitionResultMessage)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadGateway, err)
+	}
+
+	var res model.RecognitionReply
+	if err := json.Unmarshal(envelope.Data, &res); err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadGateway, err)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return &res, nil
+}
+
+// declare creates the request queue once. It has no TTL: each request expires with its own deadline, so changing
+// messageBus.requestTimeout never changes the queue's arguments.
+func (c *RecognitionClient) declare(ctx context.Context) error {
+	if c.declared.Load() {
+		return nil
+	}
+
+	err := c.mq.Declare(ctx, connection.Topology{
+		Queues: []connection.Queue{{
+			Name: model.RecognitionQueue,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	c.declared.Store(true)
+
+	return nil
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceRecognitionEventClient interface {
+	InsertEvent(ctx context.Context, event *model.RecognitionEvent) error
+	GetEvents(ctx context.Context, filter *model.FilterRecognitionEvent, pagination *model.Pagination) ([]*model.RecognitionEvent, *model.Pagination, error)
+	GetEventByID(ctx context.Context, id string) (*model.RecognitionEvent, error)
+	GetStats(ctx context.Context, filter *model.FilterRecognitionEvent, hourly bool) ([]*model.RecognitionStats, error)
+}
+
+type RecognitionEventClient struct {
+	db *gorm.DB
+}
+
+func NewRecognitionEventClient(db *gorm.DB) *RecognitionEventClient {
+	return &RecognitionEventClient{db: db}
+}
+
+func (c *RecognitionEventClient) InsertEvent(ctx context.Context, event *model.RecognitionEvent) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertRecognitionEvent")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", event)
+
+	var faceBox interface{}
+	if len(event.FaceBox) > 0 {
+		faceBox = string(event.FaceBox)
+	}
+
+	query := `
+		INSERT INTO recognition_event (id, institution_id, kind, caller, device_id, claimed_user, matched_user, score, model_id, model_version,
+			latency_ms, decision, probe_path, message, batch_id, face_box, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CAST(? AS JSONB), ?)`
+
+	err := c.db.Debug().WithContext(ctx).Exec(query,
+		event.ID,
+		event.InstitutionID,
+		event.Kind,
+		event.Caller,
+		event.DeviceID,
+		event.ClaimedUser,
+		event.MatchedUser,
+		event.Score,
+		event.ModelID,
+		event.ModelVersion,
+		event.LatencyMs,
+		event.Decision,
+		event.ProbePath,
+		event.Message,
+		event.BatchID,
+		faceBox,
+		event.CreatedAt,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *RecognitionEventClient) GetEvents(ctx context.Context, filter *model.FilterRecognitionEvent, pagination *model.Pagination) ([]*model.RecognitionEvent, *model.Pagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRecognitionEvents")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	whereClause, args := recognitionEventConditions(filter)
+
+	var totalCount int64
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT COUNT(*) FROM recognition_event"+whereClause, args...).Scan(&totalCount).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	pagination.Total = int(totalCount)
+	pagination.TotalPages = (pagination.Total + pagination.Limit - 1) / pagination.Limit
+
+	res := []*model.RecognitionEvent{}
+
+	query := fmt.Sprintf("SELECT * FROM recognition_event%s ORDER BY created_at DESC LIMIT %d OFFSET %d",
+		whereClause, pagination.Limit, (pagination.Page-1)*pagination.Limit)
+
+	err = c.db.Debug().WithContext(ctx).Raw(query, args...).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	utils.LogEvent(span, "Pagination", pagination)
+
+	return res, pagination, nil
+}
+
+// GetEventByID returns nil when the event does not exist.
+func (c *RecognitionEventClient) GetEventByID(ctx context.Context, id string) (*model.RecognitionEvent, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRecognitionEventByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var res []*model.RecognitionEvent
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM recognition_event WHERE id = ?", id).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	return res[0], nil
+}
+
+// GetStats aggregates the filtered events, per hour when hourly is set and as a single row otherwise.
+func (c *RecognitionEventClient) GetStats(ctx context.Context, filter *model.FilterRecognitionEvent, hourly bool) ([]*model.RecognitionStats, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRecognitionStats")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	whereClause, args := recognitionEventConditions(filter)
+
+	sb := strings.Builder{}
+	sb.WriteString("SELECT ")
+	if hourly {
+		sb.WriteString("date_trunc('hour', created_at) AS hour, ")
+	}
+	sb.WriteString(`COUNT(*) AS total,
+		COUNT(*) FILTER (WHERE decision = 'MATCH') AS matches,
+		COUNT(*) FILTER (WHERE decision = 'NO_MATCH') AS no_matches,
+		COUNT(*) FILTER (WHERE decision = 'ERROR') AS errors,
+		COUNT(*) FILTER (WHERE decision = 'MATCH')::float / NULLIF(COUNT(*) FILTER (WHERE decision <> 'ERROR'), 0) AS match_rate,
+		AVG(score) AS average_score,
+		AVG(score) FILTER (WHERE decision = 'MATCH') AS average_match_score,
+		AVG(latency_ms) FILTER (WHERE decision <> 'ERROR') AS average_latency_ms
+		FROM recognition_event`)
+	sb.WriteString(whereClause)
+	if hourly {
+		sb.WriteString(" GROUP BY hour ORDER BY hour")
+	}
+
+	res := []*model.RecognitionStats{}
+	err := c.db.Debug().WithContext(ctx).Raw(sb.String(), args...).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func recognitionEventConditions(filter *model.FilterRecognitionEvent) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.InstitutionID != "" {
+		conditions = append(conditions, "institution_id = ?")
+		args = append(args, filter.InstitutionID)
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.To)
+	}
+	if filter.Username != "" {
+		conditions = append(conditions, "(matched_user = ? OR claimed_user = ?)")
+		args = append(args, filter.Username, filter.Username)
+	}
+	if filter.DeviceID != "" {
+		conditions = append(conditions, "device_id = ?")
+		args = append(args, filter.DeviceID)
+	}
+	if filter.Decision != "" {
+		conditions = append(conditions, "decision = ?")
+		args = append(args, filter.Decision)
+	}
+	if filter.Kind != "" {
+		conditions = append(conditions, "kind = ?")
+		args = append(args, filter.Kind)
+	}
+	if filter.BatchID != "" {
+		conditions = append(conditions, "batch_id = ?")
+		args = append(args, filter.BatchID)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
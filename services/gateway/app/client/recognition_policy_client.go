@@ -0,0 +1,96 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceRecognitionPolicyClient interface {
+	GetPolicyByInstitution(ctx context.Context, institutionID string) (*model.RecognitionPolicy, error)
+	UpsertPolicy(ctx context.Context, policy *model.RecognitionPolicy) error
+	DeletePolicy(ctx context.Context, institutionID string) (int64, error)
+}
+
+type RecognitionPolicyClient struct {
+	db *gorm.DB
+}
+
+func NewRecognitionPolicyClient(db *gorm.DB) *RecognitionPolicyClient {
+	return &RecognitionPolicyClient{db: db}
+}
+
+// GetPolicyByInstitution returns nil when the institution has no stored policy.
+func (c *RecognitionPolicyClient) GetPolicyByInstitution(ctx context.Context, institutionID string) (*model.RecognitionPolicy, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRecognitionPolicyByInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var result []*model.RecognitionPolicy
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM recognition_policy WHERE institution_id = ?", institutionID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result[0], nil
+}
+
+func (c *RecognitionPolicyClient) UpsertPolicy(ctx context.Context, policy *model.RecognitionPolicy) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpsertRecognitionPolicy")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", policy)
+
+	query := `
+		INSERT INTO recognition_policy (id, institution_id, match_threshold, top_k, min_margin, max_model_age_days, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (institution_id) DO UPDATE SET
+			match_threshold = EXCLUDED.match_threshold,
+			top_k = EXCLUDED.top_k,
+			min_margin = EXCLUDED.min_margin,
+			max_model_age_days = EXCLUDED.max_model_age_days,
+			updated_by = EXCLUDED.updated_by`
+
+	err := c.db.Debug().WithContext(ctx).Exec(query,
+		policy.ID,
+		policy.InstitutionID,
+		policy.MatchThreshold,
+		policy.TopK,
+		policy.MinMargin,
+		policy.MaxModelAgeDays,
+		policy.CreatedAt,
+		policy.CreatedBy,
+		policy.UpdatedAt,
+		policy.UpdatedBy,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *RecognitionPolicyClient) DeletePolicy(ctx context.Context, institutionID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteRecognitionPolicy")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	result := c.db.Debug().WithContext(ctx).Exec("DELETE FROM recognition_policy WHERE institution_id = ?", institutionID)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
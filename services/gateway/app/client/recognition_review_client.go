@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceRecognitionReviewClient interface {
+	InsertReview(ctx context.Context, review *model.RecognitionReview) error
+	CountPending(ctx context.Context, institutionID string) (int64, error)
+	GetReviews(ctx context.Context, filter *model.FilterRecognitionReview, pagination *model.Pagination) ([]*model.RecognitionReview, *model.Pagination, error)
+	GetReviewByID(ctx context.Context, id string) (*model.RecognitionReview, error)
+	DecideReview(ctx context.Context, tx *gorm.DB, id string, status string, labeledUser *string, note *string, decidedBy string) (int64, error)
+	DeleteExpiredReviews(ctx context.Context, now time.Time, limit int) ([]*model.RecognitionReview, error)
+}
+
+type RecognitionReviewClient struct {
+	db *gorm.DB
+}
+
+func NewRecognitionReviewClient(db *gorm.DB) *RecognitionReviewClient {
+	return &RecognitionReviewClient{db: db}
+}
+
+func (c *RecognitionReviewClient) InsertReview(ctx context.Context, review *model.RecognitionReview) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertRecognitionReview")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", review)
+
+	query := `
+		INSERT INTO recognition_review (id, institution_id, event_id, kind, device_id, claimed_user, best_candidate, best_score, probe_path,
+			status, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	err := c.db.Debug().WithContext(ctx).Exec(query,
+		review.ID,
+		review.InstitutionID,
+		review.EventID,
+		review.Kind,
+		review.DeviceID,
+		review.ClaimedUser,
+		review.BestCandidate,
+		review.BestScore,
+		review.ProbePath,
+		review.Status,
+		review.ExpiresAt,
+		review.CreatedAt,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *RecognitionReviewClient) CountPending(ctx context.Context, institutionID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: CountPendingRecognitionReviews")
+	defer span.Finish()
+
+	var count int64
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT COUNT(*) FROM recognition_review WHERE institution_id = ? AND status = ?",
+		institutionID, model.RecognitionReviewPending).Scan(&count).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (c *RecognitionReviewClient) GetReviews(ctx context.Context, filter *model.FilterRecognitionReview, pagination *model.Pagination) ([]*model.RecognitionReview, *model.Pagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRecognitionReviews")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	var conditions []string
+	var args []interface{}
+	if filter.InstitutionID != "" {
+		conditions = append(conditions, "institution_id = ?")
+		args = append(args, filter.InstitutionID)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+
+	whereClause := ""
+	if len(conditions) > 0 {
+		whereClause = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var totalCount int64
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT COUNT(*) FROM recognition_review"+whereClause, args...).Scan(&totalCount).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	pagination.Total = int(totalCount)
+	pagination.TotalPages = (pagination.Total + pagination.Limit - 1) / pagination.Limit
+
+	res := []*model.RecognitionReview{}
+
+	query := fmt.Sprintf("SELECT * FROM recognition_review%s ORDER BY created_at DESC LIMIT %d OFFSET %d",
+		whereClause, pagination.Limit, (pagination.Page-1)*pagination.Limit)
+
+	err = c.db.Debug().WithContext(ctx).Raw(query, args...).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	utils.LogEvent(span, "Pagination", pagination)
+
+	return res, pagination, nil
+}
+
+// GetReviewByID returns nil when the review does not exist.
+func (c *RecognitionReviewClient) GetReviewByID(ctx context.Context, id string) (*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRecognitionReviewByID")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	var res []*model.RecognitionReview
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM recognition_review WHERE id = ?", id).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, nil
+	}
+
+	return res[0], nil
+}
+
+// DecideReview records a decision on a pending review and returns 0 when it was no longer pending, so two
+// admins cannot decide the same face.
+func (c *RecognitionReviewClient) DecideReview(ctx context.Context, tx *gorm.DB, id string, status string, labeledUser *string, note *string, decidedBy string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DecideRecognitionReview")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"id": id, "status": status})
+
+	query := `
+		UPDATE recognition_review
+		SET status = ?, labeled_user = ?, note = ?, decided_at = NOW(), decided_by = ?
+		WHERE id = ? AND status = ?`
+
+	result := tx.Debug().WithContext(ctx).Exec(query, status, labeledUser, note, decidedBy, id, model.RecognitionReviewPending)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// DeleteExpiredReviews removes up to limit reviews past their retention and returns them, so their images can
+// be deleted. Concurrent callers never receive the same review.
+func (c *RecognitionReviewClient) DeleteExpiredReviews(ctx context.Context, now time.Time, limit int) ([]*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteExpiredRecognitionReviews")
+	defer span.Finish()
+
+	query := `
+		DELETE FROM recognition_review
+		WHERE id IN (
+			SELECT id FROM recognition_review
+			WHERE expires_at < ?
+			ORDER BY expires_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`
+
+	var res []*model.RecognitionReview
+	err := c.db.Debug().WithContext(ctx).Raw(query, now, limit).Scan(&res).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(res))
+
+	return res, nil
+}
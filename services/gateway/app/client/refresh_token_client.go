@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceRefreshTokenClient interface {
+	InsertRefreshToken(ctx context.Context, tx *gorm.DB, token *model.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(ctx context.Context, tx *gorm.DB, id string, replacedBy string) error
+	RevokeRefreshTokenFamily(ctx context.Context, tx *gorm.DB, familyID string) (int64, error)
+}
+
+type RefreshTokenClient struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenClient(db *gorm.DB) *RefreshTokenClient {
+	return &RefreshTokenClient{db: db}
+}
+
+// InsertRefreshToken stores a refresh token, inside tx when given.
+func (c *RefreshTokenClient) InsertRefreshToken(ctx context.Context, tx *gorm.DB, token *model.RefreshToken) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: InsertRefreshToken")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"id": token.ID, "family_id": token.FamilyID, "user_id": token.UserID})
+
+	if tx == nil {
+		tx = c.db
+	}
+
+	query := `
+		INSERT INTO refresh_token (id, family_id, user_id, institution_id, token_hash, expires_at, ip_address, user_agent, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?)`
+
+	err := tx.Debug().WithContext(ctx).Exec(query,
+		token.ID,
+		token.FamilyID,
+		token.UserID,
+		token.InstitutionID,
+		token.TokenHash,
+		token.ExpiresAt,
+		token.IPAddress,
+		token.UserAgent,
+		token.CreatedAt,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetRefreshTokenByHash returns the token with the username of its user, locked until tx ends, or nil when no token
+// has the hash.
+func (c *RefreshTokenClient) GetRefreshTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (*model.RefreshToken, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetRefreshTokenByHash")
+	defer span.Finish()
+
+	var result []*model.RefreshToken
+
+	query := `
+		SELECT rt.*, u.username
+		FROM refresh_token rt
+		JOIN "user" u ON u.id = rt.user_id
+		WHERE rt.token_hash = ?
+		FOR UPDATE OF rt`
+
+	err := tx.Debug().WithContext(ctx).Raw(query, tokenHash).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	utils.LogEvent(span, "Response", map[string]any{"id": result[0].ID, "family_id": result[0].FamilyID})
+
+	return result[0], nil
+}
+
+func (c *RefreshTokenClient) MarkRefreshTokenUsed(ctx context.Context, tx *gorm.DB, id string, replacedBy string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: MarkRefreshTokenUsed")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"id": id, "replaced_by": replacedBy})
+
+	err := tx.Debug().WithContext(ctx).Exec("UPDATE refresh_token SET used_at = ?, replaced_by = ? WHERE id = ?",
+		time.Now(), replacedBy, id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// RevokeRefreshTokenFamily revokes every token of the family that is not revoked yet and returns how many were.
+func (c *RefreshTokenClient) RevokeRefreshTokenFamily(ctx context.Context, tx *gorm.DB, familyID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: RevokeRefreshTokenFamily")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", familyID)
+
+	result := tx.Debug().WithContext(ctx).Exec("UPDATE refresh_token SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL",
+		time.Now(), familyID)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
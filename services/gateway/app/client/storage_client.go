@@ -7,10 +7,14 @@ import (
 	"face-recognition-svc/gateway/app/model"
 	"face-recognition-svc/gateway/app/utils"
 	"fmt"
+	"io"
 	"net/http"
+	"path"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
@@ -22,7 +26,11 @@ type InterfaceStorageClient interface {
 	StoreFileData(ctx context.Context, tx *gorm.DB, req *model.Dataset) error
 
 	DeleteDatasetDB(ctx context.Context, tx *gorm.DB, username string) error
+	DeleteDatasetRecord(ctx context.Context, tx *gorm.DB, dataset string) error
 	DeleteObject(ctx context.Context, bucket string, prefix string) error
+	GetObject(ctx context.Context, bucket string, key string) (*model.File, error)
+	ListDatasetPrefixes(ctx context.Context, bucket string) (map[string]time.Time, error)
+	ListDatasetObjects(ctx context.Context, bucket string, prefix string) ([]*model.DatasetObject, error)
 
 	GetDatasetsByUsername(ctx context.Context, bucket string, username string) ([]string, error)
 }
@@ -139,6 +147,40 @@ func (c *StorageClient) DeleteObject(ctx context.Context, bucket string, prefix
 	return nil
 }
 
+// GetObject downloads one object into memory. A missing object is reported as 404.
+func (c *StorageClient) GetObject(ctx context.Context, bucket string, key string) (*model.File, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetObject")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", key)
+
+	output, err := c.s3.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, model.ThrowError(http.StatusNotFound, fmt.Errorf("object %s not found", key))
+		}
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return &model.File{
+		FileName:    path.Base(key),
+		BytesObject: data,
+		Extension:   strings.TrimPrefix(path.Ext(key), "."),
+	}, nil
+}
+
 func (c *StorageClient) StoreFileData(ctx context.Context, tx *gorm.DB, req *model.Dataset) error {
 	span, ctx := utils.SpanFromContext(ctx, "Client: StoreFileData")
 	defer span.Finish()
@@ -185,6 +227,97 @@ func (c *StorageClient) DeleteDatasetDB(ctx context.Context, tx *gorm.DB, userna
 	return nil
 }
 
+func (c *StorageClient) DeleteDatasetRecord(ctx context.Context, tx *gorm.DB, dataset string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteDatasetRecord")
+	defer span.Finish()
+
+	var result *gorm.DB
+	query := "DELETE FROM face_datasets WHERE dataset = ?"
+
+	if tx != nil {
+		result = tx.Debug().WithContext(ctx).Exec(query, dataset)
+	} else {
+		result = c.db.Debug().WithContext(ctx).Exec(query, dataset)
+	}
+
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return result.Error
+	}
+
+	utils.LogEvent(span, "Response", fmt.Sprintf("deleted %d rows", result.RowsAffected))
+
+	return nil
+}
+
+// ListDatasetPrefixes returns every distinct "<institution>/<username>" prefix found in the bucket with the time
+// its newest object was modified.
+func (c *StorageClient) ListDatasetPrefixes(ctx context.Context, bucket string) (map[string]time.Time, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ListDatasetPrefixes")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", bucket)
+
+	res := map[string]time.Time{}
+
+	err := c.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			parts := strings.SplitN(aws.StringValue(object.Key), "/", 3)
+			if len(parts) < 3 {
+				continue
+			}
+			prefix := parts[0] + "/" + parts[1]
+			if modified := aws.TimeValue(object.LastModified); modified.After(res[prefix]) {
+				res[prefix] = modified
+			}
+		}
+		return true
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// ListDatasetObjects returns every object under prefix with the attributes needed to detect new or changed images.
+func (c *StorageClient) ListDatasetObjects(ctx context.Context, bucket string, prefix string) ([]*model.DatasetObject, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: ListDatasetObjects")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", prefix)
+
+	var res []*model.DatasetObject
+
+	err := c.s3.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			res = append(res, &model.DatasetObject{
+				Key:          aws.StringValue(object.Key),
+				ETag:         strings.Trim(aws.StringValue(object.ETag), `"`),
+				Size:         aws.Int64Value(object.Size),
+				LastModified: aws.TimeValue(object.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(res))
+
+	return res, nil
+}
+
 func (c *StorageClient) GetDatasetsByUsername(ctx context.Context, bucket string, prefix string) ([]string, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Client: GetDatasetByUsername")
 	defer span.Finish()
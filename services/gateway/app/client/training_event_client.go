@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+type InterfaceTrainingEventClient interface {
+	Publish(ctx context.Context, event *model.TrainingEvent) error
+	Subscribe(ctx context.Context, institutionID string) (<-chan *model.TrainingEvent, error)
+}
+
+// trainingEventBuffer is how many events a slow stream may fall behind before further events are dropped for it.
+const trainingEventBuffer = 16
+
+// TrainingEventClient shares one Redis pattern subscription among all streams of this replica and fans the events
+// out by institution.
+type TrainingEventClient struct {
+	redis *redis.Client
+
+	mu          sync.Mutex
+	pubsub      *redis.PubSub
+	subscribers map[string]map[chan *model.TrainingEvent]struct{}
+}
+
+func NewTrainingEventClient(redis *redis.Client) *TrainingEventClient {
+	return &TrainingEventClient{
+		redis:       redis,
+		subscribers: map[string]map[chan *model.TrainingEvent]struct{}{},
+	}
+}
+
+func (c *TrainingEventClient) Publish(ctx context.Context, event *model.TrainingEvent) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: PublishTrainingEvent")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", event)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.redis.Publish(ctx, fmt.Sprintf(model.TrainingEventChannel, event.InstitutionID), payload).Err()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Subscribe streams the institution's training events until ctx is done, then closes the channel. Events
+// published by any gateway replica are received.
+func (c *TrainingEventClient) Subscribe(ctx context.Context, institutionID string) (<-chan *model.TrainingEvent, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: SubscribeTrainingEvents")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pubsub == nil {
+		// The subscription outlives the request that opens it, so it is not bound to ctx.
+		pubsub := c.redis.PSubscribe(context.Background(), fmt.Sprintf(model.TrainingEventChannel, "*"))
+
+		// Wait for the subscription to be confirmed so no event published after Subscribe returns is missed.
+		if _, err := pubsub.Receive(ctx); err != nil {
+			utils.LogEventError(span, err)
+			pubsub.Close()
+			return nil, err
+		}
+
+		c.pubsub = pubsub
+		go c.dispatch(pubsub.Channel())
+	}
+
+	events := make(chan *model.TrainingEvent, trainingEventBuffer)
+	if c.subscribers[institutionID] == nil {
+		c.subscribers[institutionID] = map[chan *model.TrainingEvent]struct{}{}
+	}
+	c.subscribers[institutionID][events] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.subscribers[institutionID], events)
+		if len(c.subscribers[institutionID]) == 0 {
+			delete(c.subscribers, institutionID)
+		}
+		close(events)
+	}()
+
+	return events, nil
+}
+
+// dispatch hands every event to the streams of its institution. go-redis reconnects and resubscribes on its own,
+// so this runs for the life of the process.
+func (c *TrainingEventClient) dispatch(messages <-chan *redis.Message) {
+	for message := range messages {
+		var event model.TrainingEvent
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			log.Warn().Err(err).Str("channel", message.Channel).Msg("Malformed training event")
+			continue
+		}
+
+		c.mu.Lock()
+		for events := range c.subscribers[event.InstitutionID] {
+			select {
+			case events <- &event:
+			default:
+				log.Warn().Str("institution_id", event.InstitutionID).Str("training_id", event.ID).Msg("Training event dropped for a slow stream")
+			}
+		}
+		c.mu.Unlock()
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceTrainingMetricClient interface {
+	UpsertMetrics(ctx context.Context, tx *gorm.DB, trainingID string, metrics *model.TrainingMetrics) error
+	GetMetrics(ctx context.Context, trainingIDs []string) (map[string]*model.ModelTrainingMetric, error)
+}
+
+type TrainingMetricClient struct {
+	db *gorm.DB
+}
+
+func NewTrainingMetricClient(db *gorm.DB) *TrainingMetricClient {
+	return &TrainingMetricClient{db: db}
+}
+
+// UpsertMetrics stores the metrics of a training. A duration the processing service did not report is taken from
+// the training's started_at and finished_at, so it must run after the final status is written.
+func (c *TrainingMetricClient) UpsertMetrics(ctx context.Context, tx *gorm.DB, trainingID string, metrics *model.TrainingMetrics) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpsertTrainingMetrics")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", metrics)
+
+	thresholds := metrics.Thresholds
+	if thresholds == nil {
+		thresholds = []model.ThresholdMetric{}
+	}
+
+	thresholdsJSON, err := json.Marshal(thresholds)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	query := `
+		INSERT INTO model_training_metric (model_training_id, identities, images, validation_accuracy, thresholds, duration_seconds, artifact_size_bytes)
+		SELECT mt.id, CAST(? AS INT), CAST(? AS INT), CAST(? AS NUMERIC), CAST(? AS JSONB),
+			COALESCE(CAST(? AS NUMERIC), EXTRACT(EPOCH FROM (mt.finished_at - mt.started_at))),
+			CAST(? AS BIGINT)
+		FROM model_training mt
+		WHERE mt.id = ?
+		ON CONFLICT (model_training_id) DO UPDATE SET
+			identities = EXCLUDED.identities,
+			images = EXCLUDED.images,
+			validation_accuracy = EXCLUDED.validation_accuracy,
+			thresholds = EXCLUDED.thresholds,
+			duration_seconds = EXCLUDED.duration_seconds,
+			artifact_size_bytes = EXCLUDED.artifact_size_bytes`
+
+	err = tx.Debug().WithContext(ctx).Exec(query,
+		metrics.Identities,
+		metrics.Images,
+		metrics.ValidationAccuracy,
+		string(thresholdsJSON),
+		metrics.DurationSeconds,
+		metrics.ArtifactSizeBytes,
+		trainingID,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetMetrics returns the metrics of the given trainings keyed by training ID. Trainings without metrics are absent.
+func (c *TrainingMetricClient) GetMetrics(ctx context.Context, trainingIDs []string) (map[string]*model.ModelTrainingMetric, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetTrainingMetrics")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", trainingIDs)
+
+	result := map[string]*model.ModelTrainingMetric{}
+	if len(trainingIDs) == 0 {
+		return result, nil
+	}
+
+	var metrics []*model.ModelTrainingMetric
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM model_training_metric WHERE model_training_id IN ?", trainingIDs).Scan(&metrics).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	for _, metric := range metrics {
+		result[metric.ModelTrainingID] = metric
+	}
+
+	return result, nil
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceTrainingScheduleClient interface {
+	GetSchedules(ctx context.Context, activeOnly bool) ([]*model.TrainingSchedule, error)
+	GetScheduleByInstitution(ctx context.Context, institutionID string) (*model.TrainingSchedule, error)
+	UpsertSchedule(ctx context.Context, schedule *model.TrainingSchedule) error
+	DeleteSchedule(ctx context.Context, institutionID string) (int64, error)
+	MarkScheduleRun(ctx context.Context, id string, run *model.TrainingScheduleRun, lastRunAt *time.Time, lastTriggeredAt *time.Time) error
+}
+
+type TrainingScheduleClient struct {
+	db *gorm.DB
+}
+
+func NewTrainingScheduleClient(db *gorm.DB) *TrainingScheduleClient {
+	return &TrainingScheduleClient{db: db}
+}
+
+func (c *TrainingScheduleClient) GetSchedules(ctx context.Context, activeOnly bool) ([]*model.TrainingSchedule, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetTrainingSchedules")
+	defer span.Finish()
+
+	var result []*model.TrainingSchedule
+
+	query := "SELECT * FROM training_schedule"
+	if activeOnly {
+		query += " WHERE is_active = TRUE"
+	}
+	query += " ORDER BY created_at"
+
+	err := c.db.Debug().WithContext(ctx).Raw(query).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", len(result))
+
+	return result, nil
+}
+
+// GetScheduleByInstitution returns nil when the institution has no schedule.
+func (c *TrainingScheduleClient) GetScheduleByInstitution(ctx context.Context, institutionID string) (*model.TrainingSchedule, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: GetTrainingScheduleByInstitution")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	var result []*model.TrainingSchedule
+
+	err := c.db.Debug().WithContext(ctx).Raw("SELECT * FROM training_schedule WHERE institution_id = ?", institutionID).Scan(&result).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, nil
+	}
+
+	return result[0], nil
+}
+
+func (c *TrainingScheduleClient) UpsertSchedule(ctx context.Context, schedule *model.TrainingSchedule) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: UpsertTrainingSchedule")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", schedule)
+
+	query := `
+		INSERT INTO training_schedule (id, institution_id, cron_expr, min_new_images, min_new_users, is_active, created_at, created_by, updated_at, updated_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (institution_id) DO UPDATE SET
+			cron_expr = EXCLUDED.cron_expr,
+			min_new_images = EXCLUDED.min_new_images,
+			min_new_users = EXCLUDED.min_new_users,
+			is_active = EXCLUDED.is_active,
+			updated_by = EXCLUDED.updated_by`
+
+	err := c.db.Debug().WithContext(ctx).Exec(query,
+		schedule.ID,
+		schedule.InstitutionID,
+		schedule.CronExpr,
+		schedule.MinNewImages,
+		schedule.MinNewUsers,
+		schedule.IsActive,
+		schedule.CreatedAt,
+		schedule.CreatedBy,
+		schedule.UpdatedAt,
+		schedule.UpdatedBy,
+	).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *TrainingScheduleClient) DeleteSchedule(ctx context.Context, institutionID string) (int64, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Client: DeleteTrainingSchedule")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	result := c.db.Debug().WithContext(ctx).Exec("DELETE FROM training_schedule WHERE institution_id = ?", institutionID)
+	if result.Error != nil {
+		utils.LogEventError(span, result.Error)
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// MarkScheduleRun stores the outcome of an evaluation. lastRunAt and lastTriggeredAt are left unchanged when nil.
+func (c *TrainingScheduleClient) MarkScheduleRun(ctx context.Context, id string, run *model.TrainingScheduleRun, lastRunAt *time.Time, lastTriggeredAt *time.Time) error {
+	span, ctx := utils.SpanFromContext(ctx, "Client: MarkTrainingScheduleRun")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", run)
+
+	var lastError, trainingID *string
+	if run.Error != "" {
+		lastError = &run.Error
+	}
+	if run.TrainingID != "" {
+		trainingID = &run.TrainingID
+	}
+
+	query := `
+		UPDATE training_schedule SET
+			last_run_at = COALESCE(?, last_run_at),
+			last_triggered_at = COALESCE(?, last_triggered_at),
+			last_training_id = COALESCE(?, last_training_id),
+			last_error = ?
+		WHERE id = ?`
+
+	err := c.db.Debug().WithContext(ctx).Exec(query, lastRunAt, lastTriggeredAt, trainingID, lastError, id).Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
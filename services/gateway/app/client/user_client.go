@@ -8,7 +8,6 @@ import (
 	"face-recognition-svc/gateway/app/utils"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
@@ -270,12 +269,12 @@ func (r *UserClient) CreateAccessToken(ctx context.Context, user *model.User, is
 
 	utils.LogEvent(span, "Request", user)
 
-	ExpireCount, _ := strconv.Atoi(r.cfg.Auth.AccessExpiry)
+	expiry := r.cfg.Auth.AccessTTL()
 	if isLogout {
-		ExpireCount = 0
+		expiry = 0
 	}
 
-	utils.LogEvent(span, "Expiry", ExpireCount)
+	utils.LogEvent(span, "Expiry", expiry.String())
 
 	permissions, err := r.GetUserPermission(ctx, user)
 	if err != nil {
@@ -283,7 +282,7 @@ func (r *UserClient) CreateAccessToken(ctx context.Context, user *model.User, is
 		return "", 0, err
 	}
 
-	exp := utils.LocalTime().Add(time.Hour * time.Duration(ExpireCount))
+	exp := utils.LocalTime().Add(expiry)
 	claims := &model.JwtCustomClaims{
 		UserID:      user.ID,
 		Username:    user.Username,
@@ -293,6 +292,7 @@ func (r *UserClient) CreateAccessToken(ctx context.Context, user *model.User, is
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
 		InstitutionID: user.InstitutionID,
+		TokenType:     model.TokenTypeAccess,
 	}
 	expired = exp.Unix()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -1,7 +1,72 @@
 package config
 
+import (
+	"strconv"
+	"time"
+)
+
 type Auth struct {
 	AccessSecret  string `yaml:"accessSecret"`
 	RefreshSecret string `yaml:"refreshSecret"`
-	AccessExpiry  string `yaml:"accessExpiry"`
+	AccessExpiry  string `yaml:"accessExpiry" default:"15m"`
+	RefreshExpiry string `yaml:"refreshExpiry" default:"720h"`
+
+	FaceLogin FaceLogin `yaml:"faceLogin"`
+}
+
+// AccessTTL is how long an access token is valid, 15 minutes unless configured. A bare number is read as hours,
+// as accessExpiry was before it took durations.
+func (a Auth) AccessTTL() time.Duration {
+	if hours, err := strconv.Atoi(a.AccessExpiry); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	d, err := time.ParseDuration(a.AccessExpiry)
+	if err != nil || d <= 0 {
+		return 15 * time.Minute
+	}
+	return d
+}
+
+// RefreshTTL is how long a refresh token can be used, 30 days unless configured. Every refresh starts it again.
+func (a Auth) RefreshTTL() time.Duration {
+	d, err := time.ParseDuration(a.RefreshExpiry)
+	if err != nil || d <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return d
+}
+
+// FaceLogin limits sign-in attempts with a photo. Each client address and each claimed username may try MaxAttempts
+// times per Window within an institution, and attempts without a username, which search every face of the
+// institution, are limited to MaxIdentifyAttempts per Window across the institution.
+type FaceLogin struct {
+	MaxAttempts         int    `yaml:"maxAttempts" default:"5"`
+	MaxIdentifyAttempts int    `yaml:"maxIdentifyAttempts" default:"100"`
+	Window              string `yaml:"window" default:"15m"`
+}
+
+// AttemptLimit is how many attempts are allowed per window, 5 unless configured.
+func (f FaceLogin) AttemptLimit() int64 {
+	if f.MaxAttempts <= 0 {
+		return 5
+	}
+	return int64(f.MaxAttempts)
+}
+
+// IdentifyAttemptLimit is how many attempts without a username an institution allows per window, 100 unless
+// configured.
+func (f FaceLogin) IdentifyAttemptLimit() int64 {
+	if f.MaxIdentifyAttempts <= 0 {
+		return 100
+	}
+	return int64(f.MaxIdentifyAttempts)
+}
+
+// WindowDuration is how long attempts are counted, 15 minutes unless configured.
+func (f FaceLogin) WindowDuration() time.Duration {
+	d, err := time.ParseDuration(f.Window)
+	if err != nil || d <= 0 {
+		return 15 * time.Minute
+	}
+	return d
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuthAccessTTL(t *testing.T) {
+	tests := []struct {
+		expiry string
+		want   time.Duration
+	}{
+		{expiry: "", want: 15 * time.Minute},
+		{expiry: "30m", want: 30 * time.Minute},
+		{expiry: "1h30m", want: 90 * time.Minute},
+		// A bare number is the hour count accessExpiry held before it took durations.
+		{expiry: "2", want: 2 * time.Hour},
+		{expiry: "184000", want: 184000 * time.Hour},
+		{expiry: "0", want: 15 * time.Minute},
+		{expiry: "-1", want: 15 * time.Minute},
+		{expiry: "-5m", want: 15 * time.Minute},
+		{expiry: "0s", want: 15 * time.Minute},
+		{expiry: "soon", want: 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := Auth{AccessExpiry: tt.expiry}.AccessTTL()
+		if got != tt.want {
+			t.Errorf("AccessTTL() with accessExpiry %q = %s, want %s", tt.expiry, got, tt.want)
+		}
+	}
+}
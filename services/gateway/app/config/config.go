@@ -2,8 +2,9 @@ package config
 
 type Config struct {
 	Listener struct {
-		Host string
-		Port int
+		Host           string
+		Port           int
+		TrustedProxies []string `yaml:"trustedProxies"`
 	}
 	DatabaseProfile struct {
 		Database Database `yaml:"database"`
@@ -15,6 +16,13 @@ type Config struct {
 	MinioProfile MinioS3     `yaml:"minioProfile"`
 	API          APIEndpoint `yaml:"api"`
 	RabbitMQ     RabbitMQ    `yaml:"rabbitmq"`
+	MessageBus   MessageBus  `yaml:"messageBus"`
+	Job          Job         `yaml:"job"`
+	Dataset      Dataset     `yaml:"dataset"`
+	Training     Training    `yaml:"training"`
+	Recognition  Recognition `yaml:"recognition"`
+	Device       Device      `yaml:"device"`
+	Grpc         Grpc        `yaml:"grpc"`
 }
 
 var config *Config
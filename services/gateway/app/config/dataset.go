@@ -0,0 +1,24 @@
+package config
+
+import "fmt"
+
+type Dataset struct {
+	MaxImageBytes int64    `yaml:"maxImageBytes" default:"5242880"`
+	MaxImages     int      `yaml:"maxImages" default:"20"`
+	FetchTimeout  string   `yaml:"fetchTimeout" default:"10s"`
+	AllowedHosts  []string `yaml:"allowedHosts"`
+}
+
+// UploadBodyLimit bounds the body of a dataset upload for echo's BodyLimit: maxImages images of maxImageBytes,
+// base64 encoded in the JSON form, plus 1 MiB for the other fields.
+func (d Dataset) UploadBodyLimit() string {
+	images := int64(d.MaxImages)
+	if images <= 0 {
+		images = 20
+	}
+	imageBytes := d.MaxImageBytes
+	if imageBytes <= 0 {
+		imageBytes = 5 << 20
+	}
+	return fmt.Sprintf("%dB", images*imageBytes*4/3+1<<20)
+}
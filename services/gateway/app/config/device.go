@@ -0,0 +1,17 @@
+package config
+
+import "time"
+
+// Device configures kiosks and cameras that authenticate with an API key.
+type Device struct {
+	HeartbeatTimeout string `yaml:"heartbeatTimeout" default:"5m"`
+}
+
+// OfflineAfter is how long after its last heartbeat a device is reported offline, 5 minutes unless configured.
+func (d Device) OfflineAfter() time.Duration {
+	timeout, err := time.ParseDuration(d.HeartbeatTimeout)
+	if err != nil || timeout <= 0 {
+		return 5 * time.Minute
+	}
+	return timeout
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// Grpc configures the gRPC recognition server that runs next to the HTTP listener. It is off unless Enabled, and
+// serves TLS from CertFile and KeyFile; plaintext needs Insecure, e.g. behind a TLS terminating proxy.
+type Grpc struct {
+	Enabled            bool    `yaml:"enabled"`
+	Host               string  `yaml:"host"`
+	Port               int     `yaml:"port" default:"9090"`
+	MaxMessageBytes    int     `yaml:"maxMessageBytes" default:"10485760"`
+	MaxFramesPerSecond float64 `yaml:"maxFramesPerSecond" default:"5"`
+	CertFile           string  `yaml:"certFile"`
+	KeyFile            string  `yaml:"keyFile"`
+	Insecure           bool    `yaml:"insecure"`
+	// Permission is required of every user calling with a bearer token. Defaults to DefaultGrpcPermission.
+	Permission string `yaml:"permission" default:"gateway.recognition.identify"`
+}
+
+// DefaultGrpcPermission is the permission users need to call the gRPC recognition API.
+const DefaultGrpcPermission = "gateway.recognition.identify"
+
+// Address is host:port the server listens on, port 9090 unless configured.
+func (g Grpc) Address() string {
+	port := g.Port
+	if port <= 0 {
+		port = 9090
+	}
+	return g.Host + ":" + strconv.Itoa(port)
+}
+
+// MessageLimit is the largest request message accepted, 10 MiB unless configured.
+func (g Grpc) MessageLimit() int {
+	if g.MaxMessageBytes <= 0 {
+		return 10 << 20
+	}
+	return g.MaxMessageBytes
+}
+
+// RequiredPermission returns Permission, or DefaultGrpcPermission when it is not set.
+func (g Grpc) RequiredPermission() string {
+	if g.Permission == "" {
+		return DefaultGrpcPermission
+	}
+	return g.Permission
+}
+
+// FrameInterval is the least time between two recognized frames of a stream, 200ms unless configured.
+func (g Grpc) FrameInterval() time.Duration {
+	if g.MaxFramesPerSecond <= 0 {
+		return 200 * time.Millisecond
+	}
+	return time.Duration(float64(time.Second) / g.MaxFramesPerSecond)
+}
@@ -0,0 +1,33 @@
+package config
+
+type Job struct {
+	DatasetReconcile struct {
+		Enabled  bool   `yaml:"enabled"`
+		Interval string `yaml:"interval" default:"24h"`
+		Repair   bool   `yaml:"repair"`
+		// GracePeriod protects uploads in progress: newer prefixes and rows are never repaired.
+		GracePeriod string `yaml:"gracePeriod" default:"1h"`
+	} `yaml:"datasetReconcile"`
+	OutboxRelay struct {
+		Enabled     bool   `yaml:"enabled"`
+		Interval    string `yaml:"interval" default:"2s"`
+		BatchSize   int    `yaml:"batchSize" default:"50"`
+		MaxAttempts int    `yaml:"maxAttempts" default:"10"`
+	} `yaml:"outboxRelay"`
+	TrainingSchedule struct {
+		Enabled   bool   `yaml:"enabled"`
+		Interval  string `yaml:"interval" default:"1m"`
+		LeaderTTL string `yaml:"leaderTTL" default:"2m"`
+	} `yaml:"trainingSchedule"`
+	TrainingReaper struct {
+		Enabled  bool   `yaml:"enabled"`
+		Interval string `yaml:"interval" default:"10m"`
+		// Timeout is how long a dispatched training may go without a status or progress update before it is failed.
+		Timeout string `yaml:"timeout" default:"6h"`
+	} `yaml:"trainingReaper"`
+	RecognitionReview struct {
+		Enabled   bool   `yaml:"enabled"`
+		Interval  string `yaml:"interval" default:"1h"`
+		BatchSize int    `yaml:"batchSize" default:"500"`
+	} `yaml:"recognitionReview"`
+}
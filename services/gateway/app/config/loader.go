@@ -14,6 +14,7 @@ func InitConfig() {
 	viper.AddConfigPath(basedir)
 	viper.SetConfigType("yaml")
 	viper.SetConfigName("config.yaml")
+	viper.SetDefault("messageBus.envelope", true)
 
 	if err := viper.MergeInConfig(); err != nil {
 		log.Panic().Err(err).Msg("Failed to load config")
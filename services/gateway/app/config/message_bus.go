@@ -0,0 +1,34 @@
+package config
+
+import "time"
+
+const (
+	MessageBusDriverAMQP   = "amqp"
+	MessageBusDriverMemory = "memory"
+)
+
+// MessageBus selects the broker behind the message bus. The memory driver keeps everything in process, for local
+// runs and tests; messages are lost on restart and are not shared between replicas.
+type MessageBus struct {
+	Driver         string `yaml:"driver" default:"amqp"`
+	RequestTimeout string `yaml:"requestTimeout" default:"10s"`
+	// Envelope wraps published messages in a CloudEvents envelope. It is on unless configured off; turn it off only
+	// while a consumer still reads bare messages, which carry only the data.
+	Envelope bool `yaml:"envelope" default:"true"`
+}
+
+func (m MessageBus) DriverName() string {
+	if m.Driver == "" {
+		return MessageBusDriverAMQP
+	}
+	return m.Driver
+}
+
+// RequestTimeoutDuration bounds request-reply calls whose context has no deadline, 10s unless configured.
+func (m MessageBus) RequestTimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(m.RequestTimeout)
+	if err != nil || d <= 0 {
+		return 10 * time.Second
+	}
+	return d
+}
@@ -0,0 +1,109 @@
+package config
+
+import "time"
+
+// Recognition holds the default recognition policy, used by institutions without a stored one, and the probe
+// storage settings.
+type Recognition struct {
+	MatchThreshold  float64 `yaml:"matchThreshold" default:"0.6"`
+	TopK            int     `yaml:"topK" default:"5"`
+	MinMargin       float64 `yaml:"minMargin"`
+	MaxModelAgeDays int     `yaml:"maxModelAgeDays"`
+	StoreProbes     bool    `yaml:"storeProbes"`
+	ProbeBucket     string  `yaml:"probeBucket"`
+
+	Batch  RecognitionBatch  `yaml:"batch"`
+	Review RecognitionReview `yaml:"review"`
+}
+
+// Threshold is the minimum score for a match, 0.6 unless configured.
+func (r Recognition) Threshold() float64 {
+	if r.MatchThreshold <= 0 || r.MatchThreshold > 1 {
+		return 0.6
+	}
+	return r.MatchThreshold
+}
+
+// Margin is the lead a match needs over the next best candidate, 0 unless configured.
+func (r Recognition) Margin() float64 {
+	if r.MinMargin < 0 || r.MinMargin >= 1 {
+		return 0
+	}
+	return r.MinMargin
+}
+
+// CandidateCount is how many candidates an identification returns, 5 unless configured.
+func (r Recognition) CandidateCount() int {
+	if r.TopK <= 0 {
+		return 5
+	}
+	return r.TopK
+}
+
+// RecognitionBatch configures group-photo recognition. It is off unless Enabled, and then needs CascadePath to
+// point to a pico face detection cascade such as "facefinder", which is not shipped with the gateway.
+type RecognitionBatch struct {
+	Enabled        bool    `yaml:"enabled"`
+	CascadePath    string  `yaml:"cascadePath"`
+	Concurrency    int     `yaml:"concurrency" default:"4"`
+	MaxFaces       int     `yaml:"maxFaces" default:"50"`
+	MinFaceSize    int     `yaml:"minFaceSize" default:"40"`
+	DetectionScore float64 `yaml:"detectionScore" default:"5"`
+}
+
+// Workers is how many faces of one photo are recognized in parallel, 4 unless configured.
+func (b RecognitionBatch) Workers() int {
+	if b.Concurrency <= 0 {
+		return 4
+	}
+	return b.Concurrency
+}
+
+// FaceLimit is the most faces recognized in one photo, 50 unless configured.
+func (b RecognitionBatch) FaceLimit() int {
+	if b.MaxFaces <= 0 {
+		return 50
+	}
+	return b.MaxFaces
+}
+
+// MinFaceSizePixels is the smallest face side searched for, 40 pixels unless configured.
+func (b RecognitionBatch) MinFaceSizePixels() int {
+	if b.MinFaceSize <= 0 {
+		return 40
+	}
+	return b.MinFaceSize
+}
+
+// MinDetectionScore is the cascade score a face must reach, 5 unless configured.
+func (b RecognitionBatch) MinDetectionScore() float64 {
+	if b.DetectionScore <= 0 {
+		return 5
+	}
+	return b.DetectionScore
+}
+
+// RecognitionReview configures the queue of unmatched faces. Review images are kept in the probe bucket for
+// Retention, and at most MaxPending faces per institution wait for a decision; further faces are not queued.
+type RecognitionReview struct {
+	Enabled    bool   `yaml:"enabled"`
+	Retention  string `yaml:"retention" default:"720h"`
+	MaxPending int    `yaml:"maxPending" default:"1000"`
+}
+
+// RetentionDuration is how long a review and its image are kept, 30 days unless configured.
+func (r RecognitionReview) RetentionDuration() time.Duration {
+	d, err := time.ParseDuration(r.Retention)
+	if err != nil || d <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return d
+}
+
+// PendingLimit is the most undecided reviews per institution, 1000 unless configured.
+func (r RecognitionReview) PendingLimit() int64 {
+	if r.MaxPending <= 0 {
+		return 1000
+	}
+	return int64(r.MaxPending)
+}
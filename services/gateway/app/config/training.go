@@ -0,0 +1,92 @@
+package config
+
+import "time"
+
+type Training struct {
+	// Queue is the main training queue the processing service consumes. Defaults to TrainModel.
+	Queue string `yaml:"queue" default:"TrainModel"`
+	// PriorityQueue declares Queue with TrainingQueueMaxPriority and the dead-letter exchange. RabbitMQ cannot
+	// add them to an existing queue, so enable it together with a new Queue name and the matching consumer.
+	PriorityQueue  bool           `yaml:"priorityQueue"`
+	LockTTL        string         `yaml:"lockTTL" default:"6h"`
+	RetryDelays    []string       `yaml:"retryDelays"`
+	MaxPriority    int            `yaml:"maxPriority" default:"10"`
+	TierPriorities map[string]int `yaml:"tierPriorities"`
+	PriorityAging  string         `yaml:"priorityAging" default:"10m"`
+	MaxInFlight    int            `yaml:"maxInFlight"`
+	// MaxInFlightPerInstitution caps the dispatched, unfinished trainings of one institution. 0 means no cap.
+	MaxInFlightPerInstitution int `yaml:"maxInFlightPerInstitution"`
+}
+
+// TrainingQueueMaxPriority is the x-max-priority the training queue is declared with. It does not follow
+// MaxPriority because RabbitMQ refuses to redeclare a queue with other arguments.
+const TrainingQueueMaxPriority = 10
+
+// DefaultTrainingQueue is the queue the processing service consumes. It is declared auto-delete without arguments,
+// as it always was.
+const DefaultTrainingQueue = "TrainModel"
+
+// QueueName returns Queue, or DefaultTrainingQueue when it is not set.
+func (t Training) QueueName() string {
+	if t.Queue == "" {
+		return DefaultTrainingQueue
+	}
+	return t.Queue
+}
+
+// RetryDurations parses RetryDelays, skipping invalid entries. Its length is the number of retries a rejected
+// training message gets before it is parked as dead letter.
+func (t Training) RetryDurations() []time.Duration {
+	var delays []time.Duration
+	for _, delay := range t.RetryDelays {
+		d, err := time.ParseDuration(delay)
+		if err != nil || d <= 0 {
+			continue
+		}
+		delays = append(delays, d)
+	}
+	return delays
+}
+
+// MaxPriorityLevel is the highest priority a training is published with. MaxPriority can only lower it below
+// TrainingQueueMaxPriority.
+func (t Training) MaxPriorityLevel() int {
+	if t.MaxPriority <= 0 || t.MaxPriority > TrainingQueueMaxPriority {
+		return TrainingQueueMaxPriority
+	}
+	return t.MaxPriority
+}
+
+// TierPriority returns the queue priority of an institution tier, clamped to MaxPriorityLevel. Unknown tiers get 0.
+func (t Training) TierPriority(tier string) int {
+	priority := t.TierPriorities[tier]
+	if priority > t.MaxPriorityLevel() {
+		return t.MaxPriorityLevel()
+	}
+	if priority < 0 {
+		return 0
+	}
+	return priority
+}
+
+// PriorityAgingInterval parses PriorityAging and returns 0, meaning no aging, when it is not a valid duration.
+func (t Training) PriorityAgingInterval() time.Duration {
+	aging, err := time.ParseDuration(t.PriorityAging)
+	if err != nil || aging <= 0 {
+		return 0
+	}
+	return aging
+}
+
+// EffectivePriority raises priority by one for every PriorityAging a job has waited, so jobs of low tiers are
+// not starved by a steady stream of high tier jobs.
+func (t Training) EffectivePriority(priority int, waited time.Duration) int {
+	aging := t.PriorityAgingInterval()
+	if aging > 0 && waited > 0 {
+		priority += int(waited / aging)
+	}
+	if priority > t.MaxPriorityLevel() {
+		return t.MaxPriorityLevel()
+	}
+	return priority
+}
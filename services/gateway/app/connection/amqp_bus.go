@@ -0,0 +1,392 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+// AMQPBus publishes through the confirm-mode Publisher and uses a second connection for declarations, consumers
+// and request-reply, so a slow consumer never holds up publishing.
+type AMQPBus struct {
+	url            string
+	publisher      *Publisher
+	requestTimeout time.Duration
+
+	mu   sync.Mutex
+	conn *amqp.Connection
+
+	replyMu sync.Mutex
+	replyCh *amqp.Channel
+	pending map[string]pendingRequest
+}
+
+// pendingRequest remembers the channel a request was published on, because its reply can only arrive there.
+type pendingRequest struct {
+	ch     *amqp.Channel
+	result chan requestResult
+}
+
+type requestResult struct {
+	reply *Message
+	err   error
+}
+
+func NewAMQPBus(c *config.RabbitMQ, busConfig *config.MessageBus) *AMQPBus {
+	b := &AMQPBus{
+		url:            fmt.Sprintf("amqp://%s:%s@%s:%s/", c.Username, c.Password, c.Host, c.Port),
+		publisher:      NewPublisher(c),
+		requestTimeout: busConfig.RequestTimeoutDuration(),
+		pending:        make(map[string]pendingRequest),
+	}
+
+	if _, err := b.connection(); err != nil {
+		log.Panic().Err(err).Msg("Cannot Connect To RabbitMQ")
+	}
+	log.Info().Str("host", c.Host).Str("port", c.Port).Msg("Connected To RabbitMQ")
+
+	return b
+}
+
+// connection returns the consumer connection, dialling again if it was closed.
+func (b *AMQPBus) connection() (*amqp.Connection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn != nil && !b.conn.IsClosed() {
+		return b.conn, nil
+	}
+
+	conn, err := amqp.Dial(b.url)
+	if err != nil {
+		return nil, err
+	}
+	b.conn = conn
+
+	return conn, nil
+}
+
+// Declare uses a short-lived channel because a failed declaration closes the channel it ran on.
+func (b *AMQPBus) Declare(ctx context.Context, topology Topology) error {
+	conn, err := b.connection()
+	if err != nil {
+		return err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for _, exchange := range topology.Exchanges {
+		err := ch.ExchangeDeclare(
+			exchange.Name, // Exchange name
+			exchange.Kind, // Type
+			true,          // Durable
+			false,         // Auto-deleted
+			false,         // Internal
+			false,         // No-wait
+			nil,           // Arguments
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, queue := range topology.Queues {
+		_, err := ch.QueueDeclare(
+			queue.Name,            // Queue name
+			true,                  // Durable
+			queue.AutoDelete,      // Delete when unused
+			false,                 // Exclusive
+			false,                 // No-wait
+			queueArguments(queue), // Arguments
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, binding := range topology.Bindings {
+		err := ch.QueueBind(
+			binding.Queue,    // Queue name
+			binding.Key,      // Routing key
+			binding.Exchange, // Exchange
+			false,            // No-wait
+			nil,              // Arguments
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func queueArguments(queue Queue) amqp.Table {
+	args := amqp.Table{}
+	if queue.MaxPriority > 0 {
+		args["x-max-priority"] = int32(queue.MaxPriority)
+	}
+	if queue.MessageTTL > 0 {
+		args["x-message-ttl"] = queue.MessageTTL.Milliseconds()
+	}
+	if queue.DeadLetter != nil {
+		args["x-dead-letter-exchange"] = queue.DeadLetter.Exchange
+		if queue.DeadLetter.RoutingKey != "" {
+			args["x-dead-letter-routing-key"] = queue.DeadLetter.RoutingKey
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+func (b *AMQPBus) Publish(ctx context.Context, exchange string, key string, msg Message) error {
+	return b.publisher.Publish(ctx, exchange, key, toPublishing(msg))
+}
+
+// Consume opens a new channel for queue, redialing first if the connection was lost. The returned channel is closed
+// when ctx is done or the channel or connection is lost; consume again to resubscribe.
+func (b *AMQPBus) Consume(ctx context.Context, queue string) (<-chan *Delivery, error) {
+	conn, err := b.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := ch.Consume(
+		queue, // Queue name
+		"",    // Consumer tag
+		false, // Auto-ack
+		false, // Exclusive
+		false, // No-local
+		false, // No-wait
+		nil,   // Arguments
+	)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	out := make(chan *Delivery)
+	go func() {
+		defer close(out)
+		defer ch.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+
+				select {
+				case out <- fromDelivery(d):
+				case <-ctx.Done():
+					d.Nack(false, true)
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Request publishes msg with RabbitMQ direct reply-to and waits for the reply with the same correlation id. A
+// context without deadline is bounded by the configured request timeout, and the message expires with it.
+func (b *AMQPBus) Request(ctx context.Context, exchange string, key string, msg Message) (*Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.requestTimeout)
+		defer cancel()
+	}
+
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	if msg.CorrelationID == "" {
+		msg.CorrelationID = msg.ID
+	}
+	msg.ReplyTo = ReplyToQueue
+	if msg.Expiration == 0 {
+		msg.Expiration = requestExpiration(ctx)
+	}
+
+	result := make(chan requestResult, 1)
+
+	b.replyMu.Lock()
+	ch, err := b.replyChannel()
+	if err != nil {
+		b.replyMu.Unlock()
+		return nil, err
+	}
+	b.pending[msg.CorrelationID] = pendingRequest{ch: ch, result: result}
+	// Direct reply-to requires publishing on the channel that consumes the replies.
+	err = ch.PublishWithContext(ctx, exchange, key, true, false, toPublishing(msg))
+	b.replyMu.Unlock()
+
+	defer func() {
+		b.replyMu.Lock()
+		delete(b.pending, msg.CorrelationID)
+		b.replyMu.Unlock()
+	}()
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-result:
+		return res.reply, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// replyChannel returns the channel consuming direct replies, opening it if needed. Callers hold replyMu.
+func (b *AMQPBus) replyChannel() (*amqp.Channel, error) {
+	if b.replyCh != nil && !b.replyCh.IsClosed() {
+		return b.replyCh, nil
+	}
+
+	conn, err := b.connection()
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	replies, err := ch.Consume(
+		ReplyToQueue, // Queue name
+		"",           // Consumer tag
+		true,         // Auto-ack (required by direct reply-to)
+		false,        // Exclusive
+		false,        // No-local
+		false,        // No-wait
+		nil,          // Arguments
+	)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	returns := ch.NotifyReturn(make(chan amqp.Return, 16))
+	b.replyCh = ch
+
+	go b.dispatchReplies(ch, replies, returns)
+
+	return ch, nil
+}
+
+func (b *AMQPBus) dispatchReplies(ch *amqp.Channel, replies <-chan amqp.Delivery, returns <-chan amqp.Return) {
+	for {
+		select {
+		case d, ok := <-replies:
+			if !ok {
+				b.failPending(ch, errors.New("rabbitmq reply channel closed"))
+				return
+			}
+			b.resolve(d.CorrelationId, requestResult{reply: &fromDelivery(d).Message})
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			b.resolve(r.CorrelationId, requestResult{err: fmt.Errorf("%w: %s", ErrMessageReturned, r.ReplyText)})
+		}
+	}
+}
+
+func (b *AMQPBus) resolve(correlationID string, res requestResult) {
+	b.replyMu.Lock()
+	defer b.replyMu.Unlock()
+
+	if pending, ok := b.pending[correlationID]; ok {
+		pending.result <- res
+		delete(b.pending, correlationID)
+	}
+}
+
+// failPending fails the requests published on ch. Requests already published on a newer reply channel keep
+// waiting for their replies.
+func (b *AMQPBus) failPending(ch *amqp.Channel, err error) {
+	b.replyMu.Lock()
+	defer b.replyMu.Unlock()
+
+	for correlationID, pending := range b.pending {
+		if pending.ch != ch {
+			continue
+		}
+		pending.result <- requestResult{err: err}
+		delete(b.pending, correlationID)
+	}
+}
+
+func (b *AMQPBus) Health() PublisherHealth {
+	return b.publisher.Health()
+}
+
+func toPublishing(msg Message) amqp.Publishing {
+	var expiration string
+	if msg.Expiration > 0 {
+		expiration = strconv.FormatInt(max(msg.Expiration.Milliseconds(), 1), 10)
+	}
+
+	return amqp.Publishing{
+		ContentType:   msg.ContentType,
+		Type:          msg.Type,
+		MessageId:     msg.ID,
+		CorrelationId: msg.CorrelationID,
+		ReplyTo:       msg.ReplyTo,
+		Priority:      msg.Priority,
+		Timestamp:     msg.Timestamp,
+		Expiration:    expiration,
+		Headers:       amqp.Table(msg.Headers),
+		Body:          msg.Body,
+		DeliveryMode:  amqp.Persistent,
+	}
+}
+
+func fromDelivery(d amqp.Delivery) *Delivery {
+	return &Delivery{
+		Message: Message{
+			ID:            d.MessageId,
+			Type:          d.Type,
+			ContentType:   d.ContentType,
+			CorrelationID: d.CorrelationId,
+			ReplyTo:       d.ReplyTo,
+			Priority:      d.Priority,
+			Timestamp:     d.Timestamp,
+			Headers:       map[string]any(d.Headers),
+			Body:          d.Body,
+		},
+		Redelivered: d.Redelivered,
+		ack: func() error {
+			return d.Ack(false)
+		},
+		nack: func(requeue bool) error {
+			return d.Nack(false, requeue)
+		},
+	}
+}
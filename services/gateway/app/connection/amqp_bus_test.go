@@ -0,0 +1,48 @@
+package connection
+
+import (
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestFailPendingOnlyFailsRequestsOfTheClosedChannel(t *testing.T) {
+	closed, current := &amqp.Channel{}, &amqp.Channel{}
+	stale, fresh := make(chan requestResult, 1), make(chan requestResult, 1)
+
+	b := &AMQPBus{pending: map[string]pendingRequest{
+		"stale": {ch: closed, result: stale},
+		"fresh": {ch: current, result: fresh},
+	}}
+
+	errClosed := errors.New("closed")
+	b.failPending(closed, errClosed)
+
+	select {
+	case res := <-stale:
+		if !errors.Is(res.err, errClosed) {
+			t.Errorf("stale request err = %v, want %v", res.err, errClosed)
+		}
+	default:
+		t.Error("request on the closed channel was not failed")
+	}
+
+	select {
+	case res := <-fresh:
+		t.Errorf("request on the newer channel was resolved with %+v", res)
+	default:
+	}
+
+	if _, ok := b.pending["fresh"]; !ok {
+		t.Error("request on the newer channel was removed from pending")
+	}
+	if _, ok := b.pending["stale"]; ok {
+		t.Error("failed request is still pending")
+	}
+
+	b.resolve("fresh", requestResult{reply: &Message{ID: "reply"}})
+	if res := <-fresh; res.reply == nil || res.reply.ID != "reply" {
+		t.Errorf("fresh request reply = %+v", res.reply)
+	}
+}
@@ -0,0 +1,117 @@
+package connection
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/config"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ExchangeDirect = "direct"
+	ExchangeFanout = "fanout"
+
+	// ReplyToQueue is the pseudo-queue Request asks replies to be sent to. Responders publish the reply to the
+	// default exchange with the request's ReplyTo as routing key and its CorrelationID.
+	ReplyToQueue = "amq.rabbitmq.reply-to"
+)
+
+// InterfaceMessageBus is what clients and workers use to talk to the broker, so that nothing above this package
+// depends on AMQP. Publish returns ErrMessageReturned when no queue is bound for the message.
+type InterfaceMessageBus interface {
+	Declare(ctx context.Context, topology Topology) error
+	Publish(ctx context.Context, exchange string, key string, msg Message) error
+	Consume(ctx context.Context, queue string) (<-chan *Delivery, error)
+	Request(ctx context.Context, exchange string, key string, msg Message) (*Message, error)
+	Health() PublisherHealth
+}
+
+type Message struct {
+	ID            string
+	Type          string
+	ContentType   string
+	CorrelationID string
+	ReplyTo       string
+	Priority      uint8
+	Timestamp     time.Time
+	// Expiration drops the message if it is still queued after this long. Zero keeps it until the queue's TTL.
+	Expiration time.Duration
+	Headers    map[string]any
+	Body       []byte
+}
+
+// Delivery is a consumed message. It must be acknowledged with Ack or Nack exactly once.
+type Delivery struct {
+	Message
+	Redelivered bool
+
+	ack  func() error
+	nack func(requeue bool) error
+}
+
+func (d *Delivery) Ack() error {
+	return d.ack()
+}
+
+// Nack rejects the delivery. Without requeue it is dead-lettered if its queue has a DeadLetter, otherwise dropped.
+func (d *Delivery) Nack(requeue bool) error {
+	return d.nack(requeue)
+}
+
+// Topology lists exchanges, queues and bindings to declare. Everything is durable and declaring is idempotent.
+type Topology struct {
+	Exchanges []Exchange
+	Queues    []Queue
+	Bindings  []Binding
+}
+
+type Exchange struct {
+	Name string
+	Kind string
+}
+
+type Queue struct {
+	Name        string
+	AutoDelete  bool
+	MaxPriority int
+	MessageTTL  time.Duration
+	DeadLetter  *DeadLetter
+}
+
+// DeadLetter routes expired and rejected messages. An empty Exchange is the default exchange, where the routing
+// key is the queue name; an empty RoutingKey keeps the message's own key.
+type DeadLetter struct {
+	Exchange   string
+	RoutingKey string
+}
+
+type Binding struct {
+	Queue    string
+	Exchange string
+	Key      string
+}
+
+func NewMessageBus(c *config.Config) InterfaceMessageBus {
+	switch c.MessageBus.DriverName() {
+	case config.MessageBusDriverMemory:
+		log.Warn().Msg("Using the in-memory message bus; messages are not shared between replicas")
+		return NewMemoryBus(&c.MessageBus)
+	case config.MessageBusDriverAMQP:
+		return NewAMQPBus(&c.RabbitMQ, &c.MessageBus)
+	default:
+		log.Panic().Str("driver", c.MessageBus.Driver).Msg("Unknown message bus driver")
+		return nil
+	}
+}
+
+// requestExpiration is what is left of a request's deadline, so that a request nobody picks up in time is dropped
+// instead of being answered to a caller that has already given up.
+func requestExpiration(ctx context.Context) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0
+	}
+
+	return max(time.Until(deadline), time.Millisecond)
+}
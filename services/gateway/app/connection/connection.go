@@ -11,7 +11,6 @@ import (
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"gorm.io/driver/postgres"
@@ -22,14 +21,14 @@ var (
 	Db      *gorm.DB
 	Storage *s3.S3
 	Redis   *redis.Client
-	Mq      *amqp.Channel
+	Bus     InterfaceMessageBus
 )
 
 func InitConnection(c config.Config) {
 	Db = NewDatabaseConnection(&c.DatabaseProfile.Database)
 	Storage = NewStorageConnection(&c.MinioProfile)
 	Redis = NewRedisConnection(&c.Redis, context.Background())
-	Mq = NewRabbitMQConnection(&c.RabbitMQ)
+	Bus = NewMessageBus(&c)
 }
 
 func NewDatabaseConnection(c *config.Database) *gorm.DB {
@@ -107,19 +106,3 @@ func NewRedisConnection(c *config.Redis, ctx context.Context) *redis.Client {
 	return rdb
 
 }
-
-func NewRabbitMQConnection(c *config.RabbitMQ) *amqp.Channel {
-	log.Info().Str("url", fmt.Sprintf("amqp://%s:%s@%s:%s/", c.Username, c.Password, c.Host, c.Port)).Msg("RabbitMQ connection string")
-	conn, err := amqp.Dial(fmt.Sprintf("amqp://%s:%s@%s:%s/", c.Username, c.Password, c.Host, c.Port))
-	if err != nil {
-		log.Panic().Err(err).Msg("Cannot Connect To RabbitMQ")
-	}
-	log.Info().Str("host", c.Host).Str("port", c.Port).Msg("Connected To RabbitMQ")
-
-	ch, err := conn.Channel()
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to open a channel")
-	}
-
-	return ch
-}
@@ -0,0 +1,356 @@
+package connection
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/config"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryBus is an in-process broker with the subset of RabbitMQ semantics the gateway relies on: direct and
+// fanout exchanges, the default exchange, queue priorities, message TTL, dead-lettering, requeue on Nack and
+// direct reply-to. Nothing is persisted.
+type MemoryBus struct {
+	requestTimeout time.Duration
+	connectedAt    time.Time
+
+	mu        sync.Mutex
+	exchanges map[string]string
+	bindings  map[string][]Binding
+	queues    map[string]*memoryQueue
+	pending   map[string]chan *Message
+}
+
+type memoryQueue struct {
+	def      Queue
+	messages []*memoryMessage
+	// notify is closed and replaced whenever a message is added, waking every waiting consumer.
+	notify chan struct{}
+}
+
+type memoryMessage struct {
+	msg         Message
+	key         string
+	redelivered bool
+}
+
+func NewMemoryBus(c *config.MessageBus) *MemoryBus {
+	return &MemoryBus{
+		requestTimeout: c.RequestTimeoutDuration(),
+		connectedAt:    time.Now(),
+		exchanges:      make(map[string]string),
+		bindings:       make(map[string][]Binding),
+		queues:         make(map[string]*memoryQueue),
+		pending:        make(map[string]chan *Message),
+	}
+}
+
+func (b *MemoryBus) Declare(ctx context.Context, topology Topology) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, exchange := range topology.Exchanges {
+		if kind, ok := b.exchanges[exchange.Name]; ok && kind != exchange.Kind {
+			return fmt.Errorf("exchange %q already declared as %s", exchange.Name, kind)
+		}
+		if exchange.Kind != ExchangeDirect && exchange.Kind != ExchangeFanout {
+			return fmt.Errorf("exchange kind %q is not supported", exchange.Kind)
+		}
+		b.exchanges[exchange.Name] = exchange.Kind
+	}
+
+	for _, queue := range topology.Queues {
+		if _, ok := b.queues[queue.Name]; ok {
+			continue
+		}
+		b.queues[queue.Name] = &memoryQueue{def: queue, notify: make(chan struct{})}
+	}
+
+	for _, binding := range topology.Bindings {
+		if _, ok := b.exchanges[binding.Exchange]; !ok {
+			return fmt.Errorf("exchange %q is not declared", binding.Exchange)
+		}
+		if _, ok := b.queues[binding.Queue]; !ok {
+			return fmt.Errorf("queue %q is not declared", binding.Queue)
+		}
+		if !slices.Contains(b.bindings[binding.Exchange], binding) {
+			b.bindings[binding.Exchange] = append(b.bindings[binding.Exchange], binding)
+		}
+	}
+
+	return nil
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, exchange string, key string, msg Message) error {
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if exchange == "" && strings.HasPrefix(key, ReplyToQueue) {
+		reply, ok := b.pending[msg.CorrelationID]
+		if !ok {
+			return fmt.Errorf("%w: no request waiting for %s", ErrMessageReturned, msg.CorrelationID)
+		}
+		reply <- &msg
+		delete(b.pending, msg.CorrelationID)
+		return nil
+	}
+
+	queues, err := b.route(exchange, key)
+	if err != nil {
+		return err
+	}
+	if len(queues) == 0 {
+		return fmt.Errorf("%w: NO_ROUTE", ErrMessageReturned)
+	}
+
+	for _, queue := range queues {
+		b.enqueue(queue, &memoryMessage{msg: copyMessage(msg), key: key})
+	}
+
+	return nil
+}
+
+// route returns the queues a message published to exchange with key is delivered to. Callers hold mu.
+func (b *MemoryBus) route(exchange string, key string) ([]*memoryQueue, error) {
+	if exchange == "" {
+		if queue, ok := b.queues[key]; ok {
+			return []*memoryQueue{queue}, nil
+		}
+		return nil, nil
+	}
+
+	kind, ok := b.exchanges[exchange]
+	if !ok {
+		return nil, fmt.Errorf("exchange %q is not declared", exchange)
+	}
+
+	var queues []*memoryQueue
+	for _, binding := range b.bindings[exchange] {
+		if kind == ExchangeDirect && binding.Key != key {
+			continue
+		}
+		queues = append(queues, b.queues[binding.Queue])
+	}
+
+	return queues, nil
+}
+
+// enqueue keeps a priority queue ordered by priority, then arrival. Callers hold mu.
+func (b *MemoryBus) enqueue(queue *memoryQueue, m *memoryMessage) {
+	if queue.def.MaxPriority > 0 {
+		m.msg.Priority = min(m.msg.Priority, uint8(queue.def.MaxPriority))
+		i := len(queue.messages)
+		for i > 0 && queue.messages[i-1].msg.Priority < m.msg.Priority {
+			i--
+		}
+		queue.messages = slices.Insert(queue.messages, i, m)
+	} else {
+		m.msg.Priority = 0
+		queue.messages = append(queue.messages, m)
+	}
+
+	ttl := queue.def.MessageTTL
+	if m.msg.Expiration > 0 && (ttl == 0 || m.msg.Expiration < ttl) {
+		ttl = m.msg.Expiration
+	}
+	if ttl > 0 {
+		time.AfterFunc(ttl, func() { b.expire(queue, m) })
+	}
+
+	close(queue.notify)
+	queue.notify = make(chan struct{})
+}
+
+func (b *MemoryBus) expire(queue *memoryQueue, m *memoryMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	i := slices.Index(queue.messages, m)
+	if i < 0 {
+		return
+	}
+	queue.messages = slices.Delete(queue.messages, i, i+1)
+
+	b.deadLetter(queue, m, "expired")
+}
+
+// deadLetter republishes m through the queue's DeadLetter, recording the first death like RabbitMQ does.
+// Callers hold mu.
+func (b *MemoryBus) deadLetter(queue *memoryQueue, m *memoryMessage, reason string) {
+	if queue.def.DeadLetter == nil {
+		return
+	}
+
+	msg := copyMessage(m.msg)
+	// Like RabbitMQ, the per-message expiration is removed so that it cannot expire again where it is sent.
+	msg.Expiration = 0
+	if _, ok := msg.Headers["x-first-death-reason"]; !ok {
+		msg.Headers["x-first-death-reason"] = reason
+		msg.Headers["x-first-death-queue"] = queue.def.Name
+	}
+
+	key := queue.def.DeadLetter.RoutingKey
+	if key == "" {
+		key = m.key
+	}
+
+	queues, _ := b.route(queue.def.DeadLetter.Exchange, key)
+	for _, target := range queues {
+		b.enqueue(target, &memoryMessage{msg: copyMessage(msg), key: key})
+	}
+}
+
+func (b *MemoryBus) Consume(ctx context.Context, queue string) (<-chan *Delivery, error) {
+	b.mu.Lock()
+	q, ok := b.queues[queue]
+	b.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("queue %q is not declared", queue)
+	}
+
+	out := make(chan *Delivery)
+	go func() {
+		defer close(out)
+
+		for {
+			m, notify := b.next(q)
+			if m == nil {
+				select {
+				case <-notify:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case out <- b.delivery(q, m):
+			case <-ctx.Done():
+				b.requeue(q, m)
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// next takes the head of the queue, or returns the channel to wait on when it is empty.
+func (b *MemoryBus) next(queue *memoryQueue) (*memoryMessage, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(queue.messages) == 0 {
+		return nil, queue.notify
+	}
+
+	m := queue.messages[0]
+	queue.messages = queue.messages[1:]
+
+	return m, nil
+}
+
+func (b *MemoryBus) requeue(queue *memoryQueue, m *memoryMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue.messages = slices.Insert(queue.messages, 0, m)
+	close(queue.notify)
+	queue.notify = make(chan struct{})
+}
+
+func (b *MemoryBus) delivery(queue *memoryQueue, m *memoryMessage) *Delivery {
+	var once sync.Once
+	return &Delivery{
+		Message:     copyMessage(m.msg),
+		Redelivered: m.redelivered,
+		ack: func() error {
+			once.Do(func() {})
+			return nil
+		},
+		nack: func(requeue bool) error {
+			once.Do(func() {
+				if requeue {
+					m.redelivered = true
+					b.requeue(queue, m)
+					return
+				}
+
+				b.mu.Lock()
+				defer b.mu.Unlock()
+				b.deadLetter(queue, m, "rejected")
+			})
+			return nil
+		},
+	}
+}
+
+func (b *MemoryBus) Request(ctx context.Context, exchange string, key string, msg Message) (*Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, b.requestTimeout)
+		defer cancel()
+	}
+
+	if msg.ID == "" {
+		msg.ID = uuid.New().String()
+	}
+	if msg.CorrelationID == "" {
+		msg.CorrelationID = msg.ID
+	}
+	msg.ReplyTo = ReplyToQueue
+	if msg.Expiration == 0 {
+		msg.Expiration = requestExpiration(ctx)
+	}
+
+	reply := make(chan *Message, 1)
+
+	b.mu.Lock()
+	b.pending[msg.CorrelationID] = reply
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		delete(b.pending, msg.CorrelationID)
+		b.mu.Unlock()
+	}()
+
+	if err := b.Publish(ctx, exchange, key, msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case res := <-reply:
+		return res, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *MemoryBus) Health() PublisherHealth {
+	return PublisherHealth{
+		Connected:       true,
+		LastConnectedAt: &b.connectedAt,
+	}
+}
+
+// copyMessage gives every queue its own headers, as a broker would.
+func copyMessage(msg Message) Message {
+	headers := make(map[string]any, len(msg.Headers))
+	maps.Copy(headers, msg.Headers)
+	msg.Headers = headers
+	return msg
+}
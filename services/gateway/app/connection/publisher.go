@@ -0,0 +1,216 @@
+package connection
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrPublisherUnavailable = errors.New("rabbitmq publisher is not connected")
+	ErrMessageNacked        = errors.New("rabbitmq did not confirm the message")
+	ErrMessageReturned      = errors.New("rabbitmq returned the message as unroutable")
+)
+
+const (
+	publisherMinBackoff     = time.Second
+	publisherMaxBackoff     = 30 * time.Second
+	publisherConfirmTimeout = 10 * time.Second
+)
+
+type PublisherHealth struct {
+	Connected       bool       `json:"connected"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastConnectedAt *time.Time `json:"last_connected_at"`
+	Reconnects      int        `json:"reconnects"`
+}
+
+// Publisher owns a dedicated RabbitMQ connection in confirm mode. It reconnects with backoff whenever the
+// connection or channel closes, and serialises publishes so each confirmation and return can be matched to
+// the message that caused it.
+type Publisher struct {
+	url string
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	ch      *amqp.Channel
+	returns chan amqp.Return
+
+	healthMu sync.RWMutex
+	health   PublisherHealth
+}
+
+func NewPublisher(c *config.RabbitMQ) *Publisher {
+	p := &Publisher{
+		url: fmt.Sprintf("amqp://%s:%s@%s:%s/", c.Username, c.Password, c.Host, c.Port),
+	}
+
+	go p.run()
+
+	return p
+}
+
+func (p *Publisher) run() {
+	backoff := publisherMinBackoff
+	for {
+		closed, err := p.connect()
+		if err != nil {
+			p.setHealth(false, err)
+			log.Error().Err(err).Dur("retry_in", backoff).Msg("RabbitMQ publisher cannot connect")
+			time.Sleep(backoff)
+			backoff = min(backoff*2, publisherMaxBackoff)
+			continue
+		}
+
+		backoff = publisherMinBackoff
+		p.setHealth(true, nil)
+		log.Info().Msg("RabbitMQ publisher connected")
+
+		err = <-closed
+		p.disconnect()
+		p.setHealth(false, err)
+		log.Warn().Err(err).Msg("RabbitMQ publisher connection lost, reconnecting")
+	}
+}
+
+// connect returns a channel that receives once either the connection or the channel is closed.
+func (p *Publisher) connect() (<-chan error, error) {
+	conn, err := amqp.Dial(p.url)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	closed := make(chan error, 2)
+	forward := func(source chan *amqp.Error) {
+		if err, ok := <-source; ok && err != nil {
+			closed <- err
+			return
+		}
+		closed <- errors.New("closed")
+	}
+	go forward(conn.NotifyClose(make(chan *amqp.Error, 1)))
+	go forward(ch.NotifyClose(make(chan *amqp.Error, 1)))
+
+	p.mu.Lock()
+	p.conn = conn
+	p.ch = ch
+	p.returns = ch.NotifyReturn(make(chan amqp.Return, 16))
+	p.mu.Unlock()
+
+	return closed, nil
+}
+
+func (p *Publisher) disconnect() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.conn != nil && !p.conn.IsClosed() {
+		p.conn.Close()
+	}
+	p.conn = nil
+	p.ch = nil
+	p.returns = nil
+}
+
+// Publish sends msg as mandatory and waits for the broker's confirmation. A message that cannot be routed to
+// any queue is reported as ErrMessageReturned.
+func (p *Publisher) Publish(ctx context.Context, exchange string, key string, msg amqp.Publishing) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.ch == nil {
+		return ErrPublisherUnavailable
+	}
+
+	p.drainReturns()
+
+	if msg.MessageId == "" {
+		msg.MessageId = uuid.New().String()
+	}
+
+	confirmation, err := p.ch.PublishWithDeferredConfirmWithContext(ctx, exchange, key, true, false, msg)
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, publisherConfirmTimeout)
+	defer cancel()
+
+	acked, err := confirmation.WaitContext(waitCtx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return ErrMessageNacked
+	}
+
+	// The broker sends basic.return before the ack of the same message, so it is already buffered here.
+	select {
+	case returned, ok := <-p.returns:
+		if ok && returned.MessageId == msg.MessageId {
+			return fmt.Errorf("%w: %s", ErrMessageReturned, returned.ReplyText)
+		}
+	default:
+	}
+
+	return nil
+}
+
+func (p *Publisher) Health() PublisherHealth {
+	p.healthMu.RLock()
+	defer p.healthMu.RUnlock()
+
+	return p.health
+}
+
+// drainReturns discards returns left over from earlier messages. The returns channel is closed together with its
+// AMQP channel, and a closed channel is always ready, so the loop stops there too.
+func (p *Publisher) drainReturns() {
+	for {
+		select {
+		case _, ok := <-p.returns:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *Publisher) setHealth(connected bool, err error) {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+
+	if connected {
+		now := time.Now()
+		if p.health.LastConnectedAt != nil {
+			p.health.Reconnects++
+		}
+		p.health.LastConnectedAt = &now
+	}
+
+	p.health.Connected = connected
+	p.health.LastError = ""
+	if err != nil {
+		p.health.LastError = err.Error()
+	}
+}
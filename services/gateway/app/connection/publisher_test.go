@@ -0,0 +1,206 @@
+package connection
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeBroker speaks just enough AMQP 0-9-1 for a Publisher: the connection handshake, one channel in confirm
+// mode and basic.publish. The first connection is dropped as soon as a message is published; later connections
+// ack every message.
+type fakeBroker struct {
+	ln          net.Listener
+	connections atomic.Int32
+}
+
+func newFakeBroker(t *testing.T) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	b := &fakeBroker{ln: ln}
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) url() string {
+	return fmt.Sprintf("amqp://guest:guest@%s/", b.ln.Addr())
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(conn, b.connections.Add(1) == 1)
+	}
+}
+
+func (b *fakeBroker) handle(conn net.Conn, dropOnPublish bool) {
+	defer conn.Close()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	// connection.start: version 0-9, no server properties, PLAIN, en_US.
+	start := []byte{0, 9}
+	start = binary.BigEndian.AppendUint32(start, 0)
+	start = appendLongstr(start, "PLAIN")
+	start = appendLongstr(start, "en_US")
+	writeMethod(conn, 0, 10, 10, start)
+
+	var deliveryTag uint64
+	for {
+		frameType, channel, payload, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+		if frameType != 1 {
+			continue
+		}
+
+		class, method := binary.BigEndian.Uint16(payload[0:2]), binary.BigEndian.Uint16(payload[2:4])
+		switch {
+		case class == 10 && method == 11: // connection.start-ok
+			tune := binary.BigEndian.AppendUint16(nil, 0)
+			tune = binary.BigEndian.AppendUint32(tune, 131072)
+			tune = binary.BigEndian.AppendUint16(tune, 0)
+			writeMethod(conn, 0, 10, 30, tune)
+		case class == 10 && method == 40: // connection.open
+			writeMethod(conn, 0, 10, 41, []byte{0})
+		case class == 10 && method == 50: // connection.close
+			writeMethod(conn, 0, 10, 51, nil)
+			return
+		case class == 20 && method == 10: // channel.open
+			writeMethod(conn, channel, 20, 11, binary.BigEndian.AppendUint32(nil, 0))
+		case class == 85 && method == 10: // confirm.select
+			writeMethod(conn, channel, 85, 11, nil)
+		case class == 60 && method == 40: // basic.publish
+			if dropOnPublish {
+				return
+			}
+			deliveryTag++
+			writeMethod(conn, channel, 60, 80, append(binary.BigEndian.AppendUint64(nil, deliveryTag), 0))
+		}
+	}
+}
+
+func appendLongstr(b []byte, s string) []byte {
+	return append(binary.BigEndian.AppendUint32(b, uint32(len(s))), s...)
+}
+
+func writeMethod(w io.Writer, channel uint16, class uint16, method uint16, args []byte) {
+	payload := binary.BigEndian.AppendUint16(nil, class)
+	payload = binary.BigEndian.AppendUint16(payload, method)
+	payload = append(payload, args...)
+
+	var frame bytes.Buffer
+	frame.WriteByte(1)
+	frame.Write(binary.BigEndian.AppendUint16(nil, channel))
+	frame.Write(binary.BigEndian.AppendUint32(nil, uint32(len(payload))))
+	frame.Write(payload)
+	frame.WriteByte(0xCE)
+	w.Write(frame.Bytes())
+}
+
+func readFrame(r io.Reader) (byte, uint16, []byte, error) {
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[3:7])+1)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, 0, nil, err
+	}
+
+	return header[0], binary.BigEndian.Uint16(header[1:3]), payload[:len(payload)-1], nil
+}
+
+func waitForHealth(t *testing.T, p *Publisher, ready func(PublisherHealth) bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !ready(p.Health()) {
+		if time.Now().After(deadline) {
+			t.Fatalf("publisher health never became ready: %+v", p.Health())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPublisherSurvivesConnectionLossMidPublish(t *testing.T) {
+	broker := newFakeBroker(t)
+
+	p := &Publisher{url: broker.url()}
+	go p.run()
+	waitForHealth(t, p, func(h PublisherHealth) bool { return h.Connected })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := p.Publish(ctx, "", "queue", amqp.Publishing{Body: []byte("lost")}); err == nil {
+		t.Fatal("Publish() on a dropped connection returned nil")
+	}
+
+	// Publishing must keep returning while the publisher reconnects; it used to spin on the closed returns
+	// channel while holding the lock that disconnect needs.
+	done := make(chan error, 1)
+	go func() {
+		for {
+			err := p.Publish(ctx, "", "queue", amqp.Publishing{Body: []byte("retried")})
+			if err == nil || ctx.Err() != nil {
+				done <- err
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Publish() after reconnect = %v", err)
+		}
+	case <-time.After(6 * time.Second):
+		t.Fatal("Publish() did not return after the connection was lost")
+	}
+
+	if h := p.Health(); h.Reconnects < 1 {
+		t.Errorf("Reconnects = %d, want at least 1", h.Reconnects)
+	}
+}
+
+func TestDrainReturnsStopsOnClosedChannel(t *testing.T) {
+	returns := make(chan amqp.Return, 2)
+	returns <- amqp.Return{MessageId: "stale"}
+	close(returns)
+
+	p := &Publisher{returns: returns}
+
+	done := make(chan struct{})
+	go func() {
+		p.drainReturns()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("drainReturns() kept spinning on a closed channel")
+	}
+}
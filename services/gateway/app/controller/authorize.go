@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+)
+
+// authorizeInstitution allows users of the institution and users holding a system-scoped role.
+func authorizeInstitution(ctx context.Context, roleClient client.InterfaceRoleClient, institutionID string) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	if session.InstitutionID == institutionID {
+		return nil
+	}
+
+	for _, roleID := range session.RoleIDs {
+		role, err := roleClient.GetRoleByID(ctx, roleID)
+		if err != nil {
+			continue
+		}
+		if role.Scope == "system" {
+			return nil
+		}
+	}
+
+	return model.ThrowError(http.StatusForbidden, errors.New("you are not allowed to access this data (different institution)"))
+}
+
+// authorizeInstitutionAdmin allows users holding a system-scoped role and users of the institution holding one of
+// its administrator roles.
+func authorizeInstitutionAdmin(ctx context.Context, roleClient client.InterfaceRoleClient, institutionID string) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, roleID := range session.RoleIDs {
+		role, err := roleClient.GetRoleByID(ctx, roleID)
+		if err != nil {
+			continue
+		}
+		if role.Scope == "system" {
+			return nil
+		}
+		if role.IsAdministrator && session.InstitutionID == institutionID && role.InstitutionID != nil && *role.InstitutionID == institutionID {
+			return nil
+		}
+	}
+
+	return model.ThrowError(http.StatusForbidden, errors.New("this action requires an administrator of the institution or a system role"))
+}
+
+// authorizeSystem allows only users holding a system-scoped role.
+func authorizeSystem(ctx context.Context, roleClient client.InterfaceRoleClient) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, roleID := range session.RoleIDs {
+		role, err := roleClient.GetRoleByID(ctx, roleID)
+		if err != nil {
+			continue
+		}
+		if role.Scope == "system" {
+			return nil
+		}
+	}
+
+	return model.ThrowError(http.StatusForbidden, errors.New("this action requires a system role"))
+}
@@ -2,14 +2,20 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"face-recognition-svc/gateway/app/client"
 	"face-recognition-svc/gateway/app/config"
 	"face-recognition-svc/gateway/app/model"
 	"face-recognition-svc/gateway/app/utils"
 	"fmt"
+	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 )
 
@@ -17,27 +23,82 @@ type InterfaceDatasetController interface {
 	UploadUserDataset(ctx context.Context, req *model.Dataset) error
 	GetDatasetList(ctx context.Context) ([]*model.Dataset, error)
 	DeleteDataset(ctx context.Context, username string) error
-	TrainModel(ctx context.Context, institutionID string) (*model.ResponseTrainModel, error)
+	TrainModel(ctx context.Context, institutionID string, mode string, coalesce bool) (*model.ResponseTrainModel, error)
+	ScheduleTraining(ctx context.Context, institutionID string) (*model.ResponseTrainModel, error)
 	GetLastTrainModel(ctx context.Context, institutionID string) (string, error)
 	GetModelTrainingHistory(ctx context.Context, req *model.FilterModelTraining) ([]*model.ModelTraining, error)
 	GetDatasetsByUsername(ctx context.Context, username string) ([]string, error)
+	ReconcileDatasets(ctx context.Context, repair bool) (*model.DatasetReconcileReport, error)
+	RunDatasetReconcile(ctx context.Context, repair bool) (*model.DatasetReconcileReport, error)
+	GetLastReconcileReport(ctx context.Context) (*model.DatasetReconcileReport, error)
+	GetTraining(ctx context.Context, id string) (*model.ModelTraining, error)
+	CancelTraining(ctx context.Context, id string) (*model.ModelTraining, error)
+	HandleTrainingResult(ctx context.Context, result *model.TrainModelResult) error
+	HandleDeadLetteredTraining(ctx context.Context, message *model.DeadLetteredTraining) error
+	ReapStaleTrainings(ctx context.Context) ([]string, error)
+	GetDeadLetters(ctx context.Context, institutionID string, status string) ([]*model.TrainingDeadLetter, error)
+	ReplayDeadLetter(ctx context.Context, id string) (*model.TrainingDeadLetter, error)
+	DiscardDeadLetter(ctx context.Context, id string) error
 }
 
+const (
+	datasetReconcileLockKey   = "dataset:reconcile:lock"
+	datasetReconcileReportKey = "dataset:reconcile:last"
+	trainingLockKey           = "training:lock:%s"
+
+	// datasetReconcileGrace is used when job.datasetReconcile.gracePeriod is not a valid duration.
+	datasetReconcileGrace = time.Hour
+
+	// trainingReaperTimeout is used when job.trainingReaper.timeout is not a valid duration.
+	trainingReaperTimeout = 6 * time.Hour
+
+	// trainingReaperUser is recorded as the updater of trainings failed by the reaper.
+	trainingReaperUser = "system:reaper"
+
+	// datasetManifestOverlap is how far before the previous manifest a dataset upload still gets listed again.
+	datasetManifestOverlap = 5 * time.Minute
+
+	// trainingLockGrace is how long a training lock may be held before its model_training row is committed.
+	trainingLockGrace = 2 * time.Minute
+)
+
 type DatasetController struct {
-	storageClient client.InterfaceStorageClient
-	db            *gorm.DB
-	userClient    client.InterfaceUserClient
-	cfg           *config.Config
-	datasetClient client.InterfaceDatasetClient
+	storageClient        client.InterfaceStorageClient
+	db                   *gorm.DB
+	userClient           client.InterfaceUserClient
+	cfg                  *config.Config
+	datasetClient        client.InterfaceDatasetClient
+	redis                *redis.Client
+	imageClient          client.InterfaceImageClient
+	enrollmentClient     client.InterfaceEnrollmentClient
+	lockClient           client.InterfaceLockClient
+	outboxClient         client.InterfaceOutboxClient
+	deadLetterClient     client.InterfaceDeadLetterClient
+	trainingMetricClient client.InterfaceTrainingMetricClient
+	trainingEventClient  client.InterfaceTrainingEventClient
+	institutionClient    client.InterfaceInstitutionClient
+	roleClient           client.InterfaceRoleClient
+	modelClient          client.InterfaceModelClient
 }
 
-func NewDatasetController(storageClient client.InterfaceStorageClient, db *gorm.DB, userClient client.InterfaceUserClient, cfg *config.Config, datasetClient client.InterfaceDatasetClient) *DatasetController {
+func NewDatasetController(storageClient client.InterfaceStorageClient, db *gorm.DB, userClient client.InterfaceUserClient, cfg *config.Config, datasetClient client.InterfaceDatasetClient, redis *redis.Client, imageClient client.InterfaceImageClient, enrollmentClient client.InterfaceEnrollmentClient, lockClient client.InterfaceLockClient, outboxClient client.InterfaceOutboxClient, deadLetterClient client.InterfaceDeadLetterClient, trainingMetricClient client.InterfaceTrainingMetricClient, trainingEventClient client.InterfaceTrainingEventClient, institutionClient client.InterfaceInstitutionClient, roleClient client.InterfaceRoleClient, modelClient client.InterfaceModelClient) *DatasetController {
 	return &DatasetController{
-		storageClient: storageClient,
-		db:            db,
-		userClient:    userClient,
-		cfg:           cfg,
-		datasetClient: datasetClient,
+		storageClient:        storageClient,
+		db:                   db,
+		userClient:           userClient,
+		cfg:                  cfg,
+		datasetClient:        datasetClient,
+		redis:                redis,
+		imageClient:          imageClient,
+		enrollmentClient:     enrollmentClient,
+		lockClient:           lockClient,
+		outboxClient:         outboxClient,
+		deadLetterClient:     deadLetterClient,
+		trainingMetricClient: trainingMetricClient,
+		trainingEventClient:  trainingEventClient,
+		institutionClient:    institutionClient,
+		roleClient:           roleClient,
+		modelClient:          modelClient,
 	}
 }
 
@@ -59,6 +120,28 @@ func (c *DatasetController) UploadUserDataset(ctx context.Context, req *model.Da
 		return err
 	}
 
+	// Checked before fetching, so a request with too many URLs does not download any of them.
+	err = c.checkImageCount(len(req.File) + len(req.SourceURLs))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	for _, sourceURL := range req.SourceURLs {
+		file, err := c.imageClient.FetchImage(ctx, sourceURL)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+		req.File = append(req.File, file)
+	}
+
+	err = c.validateDatasetFiles(req.File)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	bucket := fmt.Sprintf("%s/%s", user.InstitutionID, req.Username)
 	req.Bucket = bucket
 	req.CreatedAt = time.Now()
@@ -77,6 +160,13 @@ func (c *DatasetController) UploadUserDataset(ctx context.Context, req *model.Da
 			utils.LogEventError(span, err)
 			return err
 		}
+	} else {
+		err = c.datasetClient.TouchDataset(ctx, tx, req.Username)
+		if err != nil {
+			utils.LogEventError(span, err)
+			tx.Rollback()
+			return err
+		}
 	}
 
 	err = c.storageClient.UploadFiles(ctx, req.File, "face-dataset", bucket)
@@ -95,6 +185,31 @@ func (c *DatasetController) UploadUserDataset(ctx context.Context, req *model.Da
 	return nil
 }
 
+func (c *DatasetController) validateDatasetFiles(files []*model.File) error {
+	if len(files) == 0 {
+		return model.ThrowError(http.StatusBadRequest, errors.New("at least one image is required"))
+	}
+
+	if err := c.checkImageCount(len(files)); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := utils.ValidateImage(file, c.cfg.Dataset.MaxImageBytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *DatasetController) checkImageCount(count int) error {
+	if c.cfg.Dataset.MaxImages > 0 && count > c.cfg.Dataset.MaxImages {
+		return model.ThrowError(http.StatusBadRequest, fmt.Errorf("at most %d images can be uploaded at once", c.cfg.Dataset.MaxImages))
+	}
+	return nil
+}
+
 func (c *DatasetController) GetDatasetList(ctx context.Context) ([]*model.Dataset, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: GetDatasetList")
 	defer span.Finish()
@@ -158,12 +273,48 @@ func (c *DatasetController) DeleteDataset(ctx context.Context, username string)
 	return nil
 }
 
-func (c *DatasetController) TrainModel(ctx context.Context, institutionID string) (*model.ResponseTrainModel, error) {
+// TrainModel queues a training job. Only one job per institution may be queued or running; a concurrent request
+// is rejected with the ID of that job, or receives it when coalesce is set. An incremental job only carries the
+// dataset changes since the last successful model.
+func (c *DatasetController) TrainModel(ctx context.Context, institutionID string, mode string, coalesce bool) (*model.ResponseTrainModel, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: TrainModel")
 	defer span.Finish()
 
+	utils.LogEvent(span, "Request", map[string]string{"institution_id": institutionID, "mode": mode})
+
+	if mode == "" {
+		mode = model.TrainingModeFull
+	}
+	if mode != model.TrainingModeFull && mode != model.TrainingModeIncremental {
+		utils.LogEventError(span, errors.New("invalid training mode"))
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("mode must be %s or %s", model.TrainingModeFull, model.TrainingModeIncremental))
+	}
+
+	err := authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return c.trainModel(ctx, institutionID, mode, coalesce)
+}
+
+// ScheduleTraining queues a full training for the training scheduler, joining one that is already queued or
+// running. It is not reachable over HTTP and the scheduler holds no role, so it skips the administrator check.
+func (c *DatasetController) ScheduleTraining(ctx context.Context, institutionID string) (*model.ResponseTrainModel, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ScheduleTraining")
+	defer span.Finish()
+
 	utils.LogEvent(span, "Request", institutionID)
 
+	return c.trainModel(ctx, institutionID, model.TrainingModeFull, true)
+}
+
+// trainModel queues a training job for TrainModel and ScheduleTraining once the caller is authorized.
+func (c *DatasetController) trainModel(ctx context.Context, institutionID string, mode string, coalesce bool) (*model.ResponseTrainModel, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: trainModel")
+	defer span.Finish()
+
 	session, err := utils.GetMetadata(ctx)
 	if err != nil {
 		utils.LogEventError(span, err)
@@ -172,49 +323,427 @@ func (c *DatasetController) TrainModel(ctx context.Context, institutionID string
 
 	utils.LogEvent(span, "session", session)
 
+	err = c.enrollmentClient.ExpireSessions(ctx, institutionID, defaultEnrollmentExpiry)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	openEnrollments, err := c.enrollmentClient.GetSessions(ctx, institutionID, model.EnrollmentStatusOpen)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(openEnrollments) > 0 {
+		utils.LogEventError(span, errors.New("enrollment still in progress"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("enrollment still in progress for %d user(s)", len(openEnrollments)))
+	}
+
+	trainingID := uuid.New().String()
+
+	lock, holder, err := c.acquireTrainingLock(ctx, institutionID, trainingID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if lock == nil {
+		if coalesce {
+			result := &model.ResponseTrainModel{
+				ID:        holder.Owner,
+				Coalesced: true,
+			}
+			utils.LogEvent(span, "Response", result)
+			return result, nil
+		}
+		utils.LogEventError(span, errors.New("training already queued or running"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("training %s is already queued or running for this institution", holder.Owner))
+	}
+
+	result, err := c.queueTraining(ctx, institutionID, trainingID, mode, lock.Token, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if releaseErr := c.lockClient.Release(ctx, lock.Key, lock.Token); releaseErr != nil {
+			utils.LogEventError(span, releaseErr)
+		}
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return result, nil
+}
+
+func (c *DatasetController) queueTraining(ctx context.Context, institutionID string, trainingID string, mode string, fencingToken int64, createdBy string) (*model.ResponseTrainModel, error) {
+	manifestObjects, err := c.datasetManifest(ctx, institutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestJSON, err := json.Marshal(manifestObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	institution, err := c.institutionClient.GetInstitutionByID(ctx, institutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	priority := 0
+	if institution != nil {
+		priority = c.cfg.Training.TierPriority(institution.TrainingTier)
+	}
+
+	req := &model.RequestAPITrainModel{
+		BucketName: c.cfg.MinioProfile.Bucket,
+		Prefix:     institutionID,
+		CreatedBy:  createdBy,
+		ID:         trainingID,
+		Type:       model.TrainModelMessageFull,
+		Mode:       mode,
+		Priority:   priority,
+	}
+
+	if mode == model.TrainingModeIncremental {
+		base, delta, err := c.trainingDelta(ctx, institutionID, manifestObjects)
+		if err != nil {
+			return nil, err
+		}
+		req.Type = model.TrainModelMessageIncremental
+		req.BaseModelID = &base.ID
+		req.Delta = delta
+	}
+
 	modelReq := &model.ModelTraining{
-		ID:            uuid.New().String(),
+		ID:            trainingID,
 		InstitutionID: institutionID,
-		Status:        "STARTED",
+		Status:        model.ModelTrainingStatusStarted,
+		Mode:          mode,
+		BaseModelID:   req.BaseModelID,
+		Priority:      priority,
 		CreatedAt:     time.Now(),
-		CreatedBy:     session.Username,
+		CreatedBy:     createdBy,
+		FencingToken:  &fencingToken,
 	}
 
 	tx := c.db.Begin()
 
 	err = c.datasetClient.InsertTrainedModel(ctx, modelReq, tx)
 	if err != nil {
-		utils.LogEventError(span, err)
 		tx.Rollback()
 		return nil, err
 	}
 
-	req := &model.RequestAPITrainModel{
-		BucketName: c.cfg.MinioProfile.Bucket,
-		Prefix:     institutionID,
-		CreatedBy:  session.Username,
-		ID:         modelReq.ID,
-	}
-	res, err := c.datasetClient.TrainModel(ctx, req)
+	err = c.datasetClient.InsertTrainingManifest(ctx, tx, &model.TrainingManifest{
+		ModelTrainingID: modelReq.ID,
+		Objects:         manifestJSON,
+		ObjectCount:     len(manifestObjects),
+		CreatedAt:       modelReq.CreatedAt,
+	})
 	if err != nil {
-		utils.LogEventError(span, err)
 		tx.Rollback()
 		return nil, err
 	}
 
-	utils.LogEvent(span, "Response", res)
+	payload, err := json.Marshal(req)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
-	result := &model.ResponseTrainModel{
-		ID: res.Data.ID,
+	// The relay publishes the message once this transaction commits, see OutboxController.Relay.
+	err = c.outboxClient.Insert(ctx, tx, &model.OutboxMessage{
+		ID:          uuid.New().String(),
+		Topic:       model.OutboxTopicTrainModel,
+		AggregateID: modelReq.ID,
+		Payload:     payload,
+		CreatedAt:   modelReq.CreatedAt,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 
 	err = tx.Commit().Error
 	if err != nil {
-		utils.LogEventError(span, err)
 		return nil, err
 	}
 
-	return result, nil
+	c.notifyTraining(ctx, modelReq.ID)
+
+	return &model.ResponseTrainModel{
+		ID:   modelReq.ID,
+		Mode: mode,
+	}, nil
+}
+
+// datasetManifest returns the institution's dataset objects. Only the first training lists the whole prefix; later
+// ones reuse the latest manifest and list again just the users uploaded to since, dropping users whose dataset
+// was deleted.
+func (c *DatasetController) datasetManifest(ctx context.Context, institutionID string) ([]model.ManifestObject, error) {
+	previous, err := c.datasetClient.GetLatestTrainingManifest(ctx, institutionID)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return c.listManifestObjects(ctx, institutionID+"/")
+	}
+
+	var previousObjects []model.ManifestObject
+	if err := json.Unmarshal(previous.Objects, &previousObjects); err != nil {
+		return nil, err
+	}
+
+	datasets, err := c.datasetClient.GetInstitutionDatasets(ctx, institutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// An upload is stamped when its transaction starts, before its images are stored, so uploads that overlap
+	// the previous manifest are listed again.
+	since := previous.CreatedAt.Add(-datasetManifestOverlap)
+
+	users := map[string]bool{}
+	changedUsers := map[string]bool{}
+	var changed []string
+	for _, dataset := range datasets {
+		users[dataset.Username] = true
+		if dataset.CreatedAt.After(since) || dataset.UpdatedAt.After(since) {
+			changedUsers[dataset.Username] = true
+			changed = append(changed, dataset.Dataset)
+		}
+	}
+
+	objects := make([]model.ManifestObject, 0, len(previousObjects))
+	for _, object := range previousObjects {
+		user := manifestUser(object.Key)
+		if users[user] && !changedUsers[user] {
+			objects = append(objects, object)
+		}
+	}
+
+	for _, prefix := range changed {
+		listed, err := c.listManifestObjects(ctx, prefix+"/")
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, listed...)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+
+	return objects, nil
+}
+
+func (c *DatasetController) listManifestObjects(ctx context.Context, prefix string) ([]model.ManifestObject, error) {
+	objects, err := c.storageClient.ListDatasetObjects(ctx, c.cfg.MinioProfile.Bucket, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	manifestObjects := make([]model.ManifestObject, 0, len(objects))
+	for _, object := range objects {
+		manifestObjects = append(manifestObjects, model.ManifestObject{
+			Key:  object.Key,
+			ETag: object.ETag,
+			Size: object.Size,
+		})
+	}
+
+	return manifestObjects, nil
+}
+
+// trainingDelta compares the current dataset objects with the manifest of the institution's active model, which
+// becomes the base model of an incremental training.
+func (c *DatasetController) trainingDelta(ctx context.Context, institutionID string, objects []model.ManifestObject) (*model.ModelTraining, *model.TrainingDelta, error) {
+	base, err := c.modelClient.GetActiveModel(ctx, institutionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if base == nil {
+		return nil, nil, model.ThrowError(http.StatusConflict, errors.New("no active model to train incrementally from, run a full training"))
+	}
+
+	manifest, err := c.datasetClient.GetTrainingManifest(ctx, base.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if manifest == nil {
+		return nil, nil, model.ThrowError(http.StatusConflict, fmt.Errorf("model version %d has no dataset manifest, run a full training", base.Version))
+	}
+
+	var baseObjects []model.ManifestObject
+	if err := json.Unmarshal(manifest.Objects, &baseObjects); err != nil {
+		return nil, nil, err
+	}
+
+	delta := diffManifests(baseObjects, objects)
+	if delta.IsEmpty() {
+		return nil, nil, model.ThrowError(http.StatusConflict, fmt.Errorf("dataset unchanged since model version %d", base.Version))
+	}
+
+	return base, delta, nil
+}
+
+// diffManifests groups objects by user, the second segment of "institution/username/file". Images of added or
+// removed users are not listed again as changed or removed images.
+func diffManifests(base []model.ManifestObject, current []model.ManifestObject) *model.TrainingDelta {
+	baseByKey := map[string]model.ManifestObject{}
+	baseUsers := map[string]bool{}
+	for _, object := range base {
+		baseByKey[object.Key] = object
+		baseUsers[manifestUser(object.Key)] = true
+	}
+
+	currentKeys := map[string]bool{}
+	currentUsers := map[string]bool{}
+	for _, object := range current {
+		currentKeys[object.Key] = true
+		currentUsers[manifestUser(object.Key)] = true
+	}
+
+	delta := &model.TrainingDelta{
+		AddedUsers:    []string{},
+		RemovedUsers:  []string{},
+		ChangedImages: []string{},
+		RemovedImages: []string{},
+	}
+
+	for user := range currentUsers {
+		if !baseUsers[user] {
+			delta.AddedUsers = append(delta.AddedUsers, user)
+		}
+	}
+	for user := range baseUsers {
+		if !currentUsers[user] {
+			delta.RemovedUsers = append(delta.RemovedUsers, user)
+		}
+	}
+
+	for _, object := range current {
+		if !baseUsers[manifestUser(object.Key)] {
+			continue
+		}
+		previous, ok := baseByKey[object.Key]
+		if !ok || previous.ETag != object.ETag {
+			delta.ChangedImages = append(delta.ChangedImages, object.Key)
+		}
+	}
+	for _, object := range base {
+		if !currentUsers[manifestUser(object.Key)] {
+			continue
+		}
+		if !currentKeys[object.Key] {
+			delta.RemovedImages = append(delta.RemovedImages, object.Key)
+		}
+	}
+
+	sort.Strings(delta.AddedUsers)
+	sort.Strings(delta.RemovedUsers)
+
+	return delta
+}
+
+func manifestUser(key string) string {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// acquireTrainingLock takes the institution's training lock for trainingID. When the lock is taken it returns
+// the holder instead; a lock left behind by a job that already finished is released and acquired again.
+func (c *DatasetController) acquireTrainingLock(ctx context.Context, institutionID string, trainingID string) (*model.Lock, *model.Lock, error) {
+	key := fmt.Sprintf(trainingLockKey, institutionID)
+
+	ttl, err := time.ParseDuration(c.cfg.Training.LockTTL)
+	if err != nil || ttl <= 0 {
+		ttl = 6 * time.Hour
+	}
+
+	// The fence counter only lives in Redis. Starting above the stored tokens keeps the InsertTrainedModel guard
+	// from rejecting every training after the counter was lost.
+	floor, err := c.datasetClient.GetMaxFencingToken(ctx, institutionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var holder *model.Lock
+	for attempt := 0; attempt < 2; attempt++ {
+		lock, err := c.lockClient.AcquireAbove(ctx, key, trainingID, ttl, floor)
+		if err != nil || lock != nil {
+			return lock, nil, err
+		}
+
+		holder, err = c.lockClient.Get(ctx, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if holder == nil {
+			continue
+		}
+
+		// The holder's row is not visible until its transaction commits, so a missing row only counts as held while
+		// the lock is younger than trainingLockGrace. Past that the holder crashed before committing and the lock is
+		// taken over.
+		training, err := c.datasetClient.GetTrainingByID(ctx, holder.Owner)
+		var errResponse *model.ErrorResponse
+		if errors.As(err, &errResponse) && errResponse.Code == http.StatusNotFound {
+			if ttl-holder.TTL < trainingLockGrace {
+				return nil, holder, nil
+			}
+		} else if err != nil {
+			return nil, nil, err
+		} else if !isTrainingFinished(training.Status) {
+			return nil, holder, nil
+		}
+
+		err = c.lockClient.Release(ctx, key, holder.Token)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return nil, holder, nil
+}
+
+func (c *DatasetController) releaseTrainingLock(ctx context.Context, training *model.ModelTraining) error {
+	if training.FencingToken == nil {
+		return nil
+	}
+
+	return c.lockClient.Release(ctx, fmt.Sprintf(trainingLockKey, training.InstitutionID), *training.FencingToken)
+}
+
+// notifyTraining publishes the current state of a training to the event stream. Events are best effort: a
+// failure is only logged, and clients that miss one catch up from the snapshot sent when they subscribe.
+func (c *DatasetController) notifyTraining(ctx context.Context, id string) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: NotifyTraining")
+	defer span.Finish()
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return
+	}
+
+	c.publishTrainingEvent(ctx, training)
+}
+
+// publishTrainingEvent ignores the error, which the client already records on its span.
+func (c *DatasetController) publishTrainingEvent(ctx context.Context, training *model.ModelTraining) {
+	_ = c.trainingEventClient.Publish(ctx, model.NewTrainingEvent(training))
+}
+
+func isTrainingFinished(status string) bool {
+	switch status {
+	case model.ModelTrainingStatusSucceeded, model.ModelTrainingStatusFailed, model.ModelTrainingStatusCancelled:
+		return true
+	}
+	return false
 }
 
 func (c *DatasetController) GetLastTrainModel(ctx context.Context, institutionID string) (string, error) {
@@ -240,30 +769,788 @@ func (c *DatasetController) GetModelTrainingHistory(ctx context.Context, req *mo
 
 	utils.LogEvent(span, "Request", req)
 
-	res, err := c.datasetClient.GetModelTrainingHistory(ctx, req)
+	session, err := utils.GetMetadata(ctx)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
 	}
 
-	utils.LogEvent(span, "Response", res)
-
-	return res, nil
-}
-
-func (c *DatasetController) GetDatasetsByUsername(ctx context.Context, username string) ([]string, error) {
-	span, ctx := utils.SpanFromContext(ctx, "Controller: GetDatasetByUsername")
-	defer span.Finish()
-
-	utils.LogEvent(span, "Request", username)
+	if req.InstitutionID == "" {
+		req.InstitutionID = session.InstitutionID
+	}
 
-	res, err := c.storageClient.GetDatasetsByUsername(ctx, c.cfg.MinioProfile.Bucket, username)
+	err = authorizeInstitution(ctx, c.roleClient, req.InstitutionID)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return nil, err
 	}
 
-	utils.LogEvent(span, "Response", res)
-
+	res, err := c.datasetClient.GetModelTrainingHistory(ctx, req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = attachTrainingMetrics(ctx, c.trainingMetricClient, res)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *DatasetController) GetDatasetsByUsername(ctx context.Context, username string) ([]string, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetDatasetByUsername")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", username)
+
+	res, err := c.storageClient.GetDatasetsByUsername(ctx, c.cfg.MinioProfile.Bucket, username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// ReconcileDatasets runs RunDatasetReconcile for a caller holding a system role, as it spans every institution.
+func (c *DatasetController) ReconcileDatasets(ctx context.Context, repair bool) (*model.DatasetReconcileReport, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ReconcileDatasets")
+	defer span.Finish()
+
+	if err := authorizeSystem(ctx, c.roleClient); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return c.RunDatasetReconcile(ctx, repair)
+}
+
+// RunDatasetReconcile compares face_datasets rows with the dataset prefixes in the bucket and,
+// when repair is set, removes whichever side has no counterpart. Prefixes and rows changed within
+// job.datasetReconcile.gracePeriod may belong to an upload in progress and are only reported as recent.
+func (c *DatasetController) RunDatasetReconcile(ctx context.Context, repair bool) (*model.DatasetReconcileReport, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RunDatasetReconcile")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", repair)
+
+	grace, err := time.ParseDuration(c.cfg.Job.DatasetReconcile.GracePeriod)
+	if err != nil || grace <= 0 {
+		grace = datasetReconcileGrace
+	}
+	cutoff := time.Now().Add(-grace)
+
+	// Released by token, so a run that outlived the TTL cannot free the lock of a run that started since.
+	lock, err := c.lockClient.Acquire(ctx, datasetReconcileLockKey, utils.LocalTime().String(), 30*time.Minute)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+	if lock == nil {
+		utils.LogEventError(span, errors.New("dataset reconciliation is already running"))
+		return nil, model.ThrowError(http.StatusConflict, errors.New("dataset reconciliation is already running"))
+	}
+	defer c.lockClient.Release(context.Background(), lock.Key, lock.Token)
+
+	report := &model.DatasetReconcileReport{
+		StartedAt: utils.LocalTime(),
+		Repair:    repair,
+	}
+
+	bucket := c.cfg.MinioProfile.Bucket
+
+	rows, err := c.datasetClient.GetDatasetList(ctx, "")
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	stale, err := c.datasetClient.GetStaleDatasets(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	prefixes, err := c.storageClient.ListDatasetPrefixes(ctx, bucket)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	inDB := map[string]bool{}
+	for _, row := range rows {
+		inDB[row.Dataset] = true
+	}
+
+	isStale := map[string]bool{}
+	for _, row := range stale {
+		isStale[row.Dataset] = true
+		report.StaleRows = append(report.StaleRows, row.Dataset)
+	}
+
+	for prefix, modified := range prefixes {
+		if inDB[prefix] {
+			continue
+		}
+		if modified.After(cutoff) {
+			report.Recent = append(report.Recent, prefix)
+			continue
+		}
+		report.OrphanObjects = append(report.OrphanObjects, prefix)
+	}
+	sort.Strings(report.OrphanObjects)
+
+	for _, row := range rows {
+		if _, ok := prefixes[row.Dataset]; ok || isStale[row.Dataset] {
+			continue
+		}
+		if row.CreatedAt.After(cutoff) {
+			report.Recent = append(report.Recent, row.Dataset)
+			continue
+		}
+		report.MissingObjects = append(report.MissingObjects, row.Dataset)
+	}
+
+	if repair {
+		for _, prefix := range report.OrphanObjects {
+			if err := c.storageClient.DeleteObject(ctx, bucket, prefix+"/"); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("delete objects %s: %s", prefix, err.Error()))
+				continue
+			}
+			report.Repaired = append(report.Repaired, prefix)
+		}
+
+		for _, dataset := range report.MissingObjects {
+			if err := c.storageClient.DeleteDatasetRecord(ctx, nil, dataset); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("delete row %s: %s", dataset, err.Error()))
+				continue
+			}
+			report.Repaired = append(report.Repaired, dataset)
+		}
+
+		for _, dataset := range report.StaleRows {
+			if _, ok := prefixes[dataset]; ok {
+				if err := c.storageClient.DeleteObject(ctx, bucket, dataset+"/"); err != nil {
+					report.Errors = append(report.Errors, fmt.Sprintf("delete objects %s: %s", dataset, err.Error()))
+					continue
+				}
+			}
+			if err := c.storageClient.DeleteDatasetRecord(ctx, nil, dataset); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("delete row %s: %s", dataset, err.Error()))
+				continue
+			}
+			report.Repaired = append(report.Repaired, dataset)
+		}
+	}
+
+	report.FinishedAt = utils.LocalTime()
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return report, nil
+	}
+
+	if err := c.redis.Set(ctx, datasetReconcileReportKey, reportJSON, 7*24*time.Hour).Err(); err != nil {
+		utils.LogEventError(span, err)
+	}
+
+	utils.LogEvent(span, "Response", report)
+
+	return report, nil
+}
+
+func (c *DatasetController) GetLastReconcileReport(ctx context.Context) (*model.DatasetReconcileReport, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetLastReconcileReport")
+	defer span.Finish()
+
+	if err := authorizeSystem(ctx, c.roleClient); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	cache, err := c.redis.Get(ctx, datasetReconcileReportKey).Result()
+	if err == redis.Nil {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("dataset reconciliation has not run yet"))
+	}
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	report := &model.DatasetReconcileReport{}
+	if err := json.Unmarshal([]byte(cache), report); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", report)
+
+	return report, nil
+}
+
+func (c *DatasetController) GetTraining(ctx context.Context, id string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetTraining")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := c.datasetClient.GetTrainingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, res.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if res.Status == model.ModelTrainingStatusStarted {
+		position, err := c.datasetClient.GetQueuePosition(ctx, res)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+		res.QueuePosition = &position
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// CancelTraining cancels a queued job straight away and asks the worker to stop a running one; the worker's
+// result message then moves the job from CANCELLING to its final state.
+func (c *DatasetController) CancelTraining(ctx context.Context, id string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CancelTraining")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, training.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var status string
+	switch training.Status {
+	case model.ModelTrainingStatusStarted:
+		status = model.ModelTrainingStatusCancelled
+	case model.ModelTrainingStatusRunning:
+		status = model.ModelTrainingStatusCancelling
+	default:
+		utils.LogEventError(span, errors.New("training cannot be cancelled"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("training with status %s cannot be cancelled", training.Status))
+	}
+
+	tx := c.db.Begin()
+
+	affected, err := c.datasetClient.UpdateTrainingStatus(ctx, tx, id, status, []string{training.Status}, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	if affected == 0 {
+		utils.LogEventError(span, errors.New("training status changed, retry the cancellation"))
+		tx.Rollback()
+		return nil, model.ThrowError(http.StatusConflict, errors.New("training status changed, retry the cancellation"))
+	}
+
+	// A job whose message is still in the outbox is dropped there and never reaches the worker.
+	discarded, err := c.outboxClient.DiscardPending(ctx, tx, model.OutboxTopicTrainModel, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	// The cancel goes out only once the status change is committed. If it is lost, the job stays CANCELLING and
+	// the training reaper fails it and sends the cancel again.
+	if discarded == 0 {
+		err = c.datasetClient.PublishTrainingCancel(ctx, &model.TrainModelCancel{
+			ID:            id,
+			InstitutionID: training.InstitutionID,
+			RequestedBy:   session.Username,
+			RequestedAt:   time.Now(),
+		})
+		if err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	training.Status = status
+
+	c.notifyTraining(ctx, id)
+
+	if isTrainingFinished(status) {
+		if err := c.releaseTrainingLock(ctx, training); err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	utils.LogEvent(span, "Response", training)
+
+	return training, nil
+}
+
+// HandleTrainingResult applies a state change reported by the processing service. Reports that arrive out of
+// order or for jobs already in a final state are ignored.
+func (c *DatasetController) HandleTrainingResult(ctx context.Context, result *model.TrainModelResult) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: HandleTrainingResult")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", result)
+
+	var fromStatuses []string
+	switch result.Status {
+	case model.ModelTrainingStatusRunning:
+		// RUNNING is repeated by the worker to report progress.
+		fromStatuses = []string{model.ModelTrainingStatusStarted, model.ModelTrainingStatusRunning}
+	case model.ModelTrainingStatusSucceeded, model.ModelTrainingStatusFailed, model.ModelTrainingStatusCancelled:
+		fromStatuses = []string{model.ModelTrainingStatusStarted, model.ModelTrainingStatusRunning, model.ModelTrainingStatusCancelling}
+	default:
+		utils.LogEventError(span, errors.New("unknown training status"))
+		return fmt.Errorf("unknown training status %q", result.Status)
+	}
+
+	if result.Metrics != nil {
+		if dropped := normalizeTrainingMetrics(result.Metrics); len(dropped) > 0 {
+			utils.LogEvent(span, "Dropped metrics", dropped)
+		}
+	}
+
+	tx := c.db.Begin()
+
+	affected, err := c.datasetClient.UpdateTrainingResult(ctx, tx, result, fromStatuses)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	if affected == 0 {
+		utils.LogEvent(span, "Ignored", "training not found or already in a later state")
+		tx.Rollback()
+		return nil
+	}
+
+	// Metrics are written with the final status so a redelivered result, which is ignored above, cannot lose them.
+	if result.Status == model.ModelTrainingStatusSucceeded && result.Metrics != nil {
+		err = c.trainingMetricClient.UpsertMetrics(ctx, tx, result.ID, result.Metrics)
+		if err != nil {
+			utils.LogEventError(span, err)
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, result.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	c.publishTrainingEvent(ctx, training)
+
+	if isTrainingFinished(training.Status) {
+		err = c.releaseTrainingLock(ctx, training)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReapStaleTrainings fails dispatched trainings the worker has not reported on within job.trainingReaper.timeout,
+// so their in-flight slot and training lock are freed. A running job is also asked to stop, and a late result
+// for it is ignored.
+func (c *DatasetController) ReapStaleTrainings(ctx context.Context) ([]string, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ReapStaleTrainings")
+	defer span.Finish()
+
+	timeout, err := time.ParseDuration(c.cfg.Job.TrainingReaper.Timeout)
+	if err != nil || timeout <= 0 {
+		timeout = trainingReaperTimeout
+	}
+
+	trainings, err := c.datasetClient.GetStaleTrainings(ctx, time.Now().Add(-timeout))
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var reaped []string
+	for _, training := range trainings {
+		affected, err := c.datasetClient.UpdateTrainingStatus(ctx, c.db, training.ID, model.ModelTrainingStatusFailed, []string{training.Status}, trainingReaperUser)
+		if err != nil {
+			utils.LogEventError(span, err)
+			continue
+		}
+		if affected == 0 {
+			continue
+		}
+
+		if training.Status != model.ModelTrainingStatusStarted {
+			err = c.datasetClient.PublishTrainingCancel(ctx, &model.TrainModelCancel{
+				ID:            training.ID,
+				InstitutionID: training.InstitutionID,
+				RequestedBy:   trainingReaperUser,
+				RequestedAt:   time.Now(),
+			})
+			if err != nil {
+				utils.LogEventError(span, err)
+			}
+		}
+
+		c.notifyTraining(ctx, training.ID)
+		if err := c.releaseTrainingLock(ctx, training); err != nil {
+			utils.LogEventError(span, err)
+		}
+
+		reaped = append(reaped, training.ID)
+	}
+
+	utils.LogEvent(span, "Response", reaped)
+
+	return reaped, nil
+}
+
+// normalizeTrainingMetrics clears the values model_training_metric would reject, so one bad metric from the worker
+// cannot fail the status update it is written with. It returns the names of the cleared fields.
+func normalizeTrainingMetrics(metrics *model.TrainingMetrics) []string {
+	var dropped []string
+
+	if metrics.Identities != nil && *metrics.Identities < 0 {
+		metrics.Identities = nil
+		dropped = append(dropped, "identities")
+	}
+	if metrics.Images != nil && *metrics.Images < 0 {
+		metrics.Images = nil
+		dropped = append(dropped, "images")
+	}
+	if v := metrics.ValidationAccuracy; v != nil && (*v < 0 || *v > 1) {
+		metrics.ValidationAccuracy = nil
+		dropped = append(dropped, "validation_accuracy")
+	}
+	// duration_seconds is NUMERIC(12, 3).
+	if v := metrics.DurationSeconds; v != nil && (*v < 0 || *v >= 1e9) {
+		metrics.DurationSeconds = nil
+		dropped = append(dropped, "duration_seconds")
+	}
+	if metrics.ArtifactSizeBytes != nil && *metrics.ArtifactSizeBytes < 0 {
+		metrics.ArtifactSizeBytes = nil
+		dropped = append(dropped, "artifact_size_bytes")
+	}
+
+	return dropped
+}
+
+// HandleDeadLetteredTraining retries a training message rejected by the worker with the configured delays and
+// parks it as dead letter, failing its job, once the retries are used up.
+func (c *DatasetController) HandleDeadLetteredTraining(ctx context.Context, message *model.DeadLetteredTraining) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: HandleDeadLetteredTraining")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", message)
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, message.Request.ID)
+	var errResponse *model.ErrorResponse
+	if err != nil && !(errors.As(err, &errResponse) && errResponse.Code == http.StatusNotFound) {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if training != nil && isTrainingFinished(training.Status) {
+		utils.LogEvent(span, "Ignored", "training already in a final state")
+		return nil
+	}
+
+	if message.RetryCount < len(c.cfg.Training.RetryDurations()) {
+		err = c.datasetClient.PublishTrainingRetry(ctx, message.Payload, message.RetryCount+1)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return err
+		}
+		return nil
+	}
+
+	reason := message.Reason
+	deadLetter := &model.TrainingDeadLetter{
+		ID:              uuid.New().String(),
+		ModelTrainingID: message.Request.ID,
+		InstitutionID:   message.Request.Prefix,
+		Payload:         message.Payload,
+		Reason:          &reason,
+		RetryCount:      message.RetryCount,
+		Status:          model.DeadLetterStatusPending,
+		CreatedAt:       time.Now(),
+	}
+
+	tx := c.db.Begin()
+
+	err = c.deadLetterClient.InsertDeadLetter(ctx, tx, deadLetter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	_, err = c.datasetClient.UpdateTrainingStatus(ctx, tx, message.Request.ID, model.ModelTrainingStatusFailed,
+		[]string{model.ModelTrainingStatusStarted, model.ModelTrainingStatusRunning, model.ModelTrainingStatusCancelling}, "system")
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if training != nil {
+		c.notifyTraining(ctx, training.ID)
+		if err := c.releaseTrainingLock(ctx, training); err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	return nil
+}
+
+// GetDeadLetters lists the dead letters of institutionID. Without one, system roles see every institution and
+// other callers their own.
+func (c *DatasetController) GetDeadLetters(ctx context.Context, institutionID string, status string) ([]*model.TrainingDeadLetter, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetDeadLetters")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"institution_id": institutionID, "status": status})
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if institutionID == "" {
+		if authorizeSystem(ctx, c.roleClient) != nil {
+			institutionID = session.InstitutionID
+		}
+	} else {
+		err = authorizeInstitution(ctx, c.roleClient, institutionID)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+	}
+
+	res, err := c.deadLetterClient.GetDeadLetters(ctx, institutionID, status)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	for _, deadLetter := range res {
+		training, err := c.datasetClient.GetTrainingByID(ctx, deadLetter.ModelTrainingID)
+		if err != nil {
+			continue
+		}
+		deadLetter.Training = training
+	}
+
+	utils.LogEvent(span, "Response", len(res))
+
 	return res, nil
 }
+
+// ReplayDeadLetter puts the failed job back in the queue under a fresh training lock.
+func (c *DatasetController) ReplayDeadLetter(ctx context.Context, id string) (*model.TrainingDeadLetter, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ReplayDeadLetter")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	deadLetter, err := c.deadLetterClient.GetDeadLetterByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, deadLetter.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if deadLetter.Status != model.DeadLetterStatusPending {
+		utils.LogEventError(span, errors.New("dead letter already resolved"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("dead letter is already %s", deadLetter.Status))
+	}
+
+	lock, holder, err := c.acquireTrainingLock(ctx, deadLetter.InstitutionID, deadLetter.ModelTrainingID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if lock == nil {
+		utils.LogEventError(span, errors.New("training already queued or running"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("training %s is already queued or running for this institution", holder.Owner))
+	}
+
+	err = c.replayDeadLetter(ctx, deadLetter, lock.Token, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if releaseErr := c.lockClient.Release(ctx, lock.Key, lock.Token); releaseErr != nil {
+			utils.LogEventError(span, releaseErr)
+		}
+		return nil, err
+	}
+
+	deadLetter.Status = model.DeadLetterStatusReplayed
+
+	c.notifyTraining(ctx, deadLetter.ModelTrainingID)
+
+	utils.LogEvent(span, "Response", deadLetter)
+
+	return deadLetter, nil
+}
+
+func (c *DatasetController) replayDeadLetter(ctx context.Context, deadLetter *model.TrainingDeadLetter, fencingToken int64, replayedBy string) error {
+	tx := c.db.Begin()
+
+	affected, err := c.deadLetterClient.ResolveDeadLetter(ctx, tx, deadLetter.ID, model.DeadLetterStatusReplayed, replayedBy)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return model.ThrowError(http.StatusConflict, errors.New("dead letter was resolved concurrently"))
+	}
+
+	affected, err = c.datasetClient.ResetTrainingForReplay(ctx, tx, deadLetter.ModelTrainingID, fencingToken, replayedBy)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if affected == 0 {
+		tx.Rollback()
+		return model.ThrowError(http.StatusConflict, errors.New("only FAILED trainings can be replayed"))
+	}
+
+	err = c.outboxClient.Insert(ctx, tx, &model.OutboxMessage{
+		ID:          uuid.New().String(),
+		Topic:       model.OutboxTopicTrainModel,
+		AggregateID: deadLetter.ModelTrainingID,
+		Payload:     deadLetter.Payload,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (c *DatasetController) DiscardDeadLetter(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DiscardDeadLetter")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	deadLetter, err := c.deadLetterClient.GetDeadLetterByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, deadLetter.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	affected, err := c.deadLetterClient.ResolveDeadLetter(ctx, c.db, id, model.DeadLetterStatusDiscarded, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if affected == 0 {
+		utils.LogEventError(span, errors.New("dead letter already resolved"))
+		return model.ThrowError(http.StatusConflict, errors.New("dead letter is already resolved"))
+	}
+
+	return nil
+}
+
+func attachTrainingMetrics(ctx context.Context, metricClient client.InterfaceTrainingMetricClient, trainings []*model.ModelTraining) error {
+	ids := make([]string, 0, len(trainings))
+	for _, training := range trainings {
+		ids = append(ids, training.ID)
+	}
+
+	metrics, err := metricClient.GetMetrics(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, training := range trainings {
+		training.Metrics = metrics[training.ID]
+	}
+
+	return nil
+}
@@ -0,0 +1,305 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestDiffManifests(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    []model.ManifestObject
+		current []model.ManifestObject
+		want    *model.TrainingDelta
+	}{
+		{
+			name: "empty base adds every user",
+			current: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+				{Key: "inst/bob/1.jpg", ETag: "b1"},
+				{Key: "inst/alice/2.jpg", ETag: "a2"},
+			},
+			want: &model.TrainingDelta{
+				AddedUsers:    []string{"alice", "bob"},
+				RemovedUsers:  []string{},
+				ChangedImages: []string{},
+				RemovedImages: []string{},
+			},
+		},
+		{
+			name: "unchanged manifest",
+			base: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+			},
+			current: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+			},
+			want: &model.TrainingDelta{
+				AddedUsers:    []string{},
+				RemovedUsers:  []string{},
+				ChangedImages: []string{},
+				RemovedImages: []string{},
+			},
+		},
+		{
+			name: "added, changed and removed images of a kept user",
+			base: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+				{Key: "inst/alice/2.jpg", ETag: "a2"},
+				{Key: "inst/alice/3.jpg", ETag: "a3"},
+			},
+			current: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+				{Key: "inst/alice/2.jpg", ETag: "a2-new"},
+				{Key: "inst/alice/4.jpg", ETag: "a4"},
+			},
+			want: &model.TrainingDelta{
+				AddedUsers:    []string{},
+				RemovedUsers:  []string{},
+				ChangedImages: []string{"inst/alice/2.jpg", "inst/alice/4.jpg"},
+				RemovedImages: []string{"inst/alice/3.jpg"},
+			},
+		},
+		{
+			name: "images of added and removed users are not listed again",
+			base: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+				{Key: "inst/bob/1.jpg", ETag: "b1"},
+				{Key: "inst/bob/2.jpg", ETag: "b2"},
+			},
+			current: []model.ManifestObject{
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+				{Key: "inst/carol/1.jpg", ETag: "c1"},
+				{Key: "inst/dave/1.jpg", ETag: "d1"},
+			},
+			want: &model.TrainingDelta{
+				AddedUsers:    []string{"carol", "dave"},
+				RemovedUsers:  []string{"bob"},
+				ChangedImages: []string{},
+				RemovedImages: []string{},
+			},
+		},
+		{
+			name: "empty current removes every user",
+			base: []model.ManifestObject{
+				{Key: "inst/bob/1.jpg", ETag: "b1"},
+				{Key: "inst/alice/1.jpg", ETag: "a1"},
+			},
+			want: &model.TrainingDelta{
+				AddedUsers:    []string{},
+				RemovedUsers:  []string{"alice", "bob"},
+				ChangedImages: []string{},
+				RemovedImages: []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffManifests(tt.base, tt.current)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffManifests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTrainingMetrics(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	int64Ptr := func(v int64) *int64 { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name        string
+		metrics     *model.TrainingMetrics
+		want        *model.TrainingMetrics
+		wantDropped []string
+	}{
+		{
+			name:    "empty metrics",
+			metrics: &model.TrainingMetrics{},
+			want:    &model.TrainingMetrics{},
+		},
+		{
+			name: "valid metrics are kept",
+			metrics: &model.TrainingMetrics{
+				Identities:         intPtr(12),
+				Images:             intPtr(0),
+				ValidationAccuracy: floatPtr(1),
+				DurationSeconds:    floatPtr(999999999.999),
+				ArtifactSizeBytes:  int64Ptr(4096),
+			},
+			want: &model.TrainingMetrics{
+				Identities:         intPtr(12),
+				Images:             intPtr(0),
+				ValidationAccuracy: floatPtr(1),
+				DurationSeconds:    floatPtr(999999999.999),
+				ArtifactSizeBytes:  int64Ptr(4096),
+			},
+		},
+		{
+			name: "out of range values are cleared",
+			metrics: &model.TrainingMetrics{
+				Identities:         intPtr(-1),
+				Images:             intPtr(-3),
+				ValidationAccuracy: floatPtr(1.5),
+				DurationSeconds:    floatPtr(1e9),
+				ArtifactSizeBytes:  int64Ptr(-1),
+			},
+			want:        &model.TrainingMetrics{},
+			wantDropped: []string{"identities", "images", "validation_accuracy", "duration_seconds", "artifact_size_bytes"},
+		},
+		{
+			name: "negative accuracy and duration",
+			metrics: &model.TrainingMetrics{
+				Identities:         intPtr(3),
+				ValidationAccuracy: floatPtr(-0.1),
+				DurationSeconds:    floatPtr(-1),
+			},
+			want: &model.TrainingMetrics{
+				Identities: intPtr(3),
+			},
+			wantDropped: []string{"validation_accuracy", "duration_seconds"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dropped := normalizeTrainingMetrics(tt.metrics)
+			if !reflect.DeepEqual(dropped, tt.wantDropped) {
+				t.Errorf("dropped = %v, want %v", dropped, tt.wantDropped)
+			}
+			if !reflect.DeepEqual(tt.metrics, tt.want) {
+				t.Errorf("metrics = %+v, want %+v", tt.metrics, tt.want)
+			}
+		})
+	}
+}
+
+// lockDatasetClient serves the two dataset queries acquireTrainingLock makes.
+type lockDatasetClient struct {
+	client.InterfaceDatasetClient
+	maxToken  int64
+	trainings map[string]*model.ModelTraining
+}
+
+func (d *lockDatasetClient) GetMaxFencingToken(ctx context.Context, institutionID string) (int64, error) {
+	return d.maxToken, nil
+}
+
+func (d *lockDatasetClient) GetTrainingByID(ctx context.Context, id string) (*model.ModelTraining, error) {
+	if training, ok := d.trainings[id]; ok {
+		return training, nil
+	}
+	return nil, model.ThrowError(http.StatusNotFound, errors.New("training not found"))
+}
+
+func newLockTestController(t *testing.T, datasets *lockDatasetClient) (*DatasetController, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.Config{}
+	cfg.Training.LockTTL = "1h"
+
+	return &DatasetController{
+		cfg:           cfg,
+		datasetClient: datasets,
+		lockClient:    client.NewLockClient(rdb),
+	}, server
+}
+
+func TestAcquireTrainingLockStartsAboveStoredTokens(t *testing.T) {
+	c, _ := newLockTestController(t, &lockDatasetClient{maxToken: 17})
+
+	lock, holder, err := c.acquireTrainingLock(context.Background(), "inst", "job-1")
+	if err != nil || holder != nil {
+		t.Fatalf("acquireTrainingLock() holder, err = %+v, %v", holder, err)
+	}
+	if lock == nil || lock.Token != 18 {
+		t.Fatalf("lock = %+v, want token 18", lock)
+	}
+}
+
+func TestAcquireTrainingLockFencesOutFinishedHolder(t *testing.T) {
+	ctx := context.Background()
+	datasets := &lockDatasetClient{trainings: map[string]*model.ModelTraining{}}
+	c, _ := newLockTestController(t, datasets)
+
+	stale, _, err := c.acquireTrainingLock(ctx, "inst", "job-1")
+	if err != nil || stale == nil {
+		t.Fatalf("first acquireTrainingLock() = %+v, %v", stale, err)
+	}
+
+	datasets.trainings["job-1"] = &model.ModelTraining{ID: "job-1", Status: model.ModelTrainingStatusRunning}
+
+	_, holder, err := c.acquireTrainingLock(ctx, "inst", "job-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder == nil || holder.Owner != "job-1" {
+		t.Fatalf("holder while job-1 runs = %+v, want job-1", holder)
+	}
+
+	// job-1 finished without releasing its lock; job-2 takes it over with a newer token.
+	datasets.trainings["job-1"].Status = model.ModelTrainingStatusSucceeded
+
+	lock, holder, err := c.acquireTrainingLock(ctx, "inst", "job-2")
+	if err != nil || holder != nil {
+		t.Fatalf("acquireTrainingLock() holder, err = %+v, %v", holder, err)
+	}
+	if lock == nil || lock.Token <= stale.Token {
+		t.Fatalf("lock = %+v, want a token above %d", lock, stale.Token)
+	}
+
+	// The stale holder's late release leaves job-2's lock alone.
+	if err := c.releaseTrainingLock(ctx, &model.ModelTraining{InstitutionID: "inst", FencingToken: &stale.Token}); err != nil {
+		t.Fatal(err)
+	}
+	current, err := c.lockClient.Get(ctx, lock.Key)
+	if err != nil || current == nil || current.Owner != "job-2" {
+		t.Fatalf("lock after the stale release = %+v, %v; want job-2", current, err)
+	}
+}
+
+func TestAcquireTrainingLockGraceForUncommittedHolder(t *testing.T) {
+	ctx := context.Background()
+	c, server := newLockTestController(t, &lockDatasetClient{})
+
+	// job-1 holds the lock but its training row is not committed yet.
+	if _, _, err := c.acquireTrainingLock(ctx, "inst", "job-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.FastForward(trainingLockGrace / 2)
+
+	lock, holder, err := c.acquireTrainingLock(ctx, "inst", "job-2")
+	if err != nil || lock != nil {
+		t.Fatalf("acquireTrainingLock() within the grace window = %+v, %v", lock, err)
+	}
+	if holder == nil || holder.Owner != "job-1" {
+		t.Fatalf("holder = %+v, want job-1", holder)
+	}
+
+	// Past the grace window job-1 is taken to have crashed before committing.
+	server.FastForward(trainingLockGrace)
+
+	lock, holder, err = c.acquireTrainingLock(ctx, "inst", "job-2")
+	if err != nil || holder != nil {
+		t.Fatalf("acquireTrainingLock() after the grace window holder, err = %+v, %v", holder, err)
+	}
+	if lock == nil || lock.Owner != "job-2" {
+		t.Fatalf("lock = %+v, want job-2", lock)
+	}
+}
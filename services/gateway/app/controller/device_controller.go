@@ -0,0 +1,483 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InterfaceDeviceController interface {
+	GetDevices(ctx context.Context, filter *model.FilterDevice, pagination *model.Pagination) ([]*model.Device, *model.Pagination, error)
+	GetDevice(ctx context.Context, id string) (*model.Device, error)
+	CreateDevice(ctx context.Context, req *model.RequestDevice) (*model.DeviceWithKey, error)
+	UpdateDevice(ctx context.Context, id string, req *model.RequestDevice) (*model.Device, error)
+	RotateDeviceKey(ctx context.Context, id string) (*model.DeviceWithKey, error)
+	DeleteDevice(ctx context.Context, id string) error
+	Heartbeat(ctx context.Context, req *model.RequestDeviceHeartbeat) (*model.Device, error)
+}
+
+const deviceEntity = "device"
+
+var deviceStatuses = []string{model.DeviceStatusActive, model.DeviceStatusDisabled}
+
+type DeviceController struct {
+	deviceClient      client.InterfaceDeviceClient
+	institutionClient client.InterfaceInstitutionClient
+	roleClient        client.InterfaceRoleClient
+	auditClient       client.InterfaceAuditClient
+	db                *gorm.DB
+	cfg               *config.Config
+}
+
+func NewDeviceController(deviceClient client.InterfaceDeviceClient, institutionClient client.InterfaceInstitutionClient, roleClient client.InterfaceRoleClient, auditClient client.InterfaceAuditClient, db *gorm.DB, cfg *config.Config) *DeviceController {
+	return &DeviceController{
+		deviceClient:      deviceClient,
+		institutionClient: institutionClient,
+		roleClient:        roleClient,
+		auditClient:       auditClient,
+		db:                db,
+		cfg:               cfg,
+	}
+}
+
+func (c *DeviceController) GetDevices(ctx context.Context, filter *model.FilterDevice, pagination *model.Pagination) ([]*model.Device, *model.Pagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetDevices")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	if filter.InstitutionID == "" {
+		filter.InstitutionID = session.InstitutionID
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, filter.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	if filter.Status != "" && !slices.Contains(deviceStatuses, filter.Status) {
+		utils.LogEventError(span, errors.New("invalid device status"))
+		return nil, nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("status must be one of %v", deviceStatuses))
+	}
+
+	res, pagination, err := c.deviceClient.GetDevices(ctx, filter, pagination)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	for _, device := range res {
+		c.markOnline(device)
+	}
+
+	return res, pagination, nil
+}
+
+func (c *DeviceController) GetDevice(ctx context.Context, id string) (*model.Device, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetDevice")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := c.deviceClient.GetDeviceByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if res == nil {
+		utils.LogEventError(span, errors.New("device not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("device not found"))
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, res.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	c.markOnline(res)
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// CreateDevice registers a device and returns its API key, which is not shown again.
+func (c *DeviceController) CreateDevice(ctx context.Context, req *model.RequestDevice) (*model.DeviceWithKey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateDevice")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if req.InstitutionID == "" {
+		req.InstitutionID = session.InstitutionID
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, req.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	institution, err := c.institutionClient.GetInstitutionByID(ctx, req.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if institution == nil || institution.ID == "" {
+		utils.LogEventError(span, errors.New("institution not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("institution not found"))
+	}
+
+	now := time.Now()
+	device := &model.Device{
+		ID:            uuid.New().String(),
+		InstitutionID: req.InstitutionID,
+		Status:        model.DeviceStatusActive,
+		KeyRotatedAt:  now,
+		CreatedAt:     now,
+		CreatedBy:     session.Username,
+		UpdatedAt:     now,
+		UpdatedBy:     session.Username,
+	}
+
+	err = c.applyDeviceRequest(ctx, device, req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if device.Name == "" {
+		utils.LogEventError(span, errors.New("name is required"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("name is required"))
+	}
+
+	key, prefix, err := utils.GenerateDeviceKey()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+	device.KeyPrefix = prefix
+	device.KeyHash = utils.HashDeviceKey(key)
+
+	tx := c.db.Begin()
+
+	err = c.deviceClient.InsertDevice(ctx, tx, device)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = c.audit(ctx, tx, device, "create")
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return &model.DeviceWithKey{Device: device, APIKey: key}, nil
+}
+
+func (c *DeviceController) UpdateDevice(ctx context.Context, id string, req *model.RequestDevice) (*model.Device, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: UpdateDevice")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	device, err := c.manageableDevice(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = c.applyDeviceRequest(ctx, device, req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+	device.UpdatedBy = session.Username
+
+	tx := c.db.Begin()
+
+	err = c.deviceClient.UpdateDevice(ctx, tx, device)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = c.audit(ctx, tx, device, "update")
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return c.GetDevice(ctx, id)
+}
+
+// applyDeviceRequest copies the fields set in req onto device. A name must stay unique within the institution.
+func (c *DeviceController) applyDeviceRequest(ctx context.Context, device *model.Device, req *model.RequestDevice) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return model.ThrowError(http.StatusBadRequest, errors.New("name must not be empty"))
+		}
+
+		if name != device.Name {
+			existing, err := c.deviceClient.GetDeviceByName(ctx, device.InstitutionID, name)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				return model.ThrowError(http.StatusConflict, fmt.Errorf("the institution already has a device named %q", name))
+			}
+		}
+		device.Name = name
+	}
+
+	if req.Location != nil {
+		location := strings.TrimSpace(*req.Location)
+		device.Location = &location
+		if location == "" {
+			device.Location = nil
+		}
+	}
+
+	if req.Status != nil {
+		status := strings.ToUpper(*req.Status)
+		if !slices.Contains(deviceStatuses, status) {
+			return model.ThrowError(http.StatusBadRequest, fmt.Errorf("status must be one of %v", deviceStatuses))
+		}
+		device.Status = status
+	}
+
+	return nil
+}
+
+// RotateDeviceKey issues a new API key. The previous key stops working immediately.
+func (c *DeviceController) RotateDeviceKey(ctx context.Context, id string) (*model.DeviceWithKey, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RotateDeviceKey")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	device, err := c.manageableDevice(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	key, prefix, err := utils.GenerateDeviceKey()
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+	device.KeyPrefix = prefix
+
+	tx := c.db.Begin()
+
+	err = c.deviceClient.UpdateDeviceKey(ctx, tx, id, prefix, utils.HashDeviceKey(key), session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = c.audit(ctx, tx, device, "rotate_key")
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	device, err = c.GetDevice(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return &model.DeviceWithKey{Device: device, APIKey: key}, nil
+}
+
+func (c *DeviceController) DeleteDevice(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteDevice")
+	defer span.Finish()
+
+	device, err := c.manageableDevice(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	tx := c.db.Begin()
+
+	deleted, err := c.deviceClient.DeleteDevice(ctx, tx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	if deleted == 0 {
+		utils.LogEventError(span, errors.New("device not found"))
+		tx.Rollback()
+		return model.ThrowError(http.StatusNotFound, errors.New("device not found"))
+	}
+
+	err = c.audit(ctx, tx, device, "delete")
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// Heartbeat records that the calling device is alive. Only requests authenticated with a device key may send it.
+func (c *DeviceController) Heartbeat(ctx context.Context, req *model.RequestDeviceHeartbeat) (*model.Device, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeviceHeartbeat")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if session.DeviceID == "" {
+		utils.LogEventError(span, errors.New("not a device"))
+		return nil, model.ThrowError(http.StatusForbidden, fmt.Errorf("heartbeats must be sent with the %s header", model.DeviceKeyHeader))
+	}
+
+	if len(req.Metadata) > 0 && !json.Valid(req.Metadata) {
+		utils.LogEventError(span, errors.New("invalid metadata"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("metadata must be valid JSON"))
+	}
+
+	err = c.deviceClient.UpdateHeartbeat(ctx, session.DeviceID, session.IPAddress, req.Metadata)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return c.GetDevice(ctx, session.DeviceID)
+}
+
+// manageableDevice returns the device if the caller administers its institution or holds a system role.
+func (c *DeviceController) manageableDevice(ctx context.Context, id string) (*model.Device, error) {
+	device, err := c.GetDevice(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, device.InstitutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return device, nil
+}
+
+// markOnline reports a device online when its last heartbeat is within device.heartbeatTimeout.
+func (c *DeviceController) markOnline(device *model.Device) {
+	device.Online = device.LastHeartbeatAt != nil && time.Since(*device.LastHeartbeatAt) <= c.cfg.Device.OfflineAfter()
+}
+
+func (c *DeviceController) audit(ctx context.Context, tx *gorm.DB, device *model.Device, action string) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := json.Marshal(map[string]any{
+		"name":       device.Name,
+		"location":   device.Location,
+		"status":     device.Status,
+		"key_prefix": device.KeyPrefix,
+	})
+	if err != nil {
+		return err
+	}
+
+	var actor *string
+	if session.UserID != "" {
+		actor = &session.UserID
+	}
+
+	return c.auditClient.InsertAudit(ctx, tx, &model.AuditLog{
+		ID:            uuid.New().String(),
+		ActorUserID:   actor,
+		InstitutionID: &device.InstitutionID,
+		Action:        fmt.Sprintf("%s.%s", deviceEntity, action),
+		EntityType:    deviceEntity,
+		EntityID:      device.ID,
+		IPAddress:     session.IPAddress,
+		UserAgent:     session.UserAgent,
+		Metadata:      string(metadata),
+		CreatedAt:     time.Now(),
+	})
+}
@@ -0,0 +1,463 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InterfaceEnrollmentController interface {
+	CreateSession(ctx context.Context, request *model.RequestCreateEnrollment) (*model.EnrollmentSession, error)
+	GetSession(ctx context.Context, id string) (*model.EnrollmentSession, error)
+	GetSessions(ctx context.Context, status string) ([]*model.EnrollmentSession, error)
+	AddCapture(ctx context.Context, request *model.RequestEnrollmentCapture) (*model.EnrollmentSession, error)
+	CancelSession(ctx context.Context, id string) error
+	GetReadiness(ctx context.Context, institutionID string) (*model.EnrollmentReadiness, error)
+}
+
+// defaultEnrollmentExpiry bounds sessions created without expires_in_hours, so an abandoned session cannot block
+// training forever.
+const defaultEnrollmentExpiry = 24 * time.Hour
+
+type EnrollmentController struct {
+	enrollmentClient  client.InterfaceEnrollmentClient
+	userClient        client.InterfaceUserClient
+	roleClient        client.InterfaceRoleClient
+	datasetController InterfaceDatasetController
+	db                *gorm.DB
+}
+
+func NewEnrollmentController(enrollmentClient client.InterfaceEnrollmentClient, userClient client.InterfaceUserClient, roleClient client.InterfaceRoleClient, datasetController InterfaceDatasetController, db *gorm.DB) *EnrollmentController {
+	return &EnrollmentController{
+		enrollmentClient:  enrollmentClient,
+		userClient:        userClient,
+		roleClient:        roleClient,
+		datasetController: datasetController,
+		db:                db,
+	}
+}
+
+func (c *EnrollmentController) CreateSession(ctx context.Context, request *model.RequestCreateEnrollment) (*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CreateEnrollmentSession")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if request.Username == "" {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("username is required"))
+	}
+
+	err = c.authorizeEnrollmentUser(ctx, session.InstitutionID, request.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	user, err := c.userClient.GetUserDetail(ctx, request.Username, session.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = c.enrollmentClient.ExpireSessions(ctx, session.InstitutionID, defaultEnrollmentExpiry)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	existing, err := c.enrollmentClient.GetOpenSessionByUser(ctx, session.InstitutionID, user.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+	if existing != nil {
+		utils.LogEventError(span, errors.New("user already has an open enrollment session"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("user already has an open enrollment session %s", existing.ID))
+	}
+
+	now := utils.LocalTime()
+	enrollment := &model.EnrollmentSession{
+		ID:                uuid.New().String(),
+		InstitutionID:     session.InstitutionID,
+		UserID:            user.ID,
+		Username:          user.Username,
+		Status:            model.EnrollmentStatusOpen,
+		RequiredCount:     request.RequiredCount,
+		RequiredFrontal:   request.RequiredFrontal,
+		RequiredLeft:      request.RequiredLeft,
+		RequiredRight:     request.RequiredRight,
+		RequireGlassesOff: request.RequireGlassesOff,
+		CreatedAt:         now,
+		CreatedBy:         session.Username,
+		UpdatedAt:         now,
+		UpdatedBy:         session.Username,
+	}
+
+	if enrollment.RequiredCount <= 0 && enrollment.RequiredFrontal <= 0 && enrollment.RequiredLeft <= 0 && enrollment.RequiredRight <= 0 {
+		enrollment.RequiredCount = 5
+		enrollment.RequiredFrontal = 3
+		enrollment.RequiredLeft = 1
+		enrollment.RequiredRight = 1
+	}
+
+	expiresAt := now.Add(defaultEnrollmentExpiry)
+	if request.ExpiresInHours > 0 {
+		expiresAt = now.Add(time.Duration(request.ExpiresInHours) * time.Hour)
+	}
+	enrollment.ExpiresAt = &expiresAt
+
+	err = c.enrollmentClient.CreateSession(ctx, enrollment)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	enrollment.Progress = computeEnrollmentProgress(enrollment, nil)
+
+	utils.LogEvent(span, "Response", enrollment)
+
+	return enrollment, nil
+}
+
+func (c *EnrollmentController) GetSession(ctx context.Context, id string) (*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetEnrollmentSession")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	enrollment, err := c.getSessionForCaller(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	captures, err := c.enrollmentClient.GetCaptures(ctx, enrollment.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	enrollment.Progress = computeEnrollmentProgress(enrollment, captures)
+
+	utils.LogEvent(span, "Response", enrollment)
+
+	return enrollment, nil
+}
+
+func (c *EnrollmentController) GetSessions(ctx context.Context, status string) ([]*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetEnrollmentSessions")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", status)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = c.enrollmentClient.ExpireSessions(ctx, session.InstitutionID, defaultEnrollmentExpiry)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.enrollmentClient.GetSessions(ctx, session.InstitutionID, status)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	for _, enrollment := range res {
+		captures, err := c.enrollmentClient.GetCaptures(ctx, enrollment.ID)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+		enrollment.Progress = computeEnrollmentProgress(enrollment, captures)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// AddCapture stores the captured images through the regular dataset upload path, records them
+// against the session and closes the session once every requirement is met.
+func (c *EnrollmentController) AddCapture(ctx context.Context, request *model.RequestEnrollmentCapture) (*model.EnrollmentSession, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: AddEnrollmentCapture")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request.SessionID)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if request.Pose != model.PoseFrontal && request.Pose != model.PoseLeft && request.Pose != model.PoseRight {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("pose must be one of frontal, left, right"))
+	}
+
+	enrollment, err := c.getSessionForCaller(ctx, request.SessionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = c.authorizeEnrollmentUser(ctx, enrollment.InstitutionID, enrollment.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if enrollment.Status != model.EnrollmentStatusOpen {
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("enrollment session is %s", enrollment.Status))
+	}
+
+	if enrollment.ExpiresAt != nil && enrollment.ExpiresAt.Before(utils.LocalTime()) {
+		if err := c.enrollmentClient.UpdateSessionStatus(ctx, nil, enrollment.ID, model.EnrollmentStatusExpired, session.Username); err != nil {
+			utils.LogEventError(span, err)
+		}
+		return nil, model.ThrowError(http.StatusConflict, errors.New("enrollment session has expired"))
+	}
+
+	for i, file := range request.File {
+		file.FileName = fmt.Sprintf("%s_%s_%d_%d%s", enrollment.ID[:8], request.Pose, time.Now().UnixNano(), i, path.Ext(file.FileName))
+	}
+
+	err = c.datasetController.UploadUserDataset(ctx, &model.Dataset{
+		Username: enrollment.Username,
+		File:     request.File,
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	tx := c.db.Begin()
+
+	for _, file := range request.File {
+		err = c.enrollmentClient.InsertCapture(ctx, tx, &model.EnrollmentCapture{
+			ID:         uuid.New().String(),
+			SessionID:  enrollment.ID,
+			Pose:       request.Pose,
+			GlassesOff: request.GlassesOff,
+			FileName:   file.FileName,
+			CreatedBy:  session.Username,
+		})
+		if err != nil {
+			utils.LogEventError(span, err)
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	captures, err := c.enrollmentClient.GetCaptures(ctx, enrollment.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	enrollment.Progress = computeEnrollmentProgress(enrollment, captures)
+
+	if enrollment.Progress.IsComplete {
+		err = c.enrollmentClient.UpdateSessionStatus(ctx, nil, enrollment.ID, model.EnrollmentStatusCompleted, session.Username)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+		now := utils.LocalTime()
+		enrollment.Status = model.EnrollmentStatusCompleted
+		enrollment.CompletedAt = &now
+	}
+
+	utils.LogEvent(span, "Response", enrollment)
+
+	return enrollment, nil
+}
+
+func (c *EnrollmentController) CancelSession(ctx context.Context, id string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CancelEnrollmentSession")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	enrollment, err := c.getSessionForCaller(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.authorizeEnrollmentUser(ctx, enrollment.InstitutionID, enrollment.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if enrollment.Status != model.EnrollmentStatusOpen {
+		return model.ThrowError(http.StatusConflict, fmt.Errorf("enrollment session is %s", enrollment.Status))
+	}
+
+	err = c.enrollmentClient.UpdateSessionStatus(ctx, nil, enrollment.ID, model.EnrollmentStatusCancelled, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetReadiness reports whether an institution can be trained, i.e. no enrollment session is still collecting captures.
+func (c *EnrollmentController) GetReadiness(ctx context.Context, institutionID string) (*model.EnrollmentReadiness, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetEnrollmentReadiness")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = c.enrollmentClient.ExpireSessions(ctx, institutionID, defaultEnrollmentExpiry)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	open, err := c.enrollmentClient.GetSessions(ctx, institutionID, model.EnrollmentStatusOpen)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res := &model.EnrollmentReadiness{
+		InstitutionID: institutionID,
+		IsReady:       len(open) == 0,
+		OpenSessions:  len(open),
+		PendingUsers:  []string{},
+	}
+	for _, enrollment := range open {
+		res.PendingUsers = append(res.PendingUsers, enrollment.Username)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *EnrollmentController) getSessionForCaller(ctx context.Context, id string) (*model.EnrollmentSession, error) {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := c.enrollmentClient.GetSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if enrollment.InstitutionID != session.InstitutionID {
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("you are not allowed to access this data (different institution)"))
+	}
+
+	return enrollment, nil
+}
+
+// authorizeEnrollmentUser lets users enroll themselves; opening, capturing or cancelling the session of another user
+// requires an administrator of the institution or a system role, since the captures end up in that user's dataset.
+func (c *EnrollmentController) authorizeEnrollmentUser(ctx context.Context, institutionID string, username string) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	if session.Username == username {
+		return nil
+	}
+
+	return authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+}
+
+func computeEnrollmentProgress(enrollment *model.EnrollmentSession, captures []*model.EnrollmentCapture) *model.EnrollmentProgress {
+	counts := map[string]int{}
+	glassesOff := 0
+	for _, capture := range captures {
+		counts[capture.Pose]++
+		if capture.GlassesOff {
+			glassesOff++
+		}
+	}
+
+	var requirements []*model.EnrollmentRequirement
+	addRequirement := func(name string, required int, captured int) {
+		if required <= 0 {
+			return
+		}
+		requirements = append(requirements, &model.EnrollmentRequirement{
+			Name:     name,
+			Required: required,
+			Captured: captured,
+			IsMet:    captured >= required,
+		})
+	}
+
+	addRequirement("total", enrollment.RequiredCount, len(captures))
+	addRequirement(model.PoseFrontal, enrollment.RequiredFrontal, counts[model.PoseFrontal])
+	addRequirement(model.PoseLeft, enrollment.RequiredLeft, counts[model.PoseLeft])
+	addRequirement(model.PoseRight, enrollment.RequiredRight, counts[model.PoseRight])
+	if enrollment.RequireGlassesOff {
+		addRequirement("glasses_off", 1, glassesOff)
+	}
+
+	required, captured := 0, 0
+	complete := true
+	for _, requirement := range requirements {
+		required += requirement.Required
+		captured += min(requirement.Captured, requirement.Required)
+		if !requirement.IsMet {
+			complete = false
+		}
+	}
+
+	percent := 100
+	if required > 0 {
+		percent = captured * 100 / required
+	}
+
+	return &model.EnrollmentProgress{
+		Requirements: requirements,
+		Percent:      percent,
+		IsComplete:   complete,
+	}
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"face-recognition-svc/gateway/app/model"
+	"testing"
+)
+
+func TestComputeEnrollmentProgress(t *testing.T) {
+	defaults := &model.EnrollmentSession{RequiredCount: 5, RequiredFrontal: 3, RequiredLeft: 1, RequiredRight: 1}
+
+	captures := func(poses ...string) []*model.EnrollmentCapture {
+		var res []*model.EnrollmentCapture
+		for _, pose := range poses {
+			res = append(res, &model.EnrollmentCapture{Pose: pose})
+		}
+		return res
+	}
+
+	tests := []struct {
+		name         string
+		enrollment   *model.EnrollmentSession
+		captures     []*model.EnrollmentCapture
+		wantNames    []string
+		wantMet      []bool
+		wantPercent  int
+		wantComplete bool
+	}{
+		{
+			name:         "no captures",
+			enrollment:   defaults,
+			wantNames:    []string{"total", model.PoseFrontal, model.PoseLeft, model.PoseRight},
+			wantMet:      []bool{false, false, false, false},
+			wantPercent:  0,
+			wantComplete: false,
+		},
+		{
+			name:         "every requirement met",
+			enrollment:   defaults,
+			captures:     captures(model.PoseFrontal, model.PoseFrontal, model.PoseFrontal, model.PoseLeft, model.PoseRight),
+			wantNames:    []string{"total", model.PoseFrontal, model.PoseLeft, model.PoseRight},
+			wantMet:      []bool{true, true, true, true},
+			wantPercent:  100,
+			wantComplete: true,
+		},
+		{
+			name:         "extra captures of one pose count only up to its requirement",
+			enrollment:   defaults,
+			captures:     captures(model.PoseFrontal, model.PoseFrontal, model.PoseFrontal, model.PoseFrontal, model.PoseFrontal, model.PoseFrontal),
+			wantNames:    []string{"total", model.PoseFrontal, model.PoseLeft, model.PoseRight},
+			wantMet:      []bool{true, true, false, false},
+			wantPercent:  80,
+			wantComplete: false,
+		},
+		{
+			name:         "glasses off is a separate requirement",
+			enrollment:   &model.EnrollmentSession{RequiredFrontal: 1, RequireGlassesOff: true},
+			captures:     captures(model.PoseFrontal),
+			wantNames:    []string{model.PoseFrontal, "glasses_off"},
+			wantMet:      []bool{true, false},
+			wantPercent:  50,
+			wantComplete: false,
+		},
+		{
+			name:       "glasses off met",
+			enrollment: &model.EnrollmentSession{RequiredFrontal: 1, RequireGlassesOff: true},
+			captures: []*model.EnrollmentCapture{
+				{Pose: model.PoseFrontal, GlassesOff: true},
+			},
+			wantNames:    []string{model.PoseFrontal, "glasses_off"},
+			wantMet:      []bool{true, true},
+			wantPercent:  100,
+			wantComplete: true,
+		},
+		{
+			name:         "no requirements",
+			enrollment:   &model.EnrollmentSession{},
+			wantPercent:  100,
+			wantComplete: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeEnrollmentProgress(tt.enrollment, tt.captures)
+
+			if len(got.Requirements) != len(tt.wantNames) {
+				t.Fatalf("got %d requirements, want %d", len(got.Requirements), len(tt.wantNames))
+			}
+			for i, requirement := range got.Requirements {
+				if requirement.Name != tt.wantNames[i] || requirement.IsMet != tt.wantMet[i] {
+					t.Errorf("requirement %d = %s met %t, want %s met %t", i, requirement.Name, requirement.IsMet, tt.wantNames[i], tt.wantMet[i])
+				}
+			}
+			if got.Percent != tt.wantPercent {
+				t.Errorf("Percent = %d, want %d", got.Percent, tt.wantPercent)
+			}
+			if got.IsComplete != tt.wantComplete {
+				t.Errorf("IsComplete = %t, want %t", got.IsComplete, tt.wantComplete)
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InterfaceFaceEmbeddingController interface {
+	HandleEmbeddings(ctx context.Context, req *model.TrainModelEmbeddings) error
+	SearchEmbeddings(ctx context.Context, req *model.RequestEmbeddingSearch) (*model.EmbeddingSearchResult, error)
+	CleanupEmbeddings(ctx context.Context, repair bool) (*model.EmbeddingCleanupReport, error)
+}
+
+type FaceEmbeddingController struct {
+	embeddingClient  client.InterfaceFaceEmbeddingClient
+	datasetClient    client.InterfaceDatasetClient
+	modelClient      client.InterfaceModelClient
+	roleClient       client.InterfaceRoleClient
+	auditClient      client.InterfaceAuditClient
+	policyController InterfaceRecognitionPolicyController
+	db               *gorm.DB
+}
+
+func NewFaceEmbeddingController(embeddingClient client.InterfaceFaceEmbeddingClient, datasetClient client.InterfaceDatasetClient, modelClient client.InterfaceModelClient, roleClient client.InterfaceRoleClient, auditClient client.InterfaceAuditClient, policyController InterfaceRecognitionPolicyController, db *gorm.DB) *FaceEmbeddingController {
+	return &FaceEmbeddingController{
+		embeddingClient:  embeddingClient,
+		datasetClient:    datasetClient,
+		modelClient:      modelClient,
+		roleClient:       roleClient,
+		auditClient:      auditClient,
+		policyController: policyController,
+		db:               db,
+	}
+}
+
+// HandleEmbeddings stores the embeddings a training reports. Every vector of a training must have the dimension
+// of its first stored embeddings, which is fixed under a lock of the training row so concurrent messages cannot
+// store different dimensions; a message breaking that, or naming an unknown training, is rejected as invalid. The
+// first embeddings of an incremental training also bring over its base model's templates of unchanged images.
+func (c *FaceEmbeddingController) HandleEmbeddings(ctx context.Context, req *model.TrainModelEmbeddings) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: HandleFaceEmbeddings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"id": req.ID, "embeddings": len(req.Embeddings)})
+
+	if len(req.Embeddings) == 0 {
+		return nil
+	}
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, req.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if training == nil || training.ID == "" {
+		utils.LogEventError(span, errors.New("training not found"))
+		return fmt.Errorf("%w: unknown training %q", utils.ErrInvalidMessage, req.ID)
+	}
+
+	if training.Status == model.ModelTrainingStatusFailed || training.Status == model.ModelTrainingStatusCancelled {
+		utils.LogEvent(span, "Ignored", "training did not succeed")
+		return nil
+	}
+
+	dimension := len(req.Embeddings[0].Vector)
+	now := time.Now()
+	embeddings := make([]*model.FaceEmbedding, 0, len(req.Embeddings))
+	for _, trained := range req.Embeddings {
+		if len(trained.Vector) != dimension {
+			utils.LogEventError(span, errors.New("embedding dimension mismatch"))
+			return fmt.Errorf("%w: embedding of %s has %d dimensions, the message uses %d", utils.ErrInvalidMessage, trained.ImageKey, len(trained.Vector), dimension)
+		}
+
+		vector, err := normalizeEmbedding(trained.Vector)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return fmt.Errorf("%w: embedding of %s: %v", utils.ErrInvalidMessage, trained.ImageKey, err)
+		}
+
+		embeddings = append(embeddings, &model.FaceEmbedding{
+			ID:              uuid.New().String(),
+			InstitutionID:   training.InstitutionID,
+			ModelTrainingID: training.ID,
+			ModelVersion:    training.Version,
+			Username:        trained.Username,
+			ImageKey:        trained.ImageKey,
+			Dimension:       dimension,
+			Vector:          vector,
+			CreatedAt:       now,
+		})
+	}
+
+	tx := c.db.Begin()
+
+	stored, claimed, err := c.embeddingClient.ClaimDimension(ctx, tx, training.ID, dimension)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	if stored != dimension {
+		utils.LogEventError(span, errors.New("embedding dimension mismatch"))
+		tx.Rollback()
+		return fmt.Errorf("%w: embeddings have %d dimensions, the training uses %d", utils.ErrInvalidMessage, dimension, stored)
+	}
+
+	if claimed && training.Mode == model.TrainingModeIncremental {
+		copied, err := c.embeddingClient.CopyBaseEmbeddings(ctx, tx, training, dimension)
+		if err != nil {
+			utils.LogEventError(span, err)
+			tx.Rollback()
+			return err
+		}
+		utils.LogEvent(span, "Base embeddings", copied)
+	}
+
+	err = c.embeddingClient.UpsertEmbeddings(ctx, tx, embeddings)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	// Without the index searches still work, exactly and slower, so a failure does not reject the message.
+	if claimed {
+		if err := c.embeddingClient.EnsureVectorIndex(ctx, dimension); err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	return nil
+}
+
+// SearchEmbeddings returns the users whose templates are closest to the embedding, computed by the caller with
+// the same model version.
+func (c *FaceEmbeddingController) SearchEmbeddings(ctx context.Context, req *model.RequestEmbeddingSearch) (*model.EmbeddingSearchResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: SearchFaceEmbeddings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"institution_id": req.InstitutionID, "model_version": req.ModelVersion, "dimension": len(req.Embedding), "top_k": req.TopK})
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if req.InstitutionID == "" {
+		req.InstitutionID = session.InstitutionID
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, req.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if len(req.Embedding) == 0 || len(req.Embedding) > model.FaceEmbeddingMaxDimension {
+		utils.LogEventError(span, errors.New("invalid embedding"))
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("embedding must have between 1 and %d values", model.FaceEmbeddingMaxDimension))
+	}
+
+	embedding, err := normalizeEmbedding(req.Embedding)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusBadRequest, err)
+	}
+
+	policy, err := c.policyController.ResolvePolicy(ctx, req.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	topK := req.TopK
+	if topK == 0 {
+		topK = policy.TopK
+	}
+	if topK < 1 || topK > recognitionPolicyMaxTopK {
+		utils.LogEventError(span, errors.New("invalid top k"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("top_k must be between 1 and 100"))
+	}
+
+	target, err := c.resolveModel(ctx, req.InstitutionID, req.ModelVersion)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	dimension, err := c.embeddingClient.GetDimension(ctx, target.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if dimension == 0 {
+		utils.LogEventError(span, errors.New("no embeddings stored"))
+		return nil, model.ThrowError(http.StatusNotFound, fmt.Errorf("no embeddings are stored for model version %d", target.Version))
+	}
+
+	if dimension != len(embedding) {
+		utils.LogEventError(span, errors.New("embedding dimension mismatch"))
+		return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("model version %d uses %d dimensions, the embedding has %d", target.Version, dimension, len(embedding)))
+	}
+
+	matches, err := c.embeddingClient.SearchNearest(ctx, target.ID, embedding, topK)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res := &model.EmbeddingSearchResult{
+		ModelID:      target.ID,
+		ModelVersion: target.Version,
+		Dimension:    dimension,
+		Threshold:    policy.MatchThreshold,
+		Matches:      matches,
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// CleanupEmbeddings reports the templates stored with another dimension than their training's, which searches skip.
+// With repair it deletes them, one training and dimension per transaction, and records every deletion in the audit
+// log with the number of templates removed. It needs a system role, as it spans every institution.
+func (c *FaceEmbeddingController) CleanupEmbeddings(ctx context.Context, repair bool) (*model.EmbeddingCleanupReport, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CleanupFaceEmbeddings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", repair)
+
+	if err := authorizeSystem(ctx, c.roleClient); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	mismatches, err := c.embeddingClient.GetDimensionMismatches(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	report := &model.EmbeddingCleanupReport{
+		Repair:     repair,
+		Mismatches: mismatches,
+	}
+
+	if repair {
+		for _, mismatch := range mismatches {
+			deleted, err := c.deleteDimensionMismatch(ctx, mismatch)
+			if err != nil {
+				utils.LogEventError(span, err)
+				report.Errors = append(report.Errors, fmt.Sprintf("delete %d dimension embeddings of %s: %s", mismatch.Dimension, mismatch.ModelTrainingID, err.Error()))
+				continue
+			}
+			mismatch.Deleted = deleted
+			report.Deleted += deleted
+		}
+	}
+
+	utils.LogEvent(span, "Response", report)
+
+	return report, nil
+}
+
+func (c *FaceEmbeddingController) deleteDimensionMismatch(ctx context.Context, mismatch *model.EmbeddingDimensionMismatch) (int64, error) {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx := c.db.Begin()
+
+	deleted, err := c.embeddingClient.DeleteDimensionMismatches(ctx, tx, mismatch.ModelTrainingID, mismatch.Dimension)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	metadata, err := json.Marshal(map[string]any{
+		"dimension":          mismatch.Dimension,
+		"training_dimension": mismatch.TrainingDimension,
+		"deleted":            deleted,
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var actor *string
+	if session.UserID != "" {
+		actor = &session.UserID
+	}
+
+	err = c.auditClient.InsertAudit(ctx, tx, &model.AuditLog{
+		ID:            uuid.New().String(),
+		ActorUserID:   actor,
+		InstitutionID: &mismatch.InstitutionID,
+		Action:        "face_embedding.dimension_cleanup",
+		EntityType:    "model_training",
+		EntityID:      mismatch.ModelTrainingID,
+		IPAddress:     session.IPAddress,
+		UserAgent:     session.UserAgent,
+		Metadata:      string(metadata),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// resolveModel returns the institution's model with version, or its active model when version is nil.
+func (c *FaceEmbeddingController) resolveModel(ctx context.Context, institutionID string, version *int) (*model.ModelTraining, error) {
+	if version == nil {
+		active, err := c.modelClient.GetActiveModel(ctx, institutionID)
+		if err != nil {
+			return nil, err
+		}
+		if active == nil {
+			return nil, model.ThrowError(http.StatusConflict, errors.New("institution has no active model"))
+		}
+		return active, nil
+	}
+
+	models, err := c.modelClient.GetModels(ctx, institutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range models {
+		if candidate.Version == *version {
+			return candidate, nil
+		}
+	}
+
+	return nil, model.ThrowError(http.StatusNotFound, fmt.Errorf("model version %d not found", *version))
+}
+
+// normalizeEmbedding scales a vector to unit length so a dot product of two vectors is their cosine similarity.
+func normalizeEmbedding(vector []float64) ([]float64, error) {
+	var sum float64
+	for _, value := range vector {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, errors.New("embedding values must be finite")
+		}
+		sum += value * value
+	}
+
+	if sum == 0 {
+		return nil, errors.New("embedding must not be a zero vector")
+	}
+
+	norm := math.Sqrt(sum)
+	res := make([]float64, len(vector))
+	for i, value := range vector {
+		res[i] = value / norm
+	}
+
+	return res, nil
+}
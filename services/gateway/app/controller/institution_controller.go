@@ -3,8 +3,11 @@ package controller
 import (
 	"context"
 	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
 	"face-recognition-svc/gateway/app/model"
 	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
 
 	"github.com/google/uuid"
 )
@@ -19,10 +22,14 @@ type InterfaceInstitutionController interface {
 
 type InstitutionController struct {
 	institutionClient client.InterfaceInstitutionClient
+	cfg               *config.Config
 }
 
-func NewInstitutionController(institutionClient client.InterfaceInstitutionClient) *InstitutionController {
-	return &InstitutionController{institutionClient: institutionClient}
+func NewInstitutionController(institutionClient client.InterfaceInstitutionClient, cfg *config.Config) *InstitutionController {
+	return &InstitutionController{
+		institutionClient: institutionClient,
+		cfg:               cfg,
+	}
 }
 
 func (c *InstitutionController) GetAllInstitution(ctx context.Context, pagination *model.Pagination, filter *model.Filter) ([]*model.Institution, *model.Pagination, error) {
@@ -76,6 +83,12 @@ func (c *InstitutionController) InsertNewInstitution(ctx context.Context, instit
 	}
 	utils.LogEvent(span, "Request", institution)
 
+	err = c.validateTrainingTier(institution.TrainingTier)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
 	err = c.institutionClient.CreateNewInstitution(ctx, institution)
 	if err != nil {
 		utils.LogEventError(span, err)
@@ -92,7 +105,14 @@ func (c *InstitutionController) UpdateInstitution(ctx context.Context, instituti
 
 	utils.LogEvent(span, "Request", institution)
 	institution.UpdatedAt = utils.LocalTime()
-	err := c.institutionClient.UpdateInstitution(ctx, institution)
+
+	err := c.validateTrainingTier(institution.TrainingTier)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	err = c.institutionClient.UpdateInstitution(ctx, institution)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return err
@@ -116,3 +136,15 @@ func (c *InstitutionController) DeleteInstitution(ctx context.Context, id string
 	utils.LogEvent(span, "Response", "Success Delete Institution")
 	return nil
 }
+
+// validateTrainingTier accepts an empty tier, which keeps the current one, or a tier listed in
+// training.tierPriorities.
+func (c *InstitutionController) validateTrainingTier(tier string) error {
+	if tier == "" {
+		return nil
+	}
+	if _, ok := c.cfg.Training.TierPriorities[tier]; !ok {
+		return model.ThrowError(http.StatusBadRequest, fmt.Errorf("unknown training_tier %q", tier))
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InterfaceModelController interface {
+	GetModels(ctx context.Context, institutionID string) ([]*model.ModelTraining, error)
+	GetActiveModel(ctx context.Context, institutionID string) (*model.ModelTraining, error)
+	GetActivationHistory(ctx context.Context, institutionID string) ([]*model.ModelActivation, error)
+	ActivateModel(ctx context.Context, id string) (*model.ModelTraining, error)
+	RollbackModel(ctx context.Context, institutionID string, request *model.RequestRollbackModel) (*model.ModelTraining, error)
+	CompareModels(ctx context.Context, baseID string, candidateID string) (*model.ModelComparison, error)
+}
+
+type ModelController struct {
+	modelClient          client.InterfaceModelClient
+	trainingMetricClient client.InterfaceTrainingMetricClient
+	roleClient           client.InterfaceRoleClient
+	cfg                  *config.Config
+	db                   *gorm.DB
+}
+
+func NewModelController(modelClient client.InterfaceModelClient, trainingMetricClient client.InterfaceTrainingMetricClient, roleClient client.InterfaceRoleClient, cfg *config.Config, db *gorm.DB) *ModelController {
+	return &ModelController{
+		modelClient:          modelClient,
+		trainingMetricClient: trainingMetricClient,
+		roleClient:           roleClient,
+		cfg:                  cfg,
+		db:                   db,
+	}
+}
+
+func (c *ModelController) GetModels(ctx context.Context, institutionID string) ([]*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetModels")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.modelClient.GetModels(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *ModelController) GetActiveModel(ctx context.Context, institutionID string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetActiveModel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.modelClient.GetActiveModel(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if res == nil {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("no active model for this institution"))
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *ModelController) GetActivationHistory(ctx context.Context, institutionID string) ([]*model.ModelActivation, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetActivationHistory")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.modelClient.GetActivations(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *ModelController) ActivateModel(ctx context.Context, id string) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ActivateModel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	target, err := c.modelClient.GetModelByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, target.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.activate(ctx, target, model.ModelActivationActionActivate)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// RollbackModel re-activates request.ModelID, or the model that was active before the latest activation when it is empty.
+func (c *ModelController) RollbackModel(ctx context.Context, institutionID string, request *model.RequestRollbackModel) (*model.ModelTraining, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RollbackModel")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", request)
+
+	err := authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	targetID := request.ModelID
+	if targetID == "" {
+		activations, err := c.modelClient.GetActivations(ctx, institutionID)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		if len(activations) == 0 || activations[0].PreviousModelTrainingID == nil {
+			utils.LogEventError(span, errors.New("no previous model to roll back to"))
+			return nil, model.ThrowError(http.StatusConflict, errors.New("no previous model to roll back to"))
+		}
+
+		targetID = *activations[0].PreviousModelTrainingID
+	}
+
+	target, err := c.modelClient.GetModelByID(ctx, targetID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if target.InstitutionID != institutionID {
+		utils.LogEventError(span, errors.New("model belongs to another institution"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("model belongs to another institution"))
+	}
+
+	res, err := c.activate(ctx, target, model.ModelActivationActionRollback)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// activate switches the served model in one transaction and publishes the activation event once it is committed.
+func (c *ModelController) activate(ctx context.Context, target *model.ModelTraining, action string) (*model.ModelTraining, error) {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Status != model.ModelTrainingStatusSucceeded {
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("model with status %s cannot be activated", target.Status))
+	}
+
+	tx := c.db.Begin()
+
+	previous, err := c.modelClient.LockActiveModel(ctx, tx, target.InstitutionID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var previousID *string
+	if previous != nil {
+		if previous.ID == target.ID {
+			tx.Rollback()
+			return nil, model.ThrowError(http.StatusConflict, errors.New("model is already active"))
+		}
+		previousID = &previous.ID
+	}
+
+	err = c.modelClient.ActivateModel(ctx, tx, target.InstitutionID, target.ID, session.Username)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	now := time.Now()
+	err = c.modelClient.InsertActivation(ctx, tx, &model.ModelActivation{
+		ID:                      uuid.New().String(),
+		InstitutionID:           target.InstitutionID,
+		ModelTrainingID:         target.ID,
+		PreviousModelTrainingID: previousID,
+		Action:                  action,
+		CreatedAt:               now,
+		CreatedBy:               session.Username,
+	})
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The activation is already committed, so a failed publish is only logged. Recognition reads the active model from
+	// the registry on every request; only workers caching the model miss the switch until the next event.
+	err = c.modelClient.PublishActivation(ctx, &model.ModelActivationEvent{
+		InstitutionID:   target.InstitutionID,
+		ModelID:         target.ID,
+		Version:         target.Version,
+		ModelPath:       target.ModelPath,
+		BucketName:      c.cfg.MinioProfile.Bucket,
+		PreviousModelID: previousID,
+		Action:          action,
+		ActivatedBy:     session.Username,
+		ActivatedAt:     now,
+	})
+	if err != nil {
+		span, _ := utils.SpanFromContext(ctx, "Controller: PublishActivation")
+		utils.LogEventError(span, err)
+		span.Finish()
+	}
+
+	target.IsUsed = "Y"
+	target.ActivatedAt = &now
+	target.ActivatedBy = &session.Username
+
+	return target, nil
+}
+
+// CompareModels returns two models of the same institution with their metrics and the candidate's change
+// relative to base.
+func (c *ModelController) CompareModels(ctx context.Context, baseID string, candidateID string) (*model.ModelComparison, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: CompareModels")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]string{"base": baseID, "candidate": candidateID})
+
+	base, err := c.modelClient.GetModelByID(ctx, baseID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	candidate, err := c.modelClient.GetModelByID(ctx, candidateID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if base.InstitutionID != candidate.InstitutionID {
+		utils.LogEventError(span, errors.New("models belong to different institutions"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("models belong to different institutions"))
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, base.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = attachTrainingMetrics(ctx, c.trainingMetricClient, []*model.ModelTraining{base, candidate})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res := &model.ModelComparison{
+		Base:      base,
+		Candidate: candidate,
+		Diff:      diffTrainingMetrics(base.Metrics, candidate.Metrics),
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// diffTrainingMetrics returns nil unless both models have metrics. Threshold rows are paired by threshold value.
+func diffTrainingMetrics(base *model.ModelTrainingMetric, candidate *model.ModelTrainingMetric) *model.ModelMetricDiff {
+	if base == nil || candidate == nil {
+		return nil
+	}
+
+	diff := &model.ModelMetricDiff{}
+	if base.Identities != nil && candidate.Identities != nil {
+		d := *candidate.Identities - *base.Identities
+		diff.Identities = &d
+	}
+	if base.Images != nil && candidate.Images != nil {
+		d := *candidate.Images - *base.Images
+		diff.Images = &d
+	}
+	if base.ValidationAccuracy != nil && candidate.ValidationAccuracy != nil {
+		d := *candidate.ValidationAccuracy - *base.ValidationAccuracy
+		diff.ValidationAccuracy = &d
+	}
+	if base.DurationSeconds != nil && candidate.DurationSeconds != nil {
+		d := *candidate.DurationSeconds - *base.DurationSeconds
+		diff.DurationSeconds = &d
+	}
+	if base.ArtifactSizeBytes != nil && candidate.ArtifactSizeBytes != nil {
+		d := *candidate.ArtifactSizeBytes - *base.ArtifactSizeBytes
+		diff.ArtifactSizeBytes = &d
+	}
+
+	var baseThresholds, candidateThresholds []model.ThresholdMetric
+	_ = json.Unmarshal(base.Thresholds, &baseThresholds)
+	_ = json.Unmarshal(candidate.Thresholds, &candidateThresholds)
+
+	byThreshold := map[float64]model.ThresholdMetric{}
+	for _, metric := range baseThresholds {
+		byThreshold[metric.Threshold] = metric
+	}
+	for _, metric := range candidateThresholds {
+		baseMetric, ok := byThreshold[metric.Threshold]
+		if !ok {
+			continue
+		}
+		diff.Thresholds = append(diff.Thresholds, model.ThresholdMetric{
+			Threshold: metric.Threshold,
+			FAR:       metric.FAR - baseMetric.FAR,
+			FRR:       metric.FRR - baseMetric.FRR,
+		})
+	}
+
+	return diff
+}
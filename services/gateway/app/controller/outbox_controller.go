@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InterfaceOutboxController interface {
+	Relay(ctx context.Context) (*model.OutboxRelayResult, error)
+	GetMetrics(ctx context.Context) (*model.OutboxMetrics, error)
+}
+
+const (
+	outboxMaxBackoff = 5 * time.Minute
+
+	// outboxClaimLease is how long a relay may take to publish a claimed batch before other relays take it over.
+	outboxClaimLease = 5 * time.Minute
+)
+
+type OutboxController struct {
+	outboxClient        client.InterfaceOutboxClient
+	datasetClient       client.InterfaceDatasetClient
+	lockClient          client.InterfaceLockClient
+	trainingEventClient client.InterfaceTrainingEventClient
+	roleClient          client.InterfaceRoleClient
+	cfg                 *config.Config
+	db                  *gorm.DB
+}
+
+func NewOutboxController(outboxClient client.InterfaceOutboxClient, datasetClient client.InterfaceDatasetClient, lockClient client.InterfaceLockClient, trainingEventClient client.InterfaceTrainingEventClient, roleClient client.InterfaceRoleClient, cfg *config.Config, db *gorm.DB) *OutboxController {
+	return &OutboxController{
+		outboxClient:        outboxClient,
+		datasetClient:       datasetClient,
+		lockClient:          lockClient,
+		trainingEventClient: trainingEventClient,
+		roleClient:          roleClient,
+		cfg:                 cfg,
+		db:                  db,
+	}
+}
+
+// Relay publishes one batch of due outbox messages. The batch is claimed in a short transaction and published
+// without holding any lock, then each message is marked sent or for retry. Delivery is at-least-once: a message
+// whose claim expires before it is marked, because the relay crashed or could not reach the database, is
+// published again. A training whose message runs out of attempts is failed and its training lock released, so
+// the institution can queue a new one.
+func (c *OutboxController) Relay(ctx context.Context) (*model.OutboxRelayResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RelayOutbox")
+	defer span.Finish()
+
+	batchSize := c.cfg.Job.OutboxRelay.BatchSize
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+
+	maxAttempts := c.cfg.Job.OutboxRelay.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+
+	claimedAt := time.Now()
+	messages, deferred, err := c.claim(ctx, batchSize)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	result := &model.OutboxRelayResult{Deferred: deferred}
+	for i, message := range messages {
+		// The rest of a batch still unpublished after half the lease keeps its claim and is published again once it
+		// expires, so a slow broker cannot make two relays publish the same message.
+		if time.Since(claimedAt) > outboxClaimLease/2 {
+			utils.LogEvent(span, "Unpublished", len(messages)-i)
+			break
+		}
+
+		publishErr := c.publish(ctx, message)
+		if publishErr == nil {
+			err = c.outboxClient.MarkSent(ctx, c.db, message.ID)
+			if err != nil {
+				utils.LogEventError(span, err)
+				continue
+			}
+			result.Sent++
+			continue
+		}
+
+		utils.LogEventError(span, publishErr)
+
+		failed, err := c.retry(ctx, message, publishErr, maxAttempts)
+		if err != nil {
+			utils.LogEventError(span, err)
+			continue
+		}
+		if !failed {
+			result.Retried++
+			continue
+		}
+		result.Failed++
+		if message.Topic == model.OutboxTopicTrainModel {
+			c.finishFailedTraining(ctx, message.AggregateID)
+		}
+	}
+
+	utils.LogEvent(span, "Response", result)
+
+	return result, nil
+}
+
+// claim fetches up to batchSize due messages and claims the ones that may be published for outboxClaimLease.
+// Their trainings are marked dispatched in the same transaction, under the dispatch lock, so concurrent relays
+// see the in-flight caps filled. Training messages over a cap stay pending and are counted as deferred.
+func (c *OutboxController) claim(ctx context.Context, batchSize int) ([]*model.OutboxMessage, int, error) {
+	tx := c.db.Begin()
+
+	messages, err := c.outboxClient.FetchPending(ctx, tx, batchSize, c.cfg.Training.PriorityAgingInterval(), c.cfg.Training.MaxPriorityLevel())
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	slots, err := c.trainingSlots(ctx, tx, messages)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	institutionInFlight, err := c.institutionInFlight(ctx, tx, messages)
+	if err != nil {
+		tx.Rollback()
+		return nil, 0, err
+	}
+
+	maxPerInstitution := int64(c.cfg.Training.MaxInFlightPerInstitution)
+
+	var claimed []*model.OutboxMessage
+	var ids []string
+	deferred := 0
+	for _, message := range messages {
+		if message.Topic == model.OutboxTopicTrainModel {
+			institutionID := trainingInstitution(message)
+			if slots == 0 || (maxPerInstitution > 0 && institutionInFlight[institutionID] >= maxPerInstitution) {
+				// Left pending and due, so it is picked up again once a slot frees.
+				deferred++
+				continue
+			}
+
+			err = c.datasetClient.MarkTrainingDispatched(ctx, tx, message.AggregateID)
+			if err != nil {
+				tx.Rollback()
+				return nil, 0, err
+			}
+			slots--
+			institutionInFlight[institutionID]++
+		}
+
+		claimed = append(claimed, message)
+		ids = append(ids, message.ID)
+	}
+
+	if len(ids) > 0 {
+		err = c.outboxClient.Claim(ctx, tx, ids, time.Now().Add(outboxClaimLease))
+		if err != nil {
+			tx.Rollback()
+			return nil, 0, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return claimed, deferred, nil
+}
+
+// retry schedules the next attempt of a message that could not be published and frees its training's in-flight
+// slot. Once maxAttempts is reached the message and its training are failed instead, and retry reports true.
+func (c *OutboxController) retry(ctx context.Context, message *model.OutboxMessage, publishErr error, maxAttempts int) (bool, error) {
+	isTraining := message.Topic == model.OutboxTopicTrainModel
+
+	attempts := message.Attempts + 1
+	status := model.OutboxStatusPending
+	if attempts >= maxAttempts {
+		status = model.OutboxStatusFailed
+	}
+
+	tx := c.db.Begin()
+
+	err := c.outboxClient.MarkRetry(ctx, tx, message.ID, status, publishErr.Error(), time.Now().Add(outboxBackoff(attempts)))
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	if isTraining {
+		err = c.datasetClient.ClearTrainingDispatched(ctx, tx, message.AggregateID)
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	if isTraining && status == model.OutboxStatusFailed {
+		_, err = c.datasetClient.UpdateTrainingStatus(ctx, tx, message.AggregateID, model.ModelTrainingStatusFailed,
+			[]string{model.ModelTrainingStatusStarted}, "system")
+		if err != nil {
+			tx.Rollback()
+			return false, err
+		}
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return false, err
+	}
+
+	return status == model.OutboxStatusFailed, nil
+}
+
+// finishFailedTraining announces a training failed by the relay and releases its lock. Errors are only logged: an
+// unreleased lock is still taken over by the next training request once the training is final.
+func (c *OutboxController) finishFailedTraining(ctx context.Context, id string) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: FinishFailedTraining")
+	defer span.Finish()
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return
+	}
+
+	_ = c.trainingEventClient.Publish(ctx, model.NewTrainingEvent(training))
+
+	if training.FencingToken == nil {
+		return
+	}
+
+	err = c.lockClient.Release(ctx, fmt.Sprintf(trainingLockKey, training.InstitutionID), *training.FencingToken)
+	if err != nil {
+		utils.LogEventError(span, err)
+	}
+}
+
+// GetMetrics reports on the shared outbox table, which spans every institution, so it requires a system role.
+func (c *OutboxController) GetMetrics(ctx context.Context) (*model.OutboxMetrics, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetOutboxMetrics")
+	defer span.Finish()
+
+	err := authorizeSystem(ctx, c.roleClient)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.outboxClient.GetMetrics(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *OutboxController) publish(ctx context.Context, message *model.OutboxMessage) error {
+	switch message.Topic {
+	case model.OutboxTopicTrainModel:
+		var request model.RequestAPITrainModel
+		if err := json.Unmarshal(message.Payload, &request); err != nil {
+			return err
+		}
+		request.Priority = c.trainingPriority(message)
+		_, err := c.datasetClient.TrainModel(ctx, &request)
+		return err
+	default:
+		return fmt.Errorf("unknown outbox topic %q", message.Topic)
+	}
+}
+
+// trainingSlots returns how many training messages may still be dispatched under training.maxInFlight, or -1
+// when there is no limit.
+func (c *OutboxController) trainingSlots(ctx context.Context, tx *gorm.DB, messages []*model.OutboxMessage) (int, error) {
+	maxInFlight := c.cfg.Training.MaxInFlight
+	if maxInFlight <= 0 {
+		return -1, nil
+	}
+
+	hasTraining := false
+	for _, message := range messages {
+		if message.Topic == model.OutboxTopicTrainModel {
+			hasTraining = true
+			break
+		}
+	}
+	if !hasTraining {
+		return 0, nil
+	}
+
+	inFlight, err := c.datasetClient.CountInFlightTrainings(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return max(maxInFlight-int(inFlight), 0), nil
+}
+
+// institutionInFlight counts the dispatched, unfinished trainings of the institutions in messages when
+// training.maxInFlightPerInstitution is set.
+func (c *OutboxController) institutionInFlight(ctx context.Context, tx *gorm.DB, messages []*model.OutboxMessage) (map[string]int64, error) {
+	if c.cfg.Training.MaxInFlightPerInstitution <= 0 {
+		return map[string]int64{}, nil
+	}
+
+	var institutionIDs []string
+	for _, message := range messages {
+		if message.Topic == model.OutboxTopicTrainModel {
+			institutionIDs = append(institutionIDs, trainingInstitution(message))
+		}
+	}
+	if len(institutionIDs) == 0 {
+		return map[string]int64{}, nil
+	}
+
+	return c.datasetClient.CountInFlightTrainingsByInstitution(ctx, tx, institutionIDs)
+}
+
+// trainingInstitution returns the institution of a training message, whose prefix is the institution ID.
+func trainingInstitution(message *model.OutboxMessage) string {
+	var request model.RequestAPITrainModel
+	if err := json.Unmarshal(message.Payload, &request); err != nil {
+		return ""
+	}
+	return request.Prefix
+}
+
+func (c *OutboxController) trainingPriority(message *model.OutboxMessage) int {
+	var request model.RequestAPITrainModel
+	if err := json.Unmarshal(message.Payload, &request); err != nil {
+		return 0
+	}
+	return c.cfg.Training.EffectivePriority(request.Priority, time.Since(message.CreatedAt))
+}
+
+// outboxBackoff doubles from one second per attempt up to outboxMaxBackoff. The shift is clamped, so a large or
+// negative attempt count cannot overflow or panic.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := time.Duration(1<<min(max(attempts, 0), 10)) * time.Second
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return backoff
+}
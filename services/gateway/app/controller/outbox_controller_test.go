@@ -0,0 +1,30 @@
+package controller
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: -1, want: time.Second},
+		{attempts: 0, want: time.Second},
+		{attempts: 1, want: 2 * time.Second},
+		{attempts: 3, want: 8 * time.Second},
+		{attempts: 8, want: 256 * time.Second},
+		{attempts: 9, want: outboxMaxBackoff},
+		{attempts: 10, want: outboxMaxBackoff},
+		{attempts: 64, want: outboxMaxBackoff},
+		{attempts: math.MaxInt, want: outboxMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
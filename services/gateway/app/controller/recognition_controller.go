@@ -0,0 +1,605 @@
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type InterfaceRecognitionController interface {
+	Identify(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionResult, error)
+	Verify(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionResult, error)
+	IdentifyGroup(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionGroupResult, error)
+	GetEvents(ctx context.Context, filter *model.FilterRecognitionEvent, pagination *model.Pagination) ([]*model.RecognitionEvent, *model.Pagination, error)
+	GetEvent(ctx context.Context, id string) (*model.RecognitionEvent, error)
+	GetHourlyStats(ctx context.Context, filter *model.FilterRecognitionEvent) ([]*model.RecognitionStats, error)
+	GetSummary(ctx context.Context, filter *model.FilterRecognitionEvent) (*model.RecognitionStats, error)
+}
+
+// recognitionStatsMaxRange bounds the time range of an hourly aggregation.
+const recognitionStatsMaxRange = 31 * 24 * time.Hour
+
+var recognitionDecisions = []string{model.RecognitionDecisionMatch, model.RecognitionDecisionNoMatch, model.RecognitionDecisionError}
+
+type RecognitionController struct {
+	recognitionClient  client.InterfaceRecognitionClient
+	eventClient        client.InterfaceRecognitionEventClient
+	modelClient        client.InterfaceModelClient
+	storageClient      client.InterfaceStorageClient
+	roleClient         client.InterfaceRoleClient
+	faceDetectorClient client.InterfaceFaceDetectorClient
+	policyController   InterfaceRecognitionPolicyController
+	reviewController   InterfaceRecognitionReviewController
+	cfg                *config.Config
+}
+
+// recognitionScope is what every probe of one request is scored with.
+type recognitionScope struct {
+	session *model.MetadataUser
+	active  *model.ModelTraining
+	policy  *model.RecognitionPolicy
+}
+
+func NewRecognitionController(recognitionClient client.InterfaceRecognitionClient, eventClient client.InterfaceRecognitionEventClient, modelClient client.InterfaceModelClient, storageClient client.InterfaceStorageClient, roleClient client.InterfaceRoleClient, faceDetectorClient client.InterfaceFaceDetectorClient, policyController InterfaceRecognitionPolicyController, reviewController InterfaceRecognitionReviewController, cfg *config.Config) *RecognitionController {
+	return &RecognitionController{
+		recognitionClient:  recognitionClient,
+		eventClient:        eventClient,
+		modelClient:        modelClient,
+		storageClient:      storageClient,
+		roleClient:         roleClient,
+		faceDetectorClient: faceDetectorClient,
+		policyController:   policyController,
+		reviewController:   reviewController,
+		cfg:                cfg,
+	}
+}
+
+// Identify finds the best matching user of the caller's institution for the probe image.
+func (c *RecognitionController) Identify(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: Identify")
+	defer span.Finish()
+
+	res, err := c.recognize(ctx, model.RecognitionKindIdentify, req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// Verify checks whether the probe image is the claimed user.
+func (c *RecognitionController) Verify(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: Verify")
+	defer span.Finish()
+
+	if req.Username == "" {
+		utils.LogEventError(span, errors.New("username is required"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("username is required to verify"))
+	}
+
+	res, err := c.recognize(ctx, model.RecognitionKindVerify, req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// recognize asks the processing service to score the probe with the institution's active model and records the
+// attempt, including failed ones, as a recognition event.
+func (c *RecognitionController) recognize(ctx context.Context, kind string, req *model.RequestRecognition) (*model.RecognitionResult, error) {
+	scope, err := c.prepareProbe(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	event := newRecognitionEvent(scope, kind, req.DeviceID)
+	if kind == model.RecognitionKindVerify {
+		event.ClaimedUser = &req.Username
+	}
+
+	return c.recognizeProbe(ctx, scope, event, req.Image)
+}
+
+// prepareProbe validates the submitted image, finds the model it is scored with and the institution's policy,
+// and refuses a model older than the policy allows.
+func (c *RecognitionController) prepareProbe(ctx context.Context, req *model.RequestRecognition) (*recognitionScope, error) {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A device authenticated by its key is recorded as itself, whatever device_id it sends. A user session is not a
+	// device, so a device_id it sends could only claim someone else's kiosk and is dropped.
+	req.DeviceID = session.DeviceID
+
+	if req.Image == nil {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("image is required"))
+	}
+
+	err = utils.ValidateImage(req.Image, c.cfg.Dataset.MaxImageBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	active, err := c.modelClient.GetActiveModel(ctx, session.InstitutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if active == nil {
+		return nil, model.ThrowError(http.StatusConflict, errors.New("institution has no active model"))
+	}
+
+	policy, err := c.policyController.ResolvePolicy(ctx, session.InstitutionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.MaxModelAgeDays != nil {
+		trainedAt := active.CreatedAt
+		if active.FinishedAt != nil {
+			trainedAt = *active.FinishedAt
+		}
+		if time.Since(trainedAt) > time.Duration(*policy.MaxModelAgeDays)*24*time.Hour {
+			return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("active model version %d is older than %d days, retrain the institution's model", active.Version, *policy.MaxModelAgeDays))
+		}
+	}
+
+	return &recognitionScope{session: session, active: active, policy: policy}, nil
+}
+
+func newRecognitionEvent(scope *recognitionScope, kind string, deviceID string) *model.RecognitionEvent {
+	event := &model.RecognitionEvent{
+		ID:            uuid.New().String(),
+		InstitutionID: scope.session.InstitutionID,
+		Kind:          kind,
+		Caller:        scope.session.Username,
+		ModelID:       &scope.active.ID,
+		ModelVersion:  &scope.active.Version,
+		CreatedAt:     time.Now(),
+	}
+	if deviceID != "" {
+		event.DeviceID = &deviceID
+	}
+
+	return event
+}
+
+// recognizeProbe scores one probe for event, verifying against event.ClaimedUser when it is set, and records
+// the outcome.
+func (c *RecognitionController) recognizeProbe(ctx context.Context, scope *recognitionScope, event *model.RecognitionEvent, image *model.File) (*model.RecognitionResult, error) {
+	request := &model.RecognitionRequest{
+		InstitutionID: event.InstitutionID,
+		ModelID:       scope.active.ID,
+		ModelVersion:  scope.active.Version,
+		ModelPath:     scope.active.ModelPath,
+		BucketName:    c.cfg.MinioProfile.Bucket,
+		TopK:          scope.policy.TopK,
+		Image:         base64.StdEncoding.EncodeToString(image.BytesObject),
+	}
+
+	messageType := model.RecognitionIdentifyMessage
+	if event.ClaimedUser != nil {
+		messageType = model.RecognitionVerifyMessage
+		request.Username = *event.ClaimedUser
+	}
+
+	started := time.Now()
+	reply, err := c.recognitionClient.Recognize(ctx, messageType, request)
+	event.LatencyMs = time.Since(started).Milliseconds()
+	if err != nil {
+		message := err.Error()
+		event.Decision = model.RecognitionDecisionError
+		event.Message = &message
+		if recordErr := c.recordEvent(ctx, event, image); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, err
+	}
+
+	res := decide(event, reply, scope.policy)
+
+	err = c.recordEvent(ctx, event, image)
+	if err != nil {
+		return nil, err
+	}
+
+	if reply.FaceDetected && event.Decision == model.RecognitionDecisionNoMatch {
+		var best *model.RecognitionCandidate
+		if len(res.Candidates) > 0 {
+			best = res.Candidates[0]
+		}
+		// EnqueueReview traces its own failures; a face that cannot be queued must not fail the recognition.
+		_ = c.reviewController.EnqueueReview(ctx, event, best, image)
+	}
+
+	return res, nil
+}
+
+// IdentifyGroup detects every face of a group photo and identifies them in parallel, at most
+// recognition.batch.concurrency at a time. A face that fails is reported as unknown with the error instead of
+// failing the photo. Once ctx is done no further face is started, and the faces already identified, each of which
+// has written its event, are returned as a truncated result.
+func (c *RecognitionController) IdentifyGroup(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionGroupResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: IdentifyGroup")
+	defer span.Finish()
+
+	if !c.cfg.Recognition.Batch.Enabled {
+		err := model.ThrowError(http.StatusServiceUnavailable, errors.New("group photo recognition is disabled"))
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	scope, err := c.prepareProbe(ctx, req)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	started := time.Now()
+
+	faces, err := c.faceDetectorClient.DetectFaces(ctx, req.Image)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	batch := c.cfg.Recognition.Batch
+	res := &model.RecognitionGroupResult{
+		BatchID:      uuid.New().String(),
+		FaceCount:    len(faces),
+		Faces:        make([]*model.RecognitionFace, 0, len(faces)),
+		Threshold:    scope.policy.MatchThreshold,
+		ModelID:      scope.active.ID,
+		ModelVersion: scope.active.Version,
+	}
+	if len(faces) > batch.FaceLimit() {
+		sort.SliceStable(faces, func(i, j int) bool { return faces[i].Confidence > faces[j].Confidence })
+		faces = faces[:batch.FaceLimit()]
+		sort.SliceStable(faces, func(i, j int) bool { return faces[i].Box.X < faces[j].Box.X })
+		res.Truncated = true
+	}
+
+	results := make([]*model.RecognitionFace, len(faces))
+	slots := make(chan struct{}, batch.Workers())
+	var wg sync.WaitGroup
+dispatch:
+	for i, face := range faces {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = c.identifyFace(ctx, scope, req.DeviceID, res.BatchID, face)
+		}()
+	}
+	wg.Wait()
+
+	for _, face := range results {
+		if face != nil {
+			res.Faces = append(res.Faces, face)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		utils.LogEventError(span, err)
+		res.Truncated = true
+	}
+	res.LatencyMs = time.Since(started).Milliseconds()
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *RecognitionController) identifyFace(ctx context.Context, scope *recognitionScope, deviceID, batchID string, face *model.DetectedFace) *model.RecognitionFace {
+	event := newRecognitionEvent(scope, model.RecognitionKindIdentify, deviceID)
+	event.BatchID = &batchID
+	event.FaceBox, _ = json.Marshal(face.Box)
+
+	res := &model.RecognitionFace{
+		Box:        face.Box,
+		Confidence: face.Confidence,
+		EventID:    event.ID,
+		Username:   model.RecognitionUnknown,
+	}
+
+	result, err := c.recognizeProbe(ctx, scope, event, face.Image)
+	if err != nil {
+		res.Decision = model.RecognitionDecisionError
+		res.Message = err.Error()
+		return res
+	}
+
+	res.Decision = result.Decision
+	res.Score = result.Score
+	if result.Username != nil {
+		res.Username = *result.Username
+	}
+	if event.Message != nil {
+		res.Message = *event.Message
+	}
+
+	return res
+}
+
+// decide applies the institution's policy to the reply and fills the decision into event. A match needs the
+// threshold and, when the policy sets a margin, that lead over the best other candidate.
+func decide(event *model.RecognitionEvent, reply *model.RecognitionReply, policy *model.RecognitionPolicy) *model.RecognitionResult {
+	candidates := reply.Candidates
+	if candidates == nil {
+		candidates = []*model.RecognitionCandidate{}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > policy.TopK {
+		candidates = candidates[:policy.TopK]
+	}
+
+	var best, runnerUp *model.RecognitionCandidate
+	for _, candidate := range candidates {
+		claimed := event.ClaimedUser != nil && candidate.Username == *event.ClaimedUser
+		switch {
+		case best == nil && (event.ClaimedUser == nil || claimed):
+			best = candidate
+		case runnerUp == nil && !claimed:
+			runnerUp = candidate
+		}
+	}
+
+	var margin *float64
+	if best != nil && runnerUp != nil {
+		lead := best.Score - runnerUp.Score
+		margin = &lead
+	}
+
+	event.Decision = model.RecognitionDecisionNoMatch
+	if best != nil {
+		score := best.Score
+		event.Score = &score
+		if reply.FaceDetected && score >= policy.MatchThreshold {
+			if policy.MinMargin <= 0 || margin == nil || *margin >= policy.MinMargin {
+				event.Decision = model.RecognitionDecisionMatch
+				event.MatchedUser = &best.Username
+			} else {
+				message := fmt.Sprintf("ambiguous match: lead of %.3f over %s is below the required %.3f", *margin, runnerUp.Username, policy.MinMargin)
+				event.Message = &message
+			}
+		}
+	}
+
+	if !reply.FaceDetected {
+		message := "no face detected"
+		if reply.Message != "" {
+			message = reply.Message
+		}
+		event.Message = &message
+	}
+
+	return &model.RecognitionResult{
+		EventID:      event.ID,
+		Kind:         event.Kind,
+		Decision:     event.Decision,
+		Username:     event.MatchedUser,
+		Score:        event.Score,
+		Threshold:    policy.MatchThreshold,
+		Margin:       margin,
+		MinMargin:    policy.MinMargin,
+		Candidates:   candidates,
+		ModelID:      *event.ModelID,
+		ModelVersion: *event.ModelVersion,
+		LatencyMs:    event.LatencyMs,
+		Message:      event.Message,
+	}
+}
+
+// recordEvent stores the event and, when configured, its probe image. A probe that cannot be stored is logged
+// and the event is kept without it.
+func (c *RecognitionController) recordEvent(ctx context.Context, event *model.RecognitionEvent, image *model.File) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RecordRecognitionEvent")
+	defer span.Finish()
+
+	if c.cfg.Recognition.StoreProbes && c.cfg.Recognition.ProbeBucket != "" {
+		path := fmt.Sprintf("%s/%s/%s", event.InstitutionID, event.CreatedAt.Format("2006/01/02"), event.ID)
+		_, err := c.storageClient.UploadFile(ctx, image, c.cfg.Recognition.ProbeBucket, path)
+		if err != nil {
+			utils.LogEventError(span, err)
+		} else {
+			probePath := path + "." + image.Extension
+			event.ProbePath = &probePath
+		}
+	}
+
+	err := c.eventClient.InsertEvent(ctx, event)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+func (c *RecognitionController) GetEvents(ctx context.Context, filter *model.FilterRecognitionEvent, pagination *model.Pagination) ([]*model.RecognitionEvent, *model.Pagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionEvents")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	err := c.prepareFilter(ctx, filter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	res, pagination, err := c.eventClient.GetEvents(ctx, filter, pagination)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	utils.LogEvent(span, "Pagination", pagination)
+
+	return res, pagination, nil
+}
+
+func (c *RecognitionController) GetEvent(ctx context.Context, id string) (*model.RecognitionEvent, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionEvent")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := c.eventClient.GetEventByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if res == nil {
+		utils.LogEventError(span, errors.New("recognition event not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("recognition event not found"))
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, res.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// GetHourlyStats aggregates events per hour, over the last 24 hours unless a range is given.
+func (c *RecognitionController) GetHourlyStats(ctx context.Context, filter *model.FilterRecognitionEvent) ([]*model.RecognitionStats, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionHourlyStats")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	err := c.prepareStatsFilter(ctx, filter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.eventClient.GetStats(ctx, filter, true)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// GetSummary aggregates events into one row, over the last 24 hours unless a range is given.
+func (c *RecognitionController) GetSummary(ctx context.Context, filter *model.FilterRecognitionEvent) (*model.RecognitionStats, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionSummary")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	err := c.prepareStatsFilter(ctx, filter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.eventClient.GetStats(ctx, filter, false)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	summary := &model.RecognitionStats{}
+	if len(res) > 0 {
+		summary = res[0]
+	}
+
+	utils.LogEvent(span, "Response", summary)
+
+	return summary, nil
+}
+
+// prepareFilter defaults the institution to the caller's and checks the caller may read it.
+func (c *RecognitionController) prepareFilter(ctx context.Context, filter *model.FilterRecognitionEvent) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	if filter.InstitutionID == "" {
+		filter.InstitutionID = session.InstitutionID
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, filter.InstitutionID)
+	if err != nil {
+		return err
+	}
+
+	if filter.Decision != "" && !slices.Contains(recognitionDecisions, filter.Decision) {
+		return model.ThrowError(http.StatusBadRequest, fmt.Errorf("decision must be one of %v", recognitionDecisions))
+	}
+
+	if filter.Kind != "" && filter.Kind != model.RecognitionKindIdentify && filter.Kind != model.RecognitionKindVerify {
+		return model.ThrowError(http.StatusBadRequest, errors.New("kind must be IDENTIFY or VERIFY"))
+	}
+
+	if filter.BatchID != "" {
+		if _, err := uuid.Parse(filter.BatchID); err != nil {
+			return model.ThrowError(http.StatusBadRequest, errors.New("batch_id must be a UUID"))
+		}
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return model.ThrowError(http.StatusBadRequest, errors.New("from must be before to"))
+	}
+
+	return nil
+}
+
+func (c *RecognitionController) prepareStatsFilter(ctx context.Context, filter *model.FilterRecognitionEvent) error {
+	if filter.To == nil {
+		to := time.Now()
+		filter.To = &to
+	}
+	if filter.From == nil {
+		from := filter.To.Add(-24 * time.Hour)
+		filter.From = &from
+	}
+
+	err := c.prepareFilter(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if filter.To.Sub(*filter.From) > recognitionStatsMaxRange {
+		return model.ThrowError(http.StatusBadRequest, errors.New("the statistics range cannot exceed 31 days"))
+	}
+
+	return nil
+}
@@ -0,0 +1,269 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type InterfaceRecognitionPolicyController interface {
+	GetPolicy(ctx context.Context, institutionID string) (*model.RecognitionPolicy, error)
+	SavePolicy(ctx context.Context, institutionID string, req *model.RequestRecognitionPolicy) (*model.RecognitionPolicy, error)
+	DeletePolicy(ctx context.Context, institutionID string) error
+	ResolvePolicy(ctx context.Context, institutionID string) (*model.RecognitionPolicy, error)
+}
+
+const (
+	recognitionPolicyCachePrefix = "recognition_policy:"
+	recognitionPolicyCacheTTL    = 6 * time.Hour
+	// recognitionPolicyMaxTopK matches the database constraint on top_k.
+	recognitionPolicyMaxTopK = 100
+)
+
+type RecognitionPolicyController struct {
+	redis             *redis.Client
+	policyClient      client.InterfaceRecognitionPolicyClient
+	institutionClient client.InterfaceInstitutionClient
+	roleClient        client.InterfaceRoleClient
+	cfg               *config.Config
+}
+
+func NewRecognitionPolicyController(redis *redis.Client, policyClient client.InterfaceRecognitionPolicyClient, institutionClient client.InterfaceInstitutionClient, roleClient client.InterfaceRoleClient, cfg *config.Config) *RecognitionPolicyController {
+	return &RecognitionPolicyController{
+		redis:             redis,
+		policyClient:      policyClient,
+		institutionClient: institutionClient,
+		roleClient:        roleClient,
+		cfg:               cfg,
+	}
+}
+
+func (c *RecognitionPolicyController) GetPolicy(ctx context.Context, institutionID string) (*model.RecognitionPolicy, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionPolicy")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.ResolvePolicy(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// ResolvePolicy returns the institution's policy, or the configured defaults when it has none. Lookups are
+// cached in Redis, including the absence of a policy, so recognition does not query the database every time.
+func (c *RecognitionPolicyController) ResolvePolicy(ctx context.Context, institutionID string) (*model.RecognitionPolicy, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: ResolveRecognitionPolicy")
+	defer span.Finish()
+
+	key := recognitionPolicyCachePrefix + institutionID
+
+	var stored *model.RecognitionPolicy
+	cached := false
+
+	cache := c.redis.Get(ctx, key).Val()
+	if cache != "" {
+		utils.LogEvent(span, "Redis", cache)
+
+		if err := json.Unmarshal([]byte(cache), &stored); err != nil {
+			utils.LogEventError(span, err)
+		} else {
+			cached = true
+		}
+	}
+
+	if !cached {
+		var err error
+		stored, err = c.policyClient.GetPolicyByInstitution(ctx, institutionID)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		resJSON, err := json.Marshal(stored)
+		if err != nil {
+			utils.LogEventError(span, err)
+		} else if err := c.redis.Set(ctx, key, resJSON, recognitionPolicyCacheTTL).Err(); err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	if stored != nil {
+		return stored, nil
+	}
+
+	return c.defaultPolicy(institutionID), nil
+}
+
+func (c *RecognitionPolicyController) defaultPolicy(institutionID string) *model.RecognitionPolicy {
+	defaults := c.cfg.Recognition
+
+	policy := &model.RecognitionPolicy{
+		InstitutionID:  institutionID,
+		MatchThreshold: defaults.Threshold(),
+		TopK:           defaults.CandidateCount(),
+		MinMargin:      defaults.Margin(),
+		IsDefault:      true,
+	}
+	if defaults.MaxModelAgeDays > 0 {
+		maxAge := defaults.MaxModelAgeDays
+		policy.MaxModelAgeDays = &maxAge
+	}
+
+	return policy
+}
+
+func (c *RecognitionPolicyController) SavePolicy(ctx context.Context, institutionID string, req *model.RequestRecognitionPolicy) (*model.RecognitionPolicy, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: SaveRecognitionPolicy")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	institution, err := c.institutionClient.GetInstitutionByID(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if institution == nil || institution.ID == "" {
+		utils.LogEventError(span, errors.New("institution not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("institution not found"))
+	}
+
+	current, err := c.policyClient.GetPolicyByInstitution(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if current == nil {
+		current = c.defaultPolicy(institutionID)
+	}
+
+	if req.MatchThreshold != nil {
+		if *req.MatchThreshold <= 0 || *req.MatchThreshold > 1 {
+			utils.LogEventError(span, errors.New("invalid match threshold"))
+			return nil, model.ThrowError(http.StatusBadRequest, errors.New("match_threshold must be greater than 0 and at most 1"))
+		}
+		current.MatchThreshold = *req.MatchThreshold
+	}
+
+	if req.TopK != nil {
+		if *req.TopK < 1 || *req.TopK > recognitionPolicyMaxTopK {
+			utils.LogEventError(span, errors.New("invalid top k"))
+			return nil, model.ThrowError(http.StatusBadRequest, errors.New("top_k must be between 1 and 100"))
+		}
+		current.TopK = *req.TopK
+	}
+
+	if req.MinMargin != nil {
+		if *req.MinMargin < 0 || *req.MinMargin >= 1 {
+			utils.LogEventError(span, errors.New("invalid margin"))
+			return nil, model.ThrowError(http.StatusBadRequest, errors.New("min_margin must be at least 0 and less than 1"))
+		}
+		current.MinMargin = *req.MinMargin
+	}
+
+	if req.MaxModelAgeDays != nil {
+		switch {
+		case *req.MaxModelAgeDays < 0:
+			utils.LogEventError(span, errors.New("invalid model age"))
+			return nil, model.ThrowError(http.StatusBadRequest, errors.New("max_model_age_days must not be negative"))
+		case *req.MaxModelAgeDays == 0:
+			current.MaxModelAgeDays = nil
+		default:
+			current.MaxModelAgeDays = req.MaxModelAgeDays
+		}
+	}
+
+	now := time.Now()
+	policy := &model.RecognitionPolicy{
+		ID:              uuid.New().String(),
+		InstitutionID:   institutionID,
+		MatchThreshold:  current.MatchThreshold,
+		TopK:            current.TopK,
+		MinMargin:       current.MinMargin,
+		MaxModelAgeDays: current.MaxModelAgeDays,
+		CreatedAt:       &now,
+		CreatedBy:       session.Username,
+		UpdatedAt:       &now,
+		UpdatedBy:       session.Username,
+	}
+
+	err = c.policyClient.UpsertPolicy(ctx, policy)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if err := c.redis.Del(ctx, recognitionPolicyCachePrefix+institutionID).Err(); err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return c.ResolvePolicy(ctx, institutionID)
+}
+
+// DeletePolicy removes the institution's policy so the configured defaults apply again.
+func (c *RecognitionPolicyController) DeletePolicy(ctx context.Context, institutionID string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteRecognitionPolicy")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	deleted, err := c.policyClient.DeletePolicy(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if deleted == 0 {
+		utils.LogEventError(span, errors.New("recognition policy not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("recognition policy not found"))
+	}
+
+	if err := c.redis.Del(ctx, recognitionPolicyCachePrefix+institutionID).Err(); err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
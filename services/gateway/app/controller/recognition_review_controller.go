@@ -0,0 +1,452 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InterfaceRecognitionReviewController interface {
+	EnqueueReview(ctx context.Context, event *model.RecognitionEvent, best *model.RecognitionCandidate, image *model.File) error
+	GetReviews(ctx context.Context, filter *model.FilterRecognitionReview, pagination *model.Pagination) ([]*model.RecognitionReview, *model.Pagination, error)
+	GetReview(ctx context.Context, id string) (*model.RecognitionReview, error)
+	GetReviewImage(ctx context.Context, id string) (*model.File, error)
+	LabelReview(ctx context.Context, id string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error)
+	DiscardReview(ctx context.Context, id string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error)
+	FlagImpostor(ctx context.Context, id string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error)
+	PurgeExpiredReviews(ctx context.Context) (int, error)
+}
+
+const recognitionReviewEntity = "recognition_review"
+
+var recognitionReviewStatuses = []string{model.RecognitionReviewPending, model.RecognitionReviewLabeled, model.RecognitionReviewDiscarded, model.RecognitionReviewImpostor}
+
+type RecognitionReviewController struct {
+	reviewClient      client.InterfaceRecognitionReviewClient
+	storageClient     client.InterfaceStorageClient
+	auditClient       client.InterfaceAuditClient
+	roleClient        client.InterfaceRoleClient
+	datasetController InterfaceDatasetController
+	db                *gorm.DB
+	cfg               *config.Config
+}
+
+func NewRecognitionReviewController(reviewClient client.InterfaceRecognitionReviewClient, storageClient client.InterfaceStorageClient, auditClient client.InterfaceAuditClient, roleClient client.InterfaceRoleClient, datasetController InterfaceDatasetController, db *gorm.DB, cfg *config.Config) *RecognitionReviewController {
+	return &RecognitionReviewController{
+		reviewClient:      reviewClient,
+		storageClient:     storageClient,
+		auditClient:       auditClient,
+		roleClient:        roleClient,
+		datasetController: datasetController,
+		db:                db,
+		cfg:               cfg,
+	}
+}
+
+// EnqueueReview stores an unmatched probe for review. Nothing is queued when reviews are disabled or the
+// institution already has recognition.review.maxPending faces waiting.
+func (c *RecognitionReviewController) EnqueueReview(ctx context.Context, event *model.RecognitionEvent, best *model.RecognitionCandidate, image *model.File) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: EnqueueRecognitionReview")
+	defer span.Finish()
+
+	bucket := c.cfg.Recognition.ProbeBucket
+	if !c.cfg.Recognition.Review.Enabled || bucket == "" {
+		return nil
+	}
+
+	pending, err := c.reviewClient.CountPending(ctx, event.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if pending >= c.cfg.Recognition.Review.PendingLimit() {
+		utils.LogEvent(span, "Skipped", "review queue is full")
+		return nil
+	}
+
+	now := time.Now()
+	review := &model.RecognitionReview{
+		ID:            uuid.New().String(),
+		InstitutionID: event.InstitutionID,
+		EventID:       &event.ID,
+		Kind:          event.Kind,
+		DeviceID:      event.DeviceID,
+		ClaimedUser:   event.ClaimedUser,
+		Status:        model.RecognitionReviewPending,
+		ExpiresAt:     now.Add(c.cfg.Recognition.Review.RetentionDuration()),
+		CreatedAt:     now,
+	}
+	if best != nil {
+		score := best.Score
+		review.BestCandidate = &best.Username
+		review.BestScore = &score
+	}
+
+	path := fmt.Sprintf("review/%s/%s", review.InstitutionID, review.ID)
+	_, err = c.storageClient.UploadFile(ctx, image, bucket, path)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+	review.ProbePath = path + "." + image.Extension
+
+	err = c.reviewClient.InsertReview(ctx, review)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if deleteErr := c.storageClient.DeleteObject(ctx, bucket, review.ProbePath); deleteErr != nil {
+			utils.LogEventError(span, deleteErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (c *RecognitionReviewController) GetReviews(ctx context.Context, filter *model.FilterRecognitionReview, pagination *model.Pagination) ([]*model.RecognitionReview, *model.Pagination, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionReviews")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", filter)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	if filter.InstitutionID == "" {
+		filter.InstitutionID = session.InstitutionID
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, filter.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	if filter.Status != "" && !slices.Contains(recognitionReviewStatuses, filter.Status) {
+		utils.LogEventError(span, errors.New("invalid review status"))
+		return nil, nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("status must be one of %v", recognitionReviewStatuses))
+	}
+
+	res, pagination, err := c.reviewClient.GetReviews(ctx, filter, pagination)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, nil, err
+	}
+
+	return res, pagination, nil
+}
+
+func (c *RecognitionReviewController) GetReview(ctx context.Context, id string) (*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionReview")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := c.reviewClient.GetReviewByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if res == nil {
+		utils.LogEventError(span, errors.New("recognition review not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("recognition review not found"))
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, res.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// GetReviewImage returns the face of a review. Labeled and discarded reviews no longer have one.
+func (c *RecognitionReviewController) GetReviewImage(ctx context.Context, id string) (*model.File, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetRecognitionReviewImage")
+	defer span.Finish()
+
+	review, err := c.GetReview(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if review.Status == model.RecognitionReviewLabeled || review.Status == model.RecognitionReviewDiscarded {
+		utils.LogEventError(span, errors.New("review image was removed"))
+		return nil, model.ThrowError(http.StatusGone, fmt.Errorf("the image of a %s review is no longer kept", review.Status))
+	}
+
+	res, err := c.storageClient.GetObject(ctx, c.cfg.Recognition.ProbeBucket, review.ProbePath)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// LabelReview adds the face to the dataset of an existing user of the review's institution.
+func (c *RecognitionReviewController) LabelReview(ctx context.Context, id string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: LabelRecognitionReview")
+	defer span.Finish()
+
+	if req.Username == "" {
+		utils.LogEventError(span, errors.New("username is required"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("username is required to label a review"))
+	}
+
+	return c.decideReview(ctx, id, model.RecognitionReviewLabeled, req)
+}
+
+func (c *RecognitionReviewController) DiscardReview(ctx context.Context, id string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DiscardRecognitionReview")
+	defer span.Finish()
+
+	req.Username = ""
+
+	return c.decideReview(ctx, id, model.RecognitionReviewDiscarded, req)
+}
+
+// FlagImpostor marks the face as an impostor attempt. Its image is kept as evidence until the review expires.
+func (c *RecognitionReviewController) FlagImpostor(ctx context.Context, id string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: FlagRecognitionReviewImpostor")
+	defer span.Finish()
+
+	req.Username = ""
+
+	return c.decideReview(ctx, id, model.RecognitionReviewImpostor, req)
+}
+
+// decideReview records the decision and its audit entry in one transaction. A label is added to the dataset
+// before the transaction, so no storage call holds the review row; if the decision is then not recorded, because
+// it failed or someone else decided the face first, the added image is removed again.
+func (c *RecognitionReviewController) decideReview(ctx context.Context, id string, status string, req *model.RequestRecognitionReviewDecision) (*model.RecognitionReview, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DecideRecognitionReview")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"id": id, "status": status, "request": req})
+
+	review, err := c.GetReview(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, review.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if review.Status != model.RecognitionReviewPending {
+		utils.LogEventError(span, errors.New("review already decided"))
+		return nil, model.ThrowError(http.StatusConflict, fmt.Errorf("review is already %s", review.Status))
+	}
+
+	var labeledUser, note *string
+	if req.Username != "" {
+		labeledUser = &req.Username
+	}
+	if req.Note != "" {
+		note = &req.Note
+	}
+
+	bucket := c.cfg.Recognition.ProbeBucket
+
+	var undoLabel func()
+	if status == model.RecognitionReviewLabeled {
+		undoLabel, err = c.labelDataset(ctx, review, req.Username)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+	}
+
+	err = c.recordDecision(ctx, review, status, labeledUser, note)
+	if err != nil {
+		utils.LogEventError(span, err)
+		if undoLabel != nil {
+			undoLabel()
+		}
+		return nil, err
+	}
+
+	if status != model.RecognitionReviewImpostor {
+		if err := c.storageClient.DeleteObject(ctx, bucket, review.ProbePath); err != nil {
+			utils.LogEventError(span, err)
+		}
+	}
+
+	return c.GetReview(ctx, id)
+}
+
+// labelDataset adds the review's face to the user's dataset and returns a function removing it again.
+func (c *RecognitionReviewController) labelDataset(ctx context.Context, review *model.RecognitionReview, username string) (func(), error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: LabelRecognitionReviewDataset")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	image, err := c.storageClient.GetObject(ctx, c.cfg.Recognition.ProbeBucket, review.ProbePath)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+	image.FileName = fmt.Sprintf("review_%s_%d.%s", review.ID[:8], time.Now().UnixNano(), image.Extension)
+
+	// The dataset is stored under the review's institution, which differs from the caller's for system admins.
+	uploadCtx := utils.NewSystemContext(ctx, session.Username, review.InstitutionID)
+	dataset := &model.Dataset{
+		Username: username,
+		File:     []*model.File{image},
+	}
+	err = c.datasetController.UploadUserDataset(uploadCtx, dataset)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s/%s", dataset.Bucket, image.FileName)
+	undo := func() {
+		// Also when the caller has gone away, since its request is what left the image behind.
+		if err := c.storageClient.DeleteObject(context.WithoutCancel(ctx), "face-dataset", key); err != nil {
+			utils.LogEvent(span, "Orphaned label", key)
+			utils.LogEventError(span, err)
+		}
+	}
+
+	return undo, nil
+}
+
+// recordDecision decides the pending review and writes its audit entry in one transaction.
+func (c *RecognitionReviewController) recordDecision(ctx context.Context, review *model.RecognitionReview, status string, labeledUser *string, note *string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RecordRecognitionReviewDecision")
+	defer span.Finish()
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	tx := c.db.Begin()
+
+	decided, err := c.reviewClient.DecideReview(ctx, tx, review.ID, status, labeledUser, note, session.Username)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	if decided == 0 {
+		utils.LogEventError(span, errors.New("review already decided"))
+		tx.Rollback()
+		return model.ThrowError(http.StatusConflict, errors.New("review was decided by someone else"))
+	}
+
+	metadata, err := json.Marshal(map[string]any{
+		"event_id":       review.EventID,
+		"best_candidate": review.BestCandidate,
+		"best_score":     review.BestScore,
+		"labeled_user":   labeledUser,
+		"note":           note,
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	var actor *string
+	if session.UserID != "" {
+		actor = &session.UserID
+	}
+
+	err = c.auditClient.InsertAudit(ctx, tx, &model.AuditLog{
+		ID:            uuid.New().String(),
+		ActorUserID:   actor,
+		InstitutionID: &review.InstitutionID,
+		Action:        fmt.Sprintf("%s.%s", recognitionReviewEntity, strings.ToLower(status)),
+		EntityType:    recognitionReviewEntity,
+		EntityID:      review.ID,
+		IPAddress:     session.IPAddress,
+		UserAgent:     session.UserAgent,
+		Metadata:      string(metadata),
+		CreatedAt:     time.Now(),
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeExpiredReviews deletes reviews past their retention, whatever their status, together with their images.
+// Audit entries of decided reviews are kept.
+func (c *RecognitionReviewController) PurgeExpiredReviews(ctx context.Context) (int, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: PurgeExpiredRecognitionReviews")
+	defer span.Finish()
+
+	batchSize := c.cfg.Job.RecognitionReview.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	purged := 0
+	for {
+		reviews, err := c.reviewClient.DeleteExpiredReviews(ctx, time.Now(), batchSize)
+		if err != nil {
+			utils.LogEventError(span, err)
+			return purged, err
+		}
+
+		for _, review := range reviews {
+			if review.Status == model.RecognitionReviewLabeled || review.Status == model.RecognitionReviewDiscarded {
+				continue
+			}
+			if err := c.storageClient.DeleteObject(ctx, c.cfg.Recognition.ProbeBucket, review.ProbePath); err != nil {
+				utils.LogEventError(span, err)
+			}
+		}
+
+		purged += len(reviews)
+		if len(reviews) < batchSize {
+			break
+		}
+	}
+
+	utils.LogEvent(span, "Response", purged)
+
+	return purged, nil
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+)
+
+type InterfaceTrainingEventController interface {
+	SubscribeTraining(ctx context.Context, id string) (*model.TrainingEventStream, error)
+	SubscribeInstitution(ctx context.Context, institutionID string) (*model.TrainingEventStream, error)
+}
+
+type TrainingEventController struct {
+	eventClient   client.InterfaceTrainingEventClient
+	datasetClient client.InterfaceDatasetClient
+	roleClient    client.InterfaceRoleClient
+}
+
+func NewTrainingEventController(eventClient client.InterfaceTrainingEventClient, datasetClient client.InterfaceDatasetClient, roleClient client.InterfaceRoleClient) *TrainingEventController {
+	return &TrainingEventController{
+		eventClient:   eventClient,
+		datasetClient: datasetClient,
+		roleClient:    roleClient,
+	}
+}
+
+// SubscribeTraining follows a single training. The subscription lives until ctx is done.
+func (c *TrainingEventController) SubscribeTraining(ctx context.Context, id string) (*model.TrainingEventStream, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: SubscribeTraining")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", id)
+
+	training, err := c.datasetClient.GetTrainingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitution(ctx, c.roleClient, training.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	events, err := c.eventClient.Subscribe(ctx, training.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	// Reload after subscribing so a change between the first read and the subscription is not lost.
+	training, err = c.datasetClient.GetTrainingByID(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return &model.TrainingEventStream{
+		TrainingID: id,
+		Snapshot:   []*model.TrainingEvent{model.NewTrainingEvent(training)},
+		Events:     events,
+	}, nil
+}
+
+// SubscribeInstitution follows every training of an institution, starting with the unfinished ones.
+func (c *TrainingEventController) SubscribeInstitution(ctx context.Context, institutionID string) (*model.TrainingEventStream, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: SubscribeInstitutionTrainings")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	events, err := c.eventClient.Subscribe(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	trainings, err := c.datasetClient.GetUnfinishedTrainings(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	snapshot := make([]*model.TrainingEvent, 0, len(trainings))
+	for _, training := range trainings {
+		snapshot = append(snapshot, model.NewTrainingEvent(training))
+	}
+
+	return &model.TrainingEventStream{
+		Snapshot: snapshot,
+		Events:   events,
+	}, nil
+}
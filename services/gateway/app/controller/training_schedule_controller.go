@@ -0,0 +1,373 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+type InterfaceTrainingScheduleController interface {
+	GetSchedules(ctx context.Context) ([]*model.TrainingSchedule, error)
+	GetSchedule(ctx context.Context, institutionID string) (*model.TrainingSchedule, error)
+	SaveSchedule(ctx context.Context, institutionID string, req *model.RequestTrainingSchedule) (*model.TrainingSchedule, error)
+	DeleteSchedule(ctx context.Context, institutionID string) error
+	EvaluateSchedules(ctx context.Context) ([]*model.TrainingScheduleRun, error)
+}
+
+// trainingScheduleUser is recorded as the creator of trainings started by a schedule.
+const trainingScheduleUser = "system:scheduler"
+
+type TrainingScheduleController struct {
+	scheduleClient    client.InterfaceTrainingScheduleClient
+	datasetClient     client.InterfaceDatasetClient
+	storageClient     client.InterfaceStorageClient
+	institutionClient client.InterfaceInstitutionClient
+	roleClient        client.InterfaceRoleClient
+	datasetController InterfaceDatasetController
+	cfg               *config.Config
+}
+
+func NewTrainingScheduleController(scheduleClient client.InterfaceTrainingScheduleClient, datasetClient client.InterfaceDatasetClient, storageClient client.InterfaceStorageClient, institutionClient client.InterfaceInstitutionClient, roleClient client.InterfaceRoleClient, datasetController InterfaceDatasetController, cfg *config.Config) *TrainingScheduleController {
+	return &TrainingScheduleController{
+		scheduleClient:    scheduleClient,
+		datasetClient:     datasetClient,
+		storageClient:     storageClient,
+		institutionClient: institutionClient,
+		roleClient:        roleClient,
+		datasetController: datasetController,
+		cfg:               cfg,
+	}
+}
+
+// GetSchedules lists the schedules of every institution, so it requires a system role.
+func (c *TrainingScheduleController) GetSchedules(ctx context.Context) ([]*model.TrainingSchedule, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetTrainingSchedules")
+	defer span.Finish()
+
+	err := authorizeSystem(ctx, c.roleClient)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.scheduleClient.GetSchedules(ctx, false)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	for _, schedule := range res {
+		setNextRunAt(schedule)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *TrainingScheduleController) GetSchedule(ctx context.Context, institutionID string) (*model.TrainingSchedule, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: GetTrainingSchedule")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitution(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, err := c.scheduleClient.GetScheduleByInstitution(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if res == nil {
+		utils.LogEventError(span, errors.New("training schedule not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("training schedule not found"))
+	}
+
+	setNextRunAt(res)
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+func (c *TrainingScheduleController) SaveSchedule(ctx context.Context, institutionID string, req *model.RequestTrainingSchedule) (*model.TrainingSchedule, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: SaveTrainingSchedule")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", req)
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	err = authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if req.CronExpr != nil && *req.CronExpr == "" {
+		req.CronExpr = nil
+	}
+
+	if req.CronExpr == nil && req.MinNewImages == nil && req.MinNewUsers == nil {
+		utils.LogEventError(span, errors.New("schedule has no trigger"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("at least one of cron_expr, min_new_images or min_new_users is required"))
+	}
+
+	if req.CronExpr != nil {
+		if _, err := cron.ParseStandard(*req.CronExpr); err != nil {
+			utils.LogEventError(span, err)
+			return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("invalid cron_expr: %w", err))
+		}
+	}
+
+	if (req.MinNewImages != nil && *req.MinNewImages <= 0) || (req.MinNewUsers != nil && *req.MinNewUsers <= 0) {
+		utils.LogEventError(span, errors.New("threshold must be positive"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("min_new_images and min_new_users must be greater than 0"))
+	}
+
+	institution, err := c.institutionClient.GetInstitutionByID(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	if institution == nil || institution.ID == "" {
+		utils.LogEventError(span, errors.New("institution not found"))
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("institution not found"))
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	now := time.Now()
+	schedule := &model.TrainingSchedule{
+		ID:            uuid.New().String(),
+		InstitutionID: institutionID,
+		CronExpr:      req.CronExpr,
+		MinNewImages:  req.MinNewImages,
+		MinNewUsers:   req.MinNewUsers,
+		IsActive:      isActive,
+		CreatedAt:     now,
+		CreatedBy:     session.Username,
+		UpdatedAt:     now,
+		UpdatedBy:     session.Username,
+	}
+
+	err = c.scheduleClient.UpsertSchedule(ctx, schedule)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	return c.GetSchedule(ctx, institutionID)
+}
+
+func (c *TrainingScheduleController) DeleteSchedule(ctx context.Context, institutionID string) error {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: DeleteTrainingSchedule")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := authorizeInstitutionAdmin(ctx, c.roleClient, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	deleted, err := c.scheduleClient.DeleteSchedule(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return err
+	}
+
+	if deleted == 0 {
+		utils.LogEventError(span, errors.New("training schedule not found"))
+		return model.ThrowError(http.StatusNotFound, errors.New("training schedule not found"))
+	}
+
+	return nil
+}
+
+// EvaluateSchedules starts a training for every active schedule whose cron time has passed or whose thresholds
+// are met. A failed trigger is recorded on the schedule and does not stop the remaining institutions.
+func (c *TrainingScheduleController) EvaluateSchedules(ctx context.Context) ([]*model.TrainingScheduleRun, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: EvaluateTrainingSchedules")
+	defer span.Finish()
+
+	schedules, err := c.scheduleClient.GetSchedules(ctx, true)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	var runs []*model.TrainingScheduleRun
+	for _, schedule := range schedules {
+		run, err := c.evaluateSchedule(ctx, schedule)
+		if err != nil {
+			utils.LogEventError(span, err)
+			continue
+		}
+		if run != nil {
+			runs = append(runs, run)
+		}
+	}
+
+	utils.LogEvent(span, "Response", runs)
+
+	return runs, nil
+}
+
+func (c *TrainingScheduleController) evaluateSchedule(ctx context.Context, schedule *model.TrainingSchedule) (*model.TrainingScheduleRun, error) {
+	now := time.Now()
+
+	trigger := ""
+	if isCronDue(schedule, now) {
+		trigger = model.TrainingTriggerCron
+	} else if schedule.MinNewImages != nil || schedule.MinNewUsers != nil {
+		met, err := c.isThresholdMet(ctx, schedule)
+		if err != nil {
+			return nil, err
+		}
+		if met {
+			trigger = model.TrainingTriggerThreshold
+		}
+	}
+
+	if trigger == "" {
+		return nil, nil
+	}
+
+	run := &model.TrainingScheduleRun{
+		InstitutionID: schedule.InstitutionID,
+		Trigger:       trigger,
+	}
+
+	sysCtx := utils.NewSystemContext(ctx, trainingScheduleUser, schedule.InstitutionID)
+	res, err := c.datasetController.ScheduleTraining(sysCtx, schedule.InstitutionID)
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.TrainingID = res.ID
+		run.Coalesced = res.Coalesced
+	}
+
+	// A cron slot is consumed even when the trigger fails, so a rejected run waits for the next slot instead of
+	// retrying every tick. Thresholds are re-checked on the next tick. A coalesced run does not reset the
+	// threshold baseline because the running job may not include the new images.
+	var lastRunAt, lastTriggeredAt *time.Time
+	if trigger == model.TrainingTriggerCron {
+		lastRunAt = &now
+	}
+	if err == nil && !run.Coalesced {
+		lastTriggeredAt = &now
+	}
+
+	if markErr := c.scheduleClient.MarkScheduleRun(ctx, schedule.ID, run, lastRunAt, lastTriggeredAt); markErr != nil {
+		return nil, markErr
+	}
+
+	return run, nil
+}
+
+// isThresholdMet counts images and users added since the later of the last successful training and the last
+// training this schedule triggered.
+func (c *TrainingScheduleController) isThresholdMet(ctx context.Context, schedule *model.TrainingSchedule) (bool, error) {
+	var since time.Time
+
+	lastSucceeded, err := c.datasetClient.GetLastSucceededTraining(ctx, schedule.InstitutionID)
+	if err != nil {
+		return false, err
+	}
+	if lastSucceeded != nil {
+		since = lastSucceeded.CreatedAt
+	}
+	if schedule.LastTriggeredAt != nil && schedule.LastTriggeredAt.After(since) {
+		since = *schedule.LastTriggeredAt
+	}
+
+	if schedule.MinNewUsers != nil {
+		users, err := c.datasetClient.CountNewDatasetUsers(ctx, schedule.InstitutionID, since)
+		if err != nil {
+			return false, err
+		}
+		if users >= int64(*schedule.MinNewUsers) {
+			return true, nil
+		}
+	}
+
+	if schedule.MinNewImages != nil {
+		objects, err := c.storageClient.ListDatasetObjects(ctx, c.cfg.MinioProfile.Bucket, schedule.InstitutionID+"/")
+		if err != nil {
+			return false, err
+		}
+
+		images := 0
+		for _, object := range objects {
+			if object.LastModified.After(since) {
+				images++
+			}
+		}
+		if images >= *schedule.MinNewImages {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// scheduleLocation is the zone cron expressions are read in. Stored times stay plain time.Now() values.
+func scheduleLocation() *time.Location {
+	return utils.LocalTime().Location()
+}
+
+func isCronDue(schedule *model.TrainingSchedule, now time.Time) bool {
+	next := nextCronRun(schedule, scheduleLocation())
+	return next != nil && !next.After(now)
+}
+
+// nextCronRun returns the first cron slot after the schedule last ran, or after it was created if it never ran.
+func nextCronRun(schedule *model.TrainingSchedule, loc *time.Location) *time.Time {
+	if schedule.CronExpr == nil {
+		return nil
+	}
+
+	sched, err := cron.ParseStandard(*schedule.CronExpr)
+	if err != nil {
+		return nil
+	}
+
+	base := schedule.CreatedAt
+	if schedule.LastRunAt != nil {
+		base = *schedule.LastRunAt
+	}
+
+	next := sched.Next(base.In(loc))
+	return &next
+}
+
+func setNextRunAt(schedule *model.TrainingSchedule) {
+	if !schedule.IsActive {
+		return
+	}
+	schedule.NextRunAt = nextCronRun(schedule, scheduleLocation())
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"face-recognition-svc/gateway/app/client"
 	"face-recognition-svc/gateway/app/config"
@@ -9,9 +10,12 @@ import (
 	"face-recognition-svc/gateway/app/utils"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type InterfaceUserController interface {
@@ -20,6 +24,8 @@ type InterfaceUserController interface {
 	UpdateUser(ctx context.Context, request *model.User) error
 	DeleteUser(ctx context.Context, username string) error
 	Login(ctx context.Context, request *model.RequestLogin) (*model.ResponseLogin, error)
+	FaceLogin(ctx context.Context, request *model.RequestFaceLogin) (*model.ResponseLogin, error)
+	RefreshToken(ctx context.Context, request *model.RequestRefreshToken) (*model.ResponseLogin, error)
 	GetAllUser(ctx context.Context, pagination *model.Pagination, filter *model.Filter) ([]*model.User, *model.Pagination, error)
 	GetInstitutionList(ctx context.Context) ([]string, error)
 
@@ -27,23 +33,47 @@ type InterfaceUserController interface {
 	UploadCoverPhoto(ctx context.Context, file *model.File) error
 }
 
+// faceLoginAttemptPrefix keys the face login attempt counters, per institution and client address or username,
+// and per institution for attempts without a username.
+const faceLoginAttemptPrefix = "face_login:attempts:"
+
+// countAttemptScript counts an attempt and starts its window in one step, so a counter can never be left without
+// an expiry.
+var countAttemptScript = redis.NewScript(`
+local attempts = redis.call("INCR", KEYS[1])
+if attempts == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return attempts
+`)
+
 type UserController struct {
-	userClient    client.InterfaceUserClient
-	roleClient    client.InterfaceRoleClient
-	paramClient   client.InterfaceParamClient
-	storageClient client.InterfaceStorageClient
-	config        *config.Config
-	redis         *redis.Client
+	userClient            client.InterfaceUserClient
+	roleClient            client.InterfaceRoleClient
+	paramClient           client.InterfaceParamClient
+	storageClient         client.InterfaceStorageClient
+	featureClient         client.InterfaceFeatureClient
+	auditClient           client.InterfaceAuditClient
+	refreshTokenClient    client.InterfaceRefreshTokenClient
+	recognitionController InterfaceRecognitionController
+	db                    *gorm.DB
+	config                *config.Config
+	redis                 *redis.Client
 }
 
-func NewUserController(userClient client.InterfaceUserClient, roleClient client.InterfaceRoleClient, paramClient client.InterfaceParamClient, storageClient client.InterfaceStorageClient, config *config.Config, redis *redis.Client) *UserController {
+func NewUserController(userClient client.InterfaceUserClient, roleClient client.InterfaceRoleClient, paramClient client.InterfaceParamClient, storageClient client.InterfaceStorageClient, featureClient client.InterfaceFeatureClient, auditClient client.InterfaceAuditClient, refreshTokenClient client.InterfaceRefreshTokenClient, recognitionController InterfaceRecognitionController, db *gorm.DB, config *config.Config, redis *redis.Client) *UserController {
 	return &UserController{
-		userClient:    userClient,
-		roleClient:    roleClient,
-		paramClient:   paramClient,
-		storageClient: storageClient,
-		config:        config,
-		redis:         redis,
+		userClient:            userClient,
+		roleClient:            roleClient,
+		paramClient:           paramClient,
+		storageClient:         storageClient,
+		featureClient:         featureClient,
+		auditClient:           auditClient,
+		refreshTokenClient:    refreshTokenClient,
+		recognitionController: recognitionController,
+		db:                    db,
+		config:                config,
+		redis:                 redis,
 	}
 }
 
@@ -144,7 +174,7 @@ func (c *UserController) Login(ctx context.Context, request *model.RequestLogin)
 	span, ctx := utils.SpanFromContext(ctx, "Controller: Login")
 	defer span.Finish()
 
-	utils.LogEvent(span, "Request", request)
+	utils.LogEvent(span, "Request", map[string]any{"username": request.Username, "institution_id": request.InstitutionID})
 
 	if request.InstitutionID == "" {
 		user, err := c.userClient.GetUserByUsername(ctx, request.Username)
@@ -170,7 +200,7 @@ func (c *UserController) Login(ctx context.Context, request *model.RequestLogin)
 			Token:        "",
 			Institutions: institutions,
 		}
-		utils.LogEvent(span, "Response", response)
+		utils.LogEvent(span, "Response", map[string]any{"user_id": response.UserID, "institutions": len(institutions)})
 		return response, nil
 	}
 
@@ -185,13 +215,33 @@ func (c *UserController) Login(ctx context.Context, request *model.RequestLogin)
 		return nil, model.ThrowError(http.StatusBadRequest, errors.New("invalid username or password "))
 	}
 
+	response, token, err := c.issueLogin(ctx, nil, user, "")
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	// The response carries the access and refresh tokens, which must not reach the trace.
+	utils.LogEvent(span, "Response", map[string]any{"user_id": response.UserID, "family_id": token.FamilyID})
+
+	return response, nil
+}
+
+// issueLogin returns the access token, refresh token and menus of a user who proved their identity for an
+// institution. The refresh token is stored inside tx when given and continues familyID, or starts a new family of
+// tokens when it is empty.
+func (c *UserController) issueLogin(ctx context.Context, tx *gorm.DB, user *model.User, familyID string) (*model.ResponseLogin, *model.RefreshToken, error) {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	var menus []*model.MenuRoleMapping
 	uniqueMenu := map[string]bool{}
 	for _, roleID := range user.RoleIDs {
 		roleMenus, err := c.roleClient.GetMenuRoleMapping(ctx, roleID)
 		if err != nil {
-			utils.LogEventError(span, err)
-			return nil, err
+			return nil, nil, err
 		}
 		for _, menu := range roleMenus {
 			if !uniqueMenu[menu.MenuID] {
@@ -201,29 +251,311 @@ func (c *UserController) Login(ctx context.Context, request *model.RequestLogin)
 		}
 	}
 
-	accessToken, _, err := c.userClient.CreateAccessToken(ctx, user, false)
+	accessToken, expiresAt, err := c.userClient.CreateAccessToken(ctx, user, false)
 	if err != nil {
-		utils.LogEventError(span, err)
-		return nil, model.ThrowError(http.StatusInternalServerError, err)
+		return nil, nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
+
+	now := time.Now()
+	stored := &model.RefreshToken{
+		ID:            uuid.New().String(),
+		FamilyID:      familyID,
+		UserID:        user.ID,
+		InstitutionID: user.InstitutionID,
+		TokenHash:     utils.HashRefreshToken(c.config.Auth.RefreshSecret, refreshToken),
+		ExpiresAt:     now.Add(c.config.Auth.RefreshTTL()),
+		IPAddress:     utils.Truncate(session.IPAddress, model.RefreshTokenIPAddressLength),
+		UserAgent:     utils.Truncate(session.UserAgent, model.RefreshTokenUserAgentLength),
+		CreatedAt:     now,
+	}
+
+	err = c.refreshTokenClient.InsertRefreshToken(ctx, tx, stored)
+	if err != nil {
+		return nil, nil, model.ThrowError(http.StatusInternalServerError, err)
 	}
 
-	response := &model.ResponseLogin{
+	return &model.ResponseLogin{
 		UserID:          user.ID,
 		Username:        user.Username,
 		Fullname:        user.Fullname,
 		Shortname:       user.Shortname,
 		RoleIDs:         user.RoleIDs,
 		Token:           accessToken,
+		ExpiresAt:       expiresAt,
+		RefreshToken:    refreshToken,
 		InstitutionID:   user.InstitutionID,
 		InstitutionName: user.InstitutionName,
 		MenuMapping:     menus,
+	}, stored, nil
+}
+
+// RefreshToken exchanges a refresh token for a new access token and a new refresh token of the same family; the
+// presented token cannot be used again. Presenting a token that was already exchanged means it leaked, so every
+// token of its family is revoked and the user has to sign in again.
+func (c *UserController) RefreshToken(ctx context.Context, request *model.RequestRefreshToken) (*model.ResponseLogin, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: RefreshToken")
+	defer span.Finish()
+
+	if request.RefreshToken == "" {
+		utils.LogEventError(span, errors.New("refresh_token is required"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("refresh_token is required"))
 	}
 
-	utils.LogEvent(span, "Response", response)
+	tx := c.db.Begin()
+
+	token, err := c.refreshTokenClient.GetRefreshTokenByHash(ctx, tx, utils.HashRefreshToken(c.config.Auth.RefreshSecret, request.RefreshToken))
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	switch {
+	case token == nil:
+		err = model.ThrowError(http.StatusUnauthorized, errors.New("invalid refresh token"))
+	case token.RevokedAt != nil:
+		err = model.ThrowError(http.StatusUnauthorized, errors.New("refresh token has been revoked"))
+	case token.UsedAt != nil:
+		err = c.revokeRefreshTokenFamily(ctx, tx, token)
+		if err != nil {
+			utils.LogEventError(span, err)
+			tx.Rollback()
+			return nil, err
+		}
+
+		err = tx.Commit().Error
+		if err != nil {
+			utils.LogEventError(span, err)
+			return nil, err
+		}
+
+		utils.LogEventError(span, errors.New("refresh token reused"))
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("refresh token was already used, sign in again"))
+	case time.Now().After(token.ExpiresAt):
+		err = model.ThrowError(http.StatusUnauthorized, errors.New("refresh token has expired"))
+	}
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	user, err := c.userClient.GetUserDetail(ctx, token.Username, token.InstitutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	response, next, err := c.issueLogin(ctx, tx, user, token.FamilyID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = c.refreshTokenClient.MarkRefreshTokenUsed(ctx, tx, token.ID, next.ID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		tx.Rollback()
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", map[string]any{"user_id": response.UserID, "family_id": token.FamilyID})
 
 	return response, nil
 }
 
+// revokeRefreshTokenFamily revokes every token of the reused token's family and audits it as
+// user.refresh_token_reused.
+func (c *UserController) revokeRefreshTokenFamily(ctx context.Context, tx *gorm.DB, token *model.RefreshToken) error {
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := c.refreshTokenClient.RevokeRefreshTokenFamily(ctx, tx, token.FamilyID)
+	if err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	metadata, err := json.Marshal(map[string]any{
+		"family_id": token.FamilyID,
+		"token_id":  token.ID,
+		"revoked":   revoked,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.auditClient.InsertAudit(ctx, tx, &model.AuditLog{
+		ID:            uuid.New().String(),
+		InstitutionID: &token.InstitutionID,
+		Action:        "user.refresh_token_reused",
+		EntityType:    "user",
+		EntityID:      token.UserID,
+		IPAddress:     session.IPAddress,
+		UserAgent:     session.UserAgent,
+		Metadata:      string(metadata),
+		CreatedAt:     time.Now(),
+	})
+}
+
+// FaceLogin signs a user in with a photo when the institution enables face login. The face is verified against the
+// claimed username, or identified without one, using the institution's active model and recognition policy. The
+// session must be a public session of the institution carrying the client's address.
+func (c *UserController) FaceLogin(ctx context.Context, request *model.RequestFaceLogin) (*model.ResponseLogin, error) {
+	span, ctx := utils.SpanFromContext(ctx, "Controller: FaceLogin")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"username": request.Username, "institution_id": request.InstitutionID})
+
+	if request.InstitutionID == "" {
+		utils.LogEventError(span, errors.New("institution_id is required"))
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("institution_id is required"))
+	}
+
+	session, err := utils.GetMetadata(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	res, token, err := c.faceLogin(ctx, session, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		// The attempt is refused either way; auditFaceLogin traces its own failures.
+		_ = c.auditFaceLogin(ctx, session, request, nil, err)
+		return nil, err
+	}
+
+	err = c.auditFaceLogin(ctx, session, request, res, nil)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+
+	utils.LogEvent(span, "Response", map[string]any{"user_id": res.UserID, "family_id": token.FamilyID})
+
+	return res, nil
+}
+
+func (c *UserController) faceLogin(ctx context.Context, session *model.MetadataUser, request *model.RequestFaceLogin) (*model.ResponseLogin, *model.RefreshToken, error) {
+	enabled, err := c.featureClient.IsFeatureEnabled(ctx, request.InstitutionID, model.FeatureFaceLogin)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !enabled {
+		return nil, nil, model.ThrowError(http.StatusForbidden, errors.New("face login is disabled for this institution"))
+	}
+
+	err = c.limitFaceLogin(ctx, request.InstitutionID, request.Username, session.IPAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	probe := &model.RequestRecognition{Username: request.Username, Image: request.Image}
+
+	var result *model.RecognitionResult
+	if request.Username != "" {
+		result, err = c.recognitionController.Verify(ctx, probe)
+	} else {
+		result, err = c.recognitionController.Identify(ctx, probe)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if result.Decision != model.RecognitionDecisionMatch || result.Username == nil {
+		return nil, nil, model.ThrowError(http.StatusUnauthorized, fmt.Errorf("face not recognized (event %s)", result.EventID))
+	}
+
+	user, err := c.userClient.GetUserDetail(ctx, *result.Username, request.InstitutionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c.issueLogin(ctx, nil, user, "")
+}
+
+// limitFaceLogin counts the attempt against the client's address and the claimed username, or the institution when
+// no username is claimed, and refuses it once any of them is over its auth.faceLogin limit for the current window.
+func (c *UserController) limitFaceLogin(ctx context.Context, institutionID string, username string, ipAddress string) error {
+	faceLogin := c.config.Auth.FaceLogin
+	keys := []string{faceLoginAttemptPrefix + institutionID + ":ip:" + ipAddress}
+	limits := []int64{faceLogin.AttemptLimit()}
+	if username != "" {
+		keys = append(keys, faceLoginAttemptPrefix+institutionID+":user:"+username)
+		limits = append(limits, faceLogin.AttemptLimit())
+	} else {
+		keys = append(keys, faceLoginAttemptPrefix+institutionID+":identify")
+		limits = append(limits, faceLogin.IdentifyAttemptLimit())
+	}
+
+	window := faceLogin.WindowDuration().Milliseconds()
+	for i, key := range keys {
+		attempts, err := countAttemptScript.Run(ctx, c.redis, []string{key}, window).Int64()
+		if err != nil {
+			return model.ThrowError(http.StatusInternalServerError, err)
+		}
+		if attempts > limits[i] {
+			return model.ThrowError(http.StatusTooManyRequests, errors.New("too many face login attempts, try again later"))
+		}
+	}
+
+	return nil
+}
+
+// auditFaceLogin records a face login attempt as user.face_login, or user.face_login_failed with the reason.
+func (c *UserController) auditFaceLogin(ctx context.Context, session *model.MetadataUser, request *model.RequestFaceLogin, res *model.ResponseLogin, loginErr error) error {
+	details := map[string]any{"claimed_username": request.Username}
+
+	action := "user.face_login"
+	var actor *string
+	var entityID string
+	if res != nil {
+		actor = &res.UserID
+		entityID = res.UserID
+		details["username"] = res.Username
+	} else {
+		action = "user.face_login_failed"
+		details["reason"] = loginErr.Error()
+	}
+
+	metadata, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	return c.auditClient.InsertAudit(ctx, nil, &model.AuditLog{
+		ID:            uuid.New().String(),
+		ActorUserID:   actor,
+		InstitutionID: &request.InstitutionID,
+		Action:        action,
+		EntityType:    "user",
+		EntityID:      entityID,
+		IPAddress:     session.IPAddress,
+		UserAgent:     session.UserAgent,
+		Metadata:      string(metadata),
+		CreatedAt:     time.Now(),
+	})
+}
+
 func (c *UserController) GetAllUser(ctx context.Context, pagination *model.Pagination, filter *model.Filter) ([]*model.User, *model.Pagination, error) {
 	span, ctx := utils.SpanFromContext(ctx, "Controller: GetAllUser")
 	defer span.Finish()
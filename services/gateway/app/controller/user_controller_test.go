@@ -0,0 +1,483 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type loginFeatureClient struct {
+	client.InterfaceFeatureClient
+	enabled bool
+}
+
+func (f *loginFeatureClient) IsFeatureEnabled(ctx context.Context, institutionID string, featureKey string) (bool, error) {
+	return f.enabled && featureKey == model.FeatureFaceLogin, nil
+}
+
+// loginRecognition answers every probe with result and counts how it was asked.
+type loginRecognition struct {
+	InterfaceRecognitionController
+	result     *model.RecognitionResult
+	identified int
+	verified   int
+}
+
+func (r *loginRecognition) Identify(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionResult, error) {
+	r.identified++
+	return r.result, nil
+}
+
+func (r *loginRecognition) Verify(ctx context.Context, req *model.RequestRecognition) (*model.RecognitionResult, error) {
+	r.verified++
+	return r.result, nil
+}
+
+type loginUserClient struct {
+	client.InterfaceUserClient
+	users map[string]*model.User
+}
+
+func (u *loginUserClient) GetUserDetail(ctx context.Context, username string, institutionID string) (*model.User, error) {
+	user, ok := u.users[username]
+	if !ok || user.InstitutionID != institutionID {
+		return nil, model.ThrowError(http.StatusNotFound, errors.New("user not found"))
+	}
+	return user, nil
+}
+
+func (u *loginUserClient) CreateAccessToken(ctx context.Context, user *model.User, isLogout bool) (string, int64, error) {
+	return "access-" + user.ID, time.Now().Add(15 * time.Minute).Unix(), nil
+}
+
+type loginAuditClient struct {
+	actions []string
+}
+
+func (a *loginAuditClient) InsertAudit(ctx context.Context, tx *gorm.DB, audit *model.AuditLog) error {
+	a.actions = append(a.actions, audit.Action)
+	return nil
+}
+
+// loginRefreshTokens keeps refresh tokens in memory, joined with the username like the real query.
+type loginRefreshTokens struct {
+	tokens    []*model.RefreshToken
+	usernames map[string]string
+}
+
+func (s *loginRefreshTokens) InsertRefreshToken(ctx context.Context, tx *gorm.DB, token *model.RefreshToken) error {
+	stored := *token
+	stored.Username = s.usernames[token.UserID]
+	s.tokens = append(s.tokens, &stored)
+	return nil
+}
+
+func (s *loginRefreshTokens) GetRefreshTokenByHash(ctx context.Context, tx *gorm.DB, tokenHash string) (*model.RefreshToken, error) {
+	for _, token := range s.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *loginRefreshTokens) MarkRefreshTokenUsed(ctx context.Context, tx *gorm.DB, id string, replacedBy string) error {
+	for _, token := range s.tokens {
+		if token.ID == id {
+			now := time.Now()
+			token.UsedAt = &now
+			token.ReplacedBy = &replacedBy
+		}
+	}
+	return nil
+}
+
+func (s *loginRefreshTokens) RevokeRefreshTokenFamily(ctx context.Context, tx *gorm.DB, familyID string) (int64, error) {
+	var revoked int64
+	for _, token := range s.tokens {
+		if token.FamilyID == familyID && token.RevokedAt == nil {
+			now := time.Now()
+			token.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+type loginFixture struct {
+	controller  *UserController
+	features    *loginFeatureClient
+	recognition *loginRecognition
+	audit       *loginAuditClient
+	tokens      *loginRefreshTokens
+	redis       *miniredis.Miniredis
+}
+
+func newLoginFixture(t *testing.T) *loginFixture {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	cfg := &config.Config{}
+	cfg.Auth.RefreshSecret = "refresh-secret"
+	cfg.Auth.FaceLogin = config.FaceLogin{MaxAttempts: 2, MaxIdentifyAttempts: 3, Window: "1m"}
+
+	alice := "alice"
+	f := &loginFixture{
+		features: &loginFeatureClient{enabled: true},
+		recognition: &loginRecognition{result: &model.RecognitionResult{
+			EventID:  "event-1",
+			Decision: model.RecognitionDecisionMatch,
+			Username: &alice,
+		}},
+		audit:  &loginAuditClient{},
+		tokens: &loginRefreshTokens{usernames: map[string]string{"user-1": "alice"}},
+		redis:  server,
+	}
+	users := &loginUserClient{users: map[string]*model.User{
+		"alice": {ID: "user-1", Username: "alice", InstitutionID: "inst-1"},
+	}}
+
+	f.controller = NewUserController(users, nil, nil, nil, f.features, f.audit, f.tokens, f.recognition, nil, cfg, rdb)
+
+	return f
+}
+
+func faceLoginContext(ipAddress string) context.Context {
+	return utils.WithPublicSession(context.Background(), "face-login", "inst-1", ipAddress, "kiosk/1.0")
+}
+
+func errorCode(err error) int {
+	var errResponse *model.ErrorResponse
+	if errors.As(err, &errResponse) {
+		return errResponse.Code
+	}
+	return 0
+}
+
+func TestFaceLoginIssuesTokensForAMatch(t *testing.T) {
+	f := newLoginFixture(t)
+
+	res, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), &model.RequestFaceLogin{Username: "alice", InstitutionID: "inst-1"})
+	if err != nil {
+		t.Fatalf("FaceLogin() error = %v", err)
+	}
+	if res.UserID != "user-1" || res.Token == "" || res.RefreshToken == "" {
+		t.Errorf("response = %+v", res)
+	}
+	if f.recognition.verified != 1 || f.recognition.identified != 0 {
+		t.Errorf("verified, identified = %d, %d; a claimed username must be verified", f.recognition.verified, f.recognition.identified)
+	}
+	if len(f.tokens.tokens) != 1 {
+		t.Errorf("stored refresh tokens = %d, want 1", len(f.tokens.tokens))
+	}
+	if len(f.audit.actions) != 1 || f.audit.actions[0] != "user.face_login" {
+		t.Errorf("audit = %v", f.audit.actions)
+	}
+
+	// Without a username the face is identified among the institution's users.
+	if _, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), &model.RequestFaceLogin{InstitutionID: "inst-1"}); err != nil {
+		t.Fatalf("FaceLogin() without username error = %v", err)
+	}
+	if f.recognition.identified != 1 {
+		t.Errorf("identified = %d, want 1", f.recognition.identified)
+	}
+}
+
+func TestFaceLoginDisabledForInstitution(t *testing.T) {
+	f := newLoginFixture(t)
+	f.features.enabled = false
+
+	_, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), &model.RequestFaceLogin{Username: "alice", InstitutionID: "inst-1"})
+	if code := errorCode(err); code != http.StatusForbidden {
+		t.Fatalf("FaceLogin() error = %v (code %d), want 403", err, code)
+	}
+	if f.recognition.verified+f.recognition.identified != 0 {
+		t.Error("a disabled face login still ran recognition")
+	}
+	if keys := f.redis.Keys(); len(keys) != 0 {
+		t.Errorf("a disabled face login counted attempts: %v", keys)
+	}
+	if len(f.audit.actions) != 1 || f.audit.actions[0] != "user.face_login_failed" {
+		t.Errorf("audit = %v", f.audit.actions)
+	}
+}
+
+func TestFaceLoginRejectsUnrecognizedFaces(t *testing.T) {
+	bob := "bob"
+	margin := 0.01
+	ambiguous := "ambiguous match: lead of 0.010 over carol is below the required 0.050"
+
+	for name, result := range map[string]*model.RecognitionResult{
+		"no match": {EventID: "event-2", Decision: model.RecognitionDecisionNoMatch},
+		"ambiguous margin": {
+			EventID:   "event-3",
+			Decision:  model.RecognitionDecisionNoMatch,
+			Margin:    &margin,
+			MinMargin: 0.05,
+			Message:   &ambiguous,
+		},
+		"match without a user": {EventID: "event-4", Decision: model.RecognitionDecisionMatch},
+		"error":                {EventID: "event-5", Decision: model.RecognitionDecisionError, Username: &bob},
+	} {
+		t.Run(name, func(t *testing.T) {
+			f := newLoginFixture(t)
+			f.recognition.result = result
+
+			_, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), &model.RequestFaceLogin{InstitutionID: "inst-1"})
+			if code := errorCode(err); code != http.StatusUnauthorized {
+				t.Fatalf("FaceLogin() error = %v (code %d), want 401", err, code)
+			}
+			if len(f.tokens.tokens) != 0 {
+				t.Error("a refused face login stored a refresh token")
+			}
+			if len(f.audit.actions) != 1 || f.audit.actions[0] != "user.face_login_failed" {
+				t.Errorf("audit = %v", f.audit.actions)
+			}
+		})
+	}
+}
+
+func TestFaceLoginLimitsAttempts(t *testing.T) {
+	f := newLoginFixture(t)
+	f.recognition.result = &model.RecognitionResult{EventID: "event-6", Decision: model.RecognitionDecisionNoMatch}
+	request := &model.RequestFaceLogin{Username: "alice", InstitutionID: "inst-1"}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		_, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), request)
+		if code := errorCode(err); code != http.StatusUnauthorized {
+			t.Fatalf("attempt %d: code %d, want 401", attempt, code)
+		}
+	}
+
+	_, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), request)
+	if code := errorCode(err); code != http.StatusTooManyRequests {
+		t.Fatalf("third attempt: code %d, want 429", code)
+	}
+
+	// The claimed username is limited on its own, so changing address does not help.
+	_, err = f.controller.FaceLogin(faceLoginContext("10.0.0.2"), request)
+	if code := errorCode(err); code != http.StatusTooManyRequests {
+		t.Fatalf("attempt from another address: code %d, want 429", code)
+	}
+	if f.recognition.verified != 2 {
+		t.Errorf("verified = %d, want 2: refused attempts must not reach recognition", f.recognition.verified)
+	}
+
+	// Every counter expires with its window.
+	for _, key := range f.redis.Keys() {
+		if ttl := f.redis.TTL(key); ttl <= 0 || ttl > time.Minute {
+			t.Errorf("TTL of %s = %v, want the 1m window", key, ttl)
+		}
+	}
+
+	f.redis.FastForward(time.Minute)
+
+	if _, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), request); errorCode(err) != http.StatusUnauthorized {
+		t.Fatalf("attempt after the window: %v, want 401", err)
+	}
+}
+
+func TestFaceLoginLimitsIdentifyAttemptsPerInstitution(t *testing.T) {
+	f := newLoginFixture(t)
+	f.recognition.result = &model.RecognitionResult{EventID: "event-7", Decision: model.RecognitionDecisionNoMatch}
+	request := &model.RequestFaceLogin{InstitutionID: "inst-1"}
+
+	// Each address stays under its own limit of two, but the institution allows three identify attempts.
+	addresses := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}
+	for i, address := range addresses {
+		_, err := f.controller.FaceLogin(faceLoginContext(address), request)
+
+		want := http.StatusUnauthorized
+		if i == len(addresses)-1 {
+			want = http.StatusTooManyRequests
+		}
+		if code := errorCode(err); code != want {
+			t.Fatalf("attempt from %s: code %d, want %d", address, code, want)
+		}
+	}
+
+	if f.recognition.identified != 3 {
+		t.Errorf("identified = %d, want 3", f.recognition.identified)
+	}
+}
+
+// withTransactions gives the controller a database that only sees the transactions RefreshToken opens; the refresh
+// tokens themselves live in loginRefreshTokens.
+func (f *loginFixture) withTransactions(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.controller.db = db
+
+	return mock
+}
+
+func refreshContext() context.Context {
+	return utils.WithPublicSession(context.Background(), "token-refresh", "", "10.0.0.1", "app/2.0")
+}
+
+func TestRefreshTokenRotatesWithinFamily(t *testing.T) {
+	f := newLoginFixture(t)
+	mock := f.withTransactions(t)
+
+	login, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), &model.RequestFaceLogin{Username: "alice", InstitutionID: "inst-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := f.tokens.tokens[0]
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	refreshed, err := f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatalf("RefreshToken() error = %v", err)
+	}
+
+	if refreshed.RefreshToken == login.RefreshToken || refreshed.Token == "" {
+		t.Errorf("refresh returned the same refresh token or no access token: %+v", refreshed)
+	}
+	if len(f.tokens.tokens) != 2 {
+		t.Fatalf("stored refresh tokens = %d, want 2", len(f.tokens.tokens))
+	}
+
+	second := f.tokens.tokens[1]
+	if second.FamilyID != first.FamilyID {
+		t.Errorf("new token family = %s, want %s", second.FamilyID, first.FamilyID)
+	}
+	if first.UsedAt == nil || first.ReplacedBy == nil || *first.ReplacedBy != second.ID {
+		t.Errorf("presented token used_at, replaced_by = %v, %v; want it replaced by %s", first.UsedAt, first.ReplacedBy, second.ID)
+	}
+	if second.TokenHash != utils.HashRefreshToken("refresh-secret", refreshed.RefreshToken) {
+		t.Error("the stored token is not the one returned to the client")
+	}
+	if second.UserAgent != "app/2.0" {
+		t.Errorf("new token user agent = %q, want the refreshing client's", second.UserAgent)
+	}
+
+	// The new token can be exchanged in turn.
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	if _, err := f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{RefreshToken: refreshed.RefreshToken}); err != nil {
+		t.Fatalf("second RefreshToken() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRefreshTokenReuseRevokesFamily(t *testing.T) {
+	f := newLoginFixture(t)
+	mock := f.withTransactions(t)
+
+	login, err := f.controller.FaceLogin(faceLoginContext("10.0.0.1"), &model.RequestFaceLogin{Username: "alice", InstitutionID: "inst-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	refreshed, err := f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{RefreshToken: login.RefreshToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Presenting the exchanged token again revokes the whole family and is committed, even though it fails.
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+	_, err = f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{RefreshToken: login.RefreshToken})
+	if code := errorCode(err); code != http.StatusUnauthorized {
+		t.Fatalf("reused RefreshToken() error = %v (code %d), want 401", err, code)
+	}
+
+	for _, token := range f.tokens.tokens {
+		if token.RevokedAt == nil {
+			t.Errorf("token %s of the reused family is not revoked", token.ID)
+		}
+	}
+	if last := f.audit.actions[len(f.audit.actions)-1]; last != "user.refresh_token_reused" {
+		t.Errorf("last audit action = %q, want user.refresh_token_reused", last)
+	}
+
+	// The token issued by the legitimate refresh is revoked with the family.
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	_, err = f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{RefreshToken: refreshed.RefreshToken})
+	if code := errorCode(err); code != http.StatusUnauthorized {
+		t.Fatalf("RefreshToken() with a revoked token error = %v (code %d), want 401", err, code)
+	}
+	if len(f.tokens.tokens) != 2 {
+		t.Errorf("stored refresh tokens = %d, want no new token after the reuse", len(f.tokens.tokens))
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRefreshTokenRejectsExpiredAndUnknownTokens(t *testing.T) {
+	f := newLoginFixture(t)
+	mock := f.withTransactions(t)
+
+	expired := &model.RefreshToken{
+		ID:            "token-1",
+		FamilyID:      "family-1",
+		UserID:        "user-1",
+		InstitutionID: "inst-1",
+		TokenHash:     utils.HashRefreshToken("refresh-secret", "expired-token"),
+		ExpiresAt:     time.Now().Add(-time.Minute),
+	}
+	f.tokens.InsertRefreshToken(context.Background(), nil, expired)
+
+	for _, presented := range []string{"expired-token", "unknown-token"} {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		_, err := f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{RefreshToken: presented})
+		if code := errorCode(err); code != http.StatusUnauthorized {
+			t.Errorf("RefreshToken(%s) error = %v (code %d), want 401", presented, err, code)
+		}
+	}
+
+	stored := f.tokens.tokens[0]
+	if stored.UsedAt != nil || stored.RevokedAt != nil {
+		t.Errorf("expired token was changed: used_at %v, revoked_at %v", stored.UsedAt, stored.RevokedAt)
+	}
+	if len(f.tokens.tokens) != 1 {
+		t.Errorf("stored refresh tokens = %d, want 1", len(f.tokens.tokens))
+	}
+
+	_, err := f.controller.RefreshToken(refreshContext(), &model.RequestRefreshToken{})
+	if code := errorCode(err); code != http.StatusBadRequest {
+		t.Errorf("RefreshToken() without a token error = %v (code %d), want 400", err, code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,40 +1,83 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Dataset struct {
-	ID        string     `json:"id" gorm:"column:id"`
-	Username  string     `json:"username" gorm:"column:username" validate:"required"`
-	Bucket    string     `json:"bucket" gorm:"column:bucket" validate:"required"`
-	Dataset   string     `json:"dataset" gorm:"column:dataset" validate:"required"`
-	File      []*File    `json:"file" gorm:"-"`
-	CreatedAt time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP"`
-	CreatedBy string     `json:"created_by" gorm:"column:created_by"`
-	UpdatedAt time.Time  `json:"updated_at" gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedBy string     `json:"updated_by" gorm:"column:updated_by"`
-	DeletedAt *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:timestamp;index"`
-	DeletedBy *string    `json:"deleted_by" gorm:"column:deleted_by"`
+	ID         string     `json:"id" gorm:"column:id"`
+	Username   string     `json:"username" gorm:"column:username" validate:"required"`
+	Bucket     string     `json:"bucket" gorm:"column:bucket" validate:"required"`
+	Dataset    string     `json:"dataset" gorm:"column:dataset" validate:"required"`
+	File       []*File    `json:"file" gorm:"-"`
+	SourceURLs []string   `json:"source_urls" gorm:"-"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP"`
+	CreatedBy  string     `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedBy  string     `json:"updated_by" gorm:"column:updated_by"`
+	DeletedAt  *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:timestamp;index"`
+	DeletedBy  *string    `json:"deleted_by" gorm:"column:deleted_by"`
 }
 
 type ModelTraining struct {
-	ID            string     `json:"id" gorm:"column:id"`
-	InstitutionID string     `json:"institution_id" gorm:"column:institution_id"`
-	Status        string     `json:"status" gorm:"column:status"`
-	IsUsed        string     `json:"is_used" gorm:"column:is_used"`
-	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP"`
-	CreatedBy     string     `json:"created_by" gorm:"column:created_by"`
-	UpdatedAt     time.Time  `json:"updated_at" gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP"`
-	UpdatedBy     string     `json:"updated_by" gorm:"column:updated_by"`
-	DeletedAt     *time.Time `json:"deleted_at" gorm:"column:deleted_at;type:timestamp;index"`
-	DeletedBy     *string    `json:"deleted_by" gorm:"column:deleted_by"`
+	ID            string               `json:"id" gorm:"column:id"`
+	InstitutionID string               `json:"institution_id" gorm:"column:institution_id"`
+	Status        string               `json:"status" gorm:"column:status"`
+	IsUsed        string               `json:"is_used" gorm:"column:is_used"`
+	Version       int                  `json:"version" gorm:"column:version"`
+	ModelPath     *string              `json:"model_path" gorm:"column:model_path"`
+	Metadata      json.RawMessage      `json:"metadata" gorm:"column:metadata;type:jsonb"`
+	ActivatedAt   *time.Time           `json:"activated_at" gorm:"column:activated_at;type:timestamp"`
+	ActivatedBy   *string              `json:"activated_by" gorm:"column:activated_by"`
+	StartedAt     *time.Time           `json:"started_at" gorm:"column:started_at;type:timestamp"`
+	FinishedAt    *time.Time           `json:"finished_at" gorm:"column:finished_at;type:timestamp"`
+	StatusMessage *string              `json:"status_message" gorm:"column:status_message"`
+	Progress      *float64             `json:"progress" gorm:"column:progress"`
+	FencingToken  *int64               `json:"fencing_token" gorm:"column:fencing_token"`
+	Mode          string               `json:"mode" gorm:"column:training_mode"`
+	BaseModelID   *string              `json:"base_model_id" gorm:"column:base_model_id"`
+	Priority      int                  `json:"priority" gorm:"column:priority"`
+	DispatchedAt  *time.Time           `json:"dispatched_at" gorm:"column:dispatched_at;type:timestamp"`
+	QueuePosition *int                 `json:"queue_position,omitempty" gorm:"-"`
+	Metrics       *ModelTrainingMetric `json:"metrics,omitempty" gorm:"-"`
+	CreatedAt     time.Time            `json:"created_at" gorm:"column:created_at;type:timestamp;default:CURRENT_TIMESTAMP"`
+	CreatedBy     string               `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt     time.Time            `json:"updated_at" gorm:"column:updated_at;type:timestamp;default:CURRENT_TIMESTAMP"`
+	UpdatedBy     string               `json:"updated_by" gorm:"column:updated_by"`
+	DeletedAt     *time.Time           `json:"deleted_at" gorm:"column:deleted_at;type:timestamp;index"`
+	DeletedBy     *string              `json:"deleted_by" gorm:"column:deleted_by"`
 }
 
 type FilterModelTraining struct {
-	InstitutionID string `json:"institution_id" gorm:"column:institution_id" validate:"required"`
-	Status        string `json:"status" gorm:"column:status" validate:"required"`
-	IsUsed        string `json:"is_used" gorm:"column:is_used" validate:"required"`
-	OrderBy       string `json:"order_by" gorm:"column:order_by" validate:"required"`
-	SortType      string `json:"sort_type" gorm:"column:sort_type" validate:"required"`
+	InstitutionID         string   `json:"institution_id" gorm:"column:institution_id" validate:"required"`
+	Status                string   `json:"status" gorm:"column:status" validate:"required"`
+	IsUsed                string   `json:"is_used" gorm:"column:is_used" validate:"required"`
+	OrderBy               string   `json:"order_by" gorm:"column:order_by" validate:"required"`
+	SortType              string   `json:"sort_type" gorm:"column:sort_type" validate:"required"`
+	MinValidationAccuracy *float64 `json:"min_validation_accuracy"`
+	MinIdentities         *int     `json:"min_identities"`
+	MinImages             *int     `json:"min_images"`
+	MaxDurationSeconds    *float64 `json:"max_duration_seconds"`
+	MaxArtifactSizeBytes  *int64   `json:"max_artifact_size_bytes"`
+}
+
+type RequestUploadDataset struct {
+	Username string                 `json:"username" validate:"required"`
+	Images   []*RequestDatasetImage `json:"images"`
+	URLs     []string               `json:"urls"`
+}
+
+type RequestDatasetImage struct {
+	FileName string `json:"file_name"`
+	Data     string `json:"data"`
+}
+
+type DatasetObject struct {
+	Key          string    `json:"key"`
+	ETag         string    `json:"etag"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
 }
 
 type DatasetURL struct {
@@ -46,14 +89,42 @@ type RequestTrainModel struct {
 }
 
 type ResponseTrainModel struct {
-	ID string `json:"id"`
+	ID        string `json:"id"`
+	Mode      string `json:"mode,omitempty"`
+	Coalesced bool   `json:"coalesced"`
 }
 
+// RequestAPITrainModel is the TrainModel message. An incremental message carries the base model and the delta
+// since it; the worker still reads the full prefix for anything it needs beyond the delta.
 type RequestAPITrainModel struct {
-	BucketName string `json:"bucket_name" validate:"required"`
-	Prefix     string `json:"prefix" validate:"required"`
-	CreatedBy  string `json:"created_by" validate:"required"`
-	ID         string `json:"id"`
+	BucketName  string         `json:"bucket_name" validate:"required"`
+	Prefix      string         `json:"prefix" validate:"required"`
+	CreatedBy   string         `json:"created_by" validate:"required"`
+	ID          string         `json:"id"`
+	Type        string         `json:"type"`
+	Mode        string         `json:"mode"`
+	Priority    int            `json:"priority"`
+	BaseModelID *string        `json:"base_model_id,omitempty"`
+	Delta       *TrainingDelta `json:"delta,omitempty"`
+}
+
+// TrainModelResult is reported by the processing service on the TrainModelResult queue whenever a job changes state.
+type TrainModelResult struct {
+	ID        string           `json:"id" validate:"required"`
+	Status    string           `json:"status" validate:"required"`
+	ModelPath *string          `json:"model_path"`
+	Metadata  json.RawMessage  `json:"metadata"`
+	Metrics   *TrainingMetrics `json:"metrics"`
+	Progress  *float64         `json:"progress"`
+	Message   string           `json:"message"`
+}
+
+// TrainModelCancel is broadcast on the TrainModelCancel exchange so the worker stops, or drops before starting, the job.
+type TrainModelCancel struct {
+	ID            string    `json:"id"`
+	InstitutionID string    `json:"institution_id"`
+	RequestedBy   string    `json:"requested_by"`
+	RequestedAt   time.Time `json:"requested_at"`
 }
 
 type ResponseAPITrainModel struct {
@@ -63,3 +134,19 @@ type ResponseAPITrainModel struct {
 		ID string `json:"id"`
 	}
 }
+
+type RequestReconcileDataset struct {
+	Repair bool `json:"repair"`
+}
+
+type DatasetReconcileReport struct {
+	StartedAt      time.Time `json:"started_at"`
+	FinishedAt     time.Time `json:"finished_at"`
+	Repair         bool      `json:"repair"`
+	OrphanObjects  []string  `json:"orphan_objects"`
+	MissingObjects []string  `json:"missing_objects"`
+	StaleRows      []string  `json:"stale_rows"`
+	Recent         []string  `json:"recent"`
+	Repaired       []string  `json:"repaired"`
+	Errors         []string  `json:"errors"`
+}
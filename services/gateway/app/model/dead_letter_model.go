@@ -0,0 +1,43 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TrainModelDeadLetterExchange = "TrainModel.dlx"
+	TrainModelDeadLetterQueue    = "TrainModel.dlq"
+	TrainModelRetryQueue         = "TrainModel.retry.%d"
+	TrainModelRetryCountHeader   = "x-retry-count"
+
+	DeadLetterStatusPending   = "PENDING"
+	DeadLetterStatusReplayed  = "REPLAYED"
+	DeadLetterStatusDiscarded = "DISCARDED"
+)
+
+type TrainingDeadLetter struct {
+	ID              string          `json:"id" gorm:"column:id"`
+	ModelTrainingID string          `json:"model_training_id" gorm:"column:model_training_id"`
+	InstitutionID   string          `json:"institution_id" gorm:"column:institution_id"`
+	Payload         json.RawMessage `json:"payload" gorm:"column:payload;type:jsonb"`
+	Reason          *string         `json:"reason" gorm:"column:reason"`
+	RetryCount      int             `json:"retry_count" gorm:"column:retry_count"`
+	Status          string          `json:"status" gorm:"column:status"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"column:created_at"`
+	ResolvedAt      *time.Time      `json:"resolved_at" gorm:"column:resolved_at"`
+	ResolvedBy      *string         `json:"resolved_by" gorm:"column:resolved_by"`
+	Training        *ModelTraining  `json:"training" gorm:"-"`
+}
+
+func (TrainingDeadLetter) TableName() string {
+	return "training_dead_letter"
+}
+
+// DeadLetteredTraining is a training message rejected by the worker, as read from the dead-letter queue.
+type DeadLetteredTraining struct {
+	Payload    json.RawMessage
+	Request    *RequestAPITrainModel
+	Reason     string
+	RetryCount int
+}
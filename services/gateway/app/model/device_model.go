@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	DeviceStatusActive   = "ACTIVE"
+	DeviceStatusDisabled = "DISABLED"
+
+	// DeviceKeyHeader carries the API key of a kiosk or camera instead of a user's bearer token.
+	DeviceKeyHeader = "X-Device-Key"
+)
+
+// Device is a kiosk or camera of an institution. Only the hash of its API key is stored; KeyPrefix identifies the
+// key and is shown to admins.
+type Device struct {
+	ID              string          `json:"id" gorm:"column:id"`
+	InstitutionID   string          `json:"institution_id" gorm:"column:institution_id"`
+	Name            string          `json:"name" gorm:"column:name"`
+	Location        *string         `json:"location" gorm:"column:location"`
+	Status          string          `json:"status" gorm:"column:status"`
+	KeyPrefix       string          `json:"key_prefix" gorm:"column:key_prefix"`
+	KeyHash         string          `json:"-" gorm:"column:key_hash"`
+	KeyRotatedAt    time.Time       `json:"key_rotated_at" gorm:"column:key_rotated_at;type:timestamp"`
+	LastHeartbeatAt *time.Time      `json:"last_heartbeat_at" gorm:"column:last_heartbeat_at;type:timestamp"`
+	LastIPAddress   *string         `json:"last_ip_address" gorm:"column:last_ip_address"`
+	Metadata        json.RawMessage `json:"metadata" gorm:"column:metadata;type:jsonb"`
+	Online          bool            `json:"online" gorm:"-"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"column:created_at;type:timestamp"`
+	CreatedBy       string          `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt       time.Time       `json:"updated_at" gorm:"column:updated_at;type:timestamp"`
+	UpdatedBy       string          `json:"updated_by" gorm:"column:updated_by"`
+}
+
+// DeviceWithKey is returned once when a device is created or its key rotated. The key cannot be read again.
+type DeviceWithKey struct {
+	*Device
+	APIKey string `json:"api_key"`
+}
+
+type FilterDevice struct {
+	InstitutionID string `json:"institution_id"`
+	Status        string `json:"status"`
+}
+
+// RequestDevice creates or updates a device. Omitted fields keep their value on update.
+type RequestDevice struct {
+	InstitutionID string  `json:"institution_id"`
+	Name          *string `json:"name"`
+	Location      *string `json:"location"`
+	Status        *string `json:"status"`
+}
+
+// RequestDeviceHeartbeat is sent by a device to report it is alive. Metadata is free-form, e.g. a software version.
+type RequestDeviceHeartbeat struct {
+	Metadata json.RawMessage `json:"metadata"`
+}
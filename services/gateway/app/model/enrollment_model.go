@@ -0,0 +1,89 @@
+package model
+
+import "time"
+
+const (
+	EnrollmentStatusOpen      = "OPEN"
+	EnrollmentStatusCompleted = "COMPLETED"
+	EnrollmentStatusCancelled = "CANCELLED"
+	EnrollmentStatusExpired   = "EXPIRED"
+
+	PoseFrontal = "frontal"
+	PoseLeft    = "left"
+	PoseRight   = "right"
+)
+
+type EnrollmentSession struct {
+	ID                string              `json:"id" gorm:"column:id"`
+	InstitutionID     string              `json:"institution_id" gorm:"column:institution_id"`
+	UserID            string              `json:"user_id" gorm:"column:user_id"`
+	Username          string              `json:"username" gorm:"column:username"`
+	Status            string              `json:"status" gorm:"column:status"`
+	RequiredCount     int                 `json:"required_count" gorm:"column:required_count"`
+	RequiredFrontal   int                 `json:"required_frontal" gorm:"column:required_frontal"`
+	RequiredLeft      int                 `json:"required_left" gorm:"column:required_left"`
+	RequiredRight     int                 `json:"required_right" gorm:"column:required_right"`
+	RequireGlassesOff bool                `json:"require_glasses_off" gorm:"column:require_glasses_off"`
+	ExpiresAt         *time.Time          `json:"expires_at" gorm:"column:expires_at"`
+	CompletedAt       *time.Time          `json:"completed_at" gorm:"column:completed_at"`
+	CreatedAt         time.Time           `json:"created_at" gorm:"column:created_at"`
+	CreatedBy         string              `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt         time.Time           `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy         string              `json:"updated_by" gorm:"column:updated_by"`
+	Progress          *EnrollmentProgress `json:"progress" gorm:"-"`
+}
+
+func (EnrollmentSession) TableName() string {
+	return "enrollment_session"
+}
+
+type EnrollmentCapture struct {
+	ID         string    `json:"id" gorm:"column:id"`
+	SessionID  string    `json:"session_id" gorm:"column:session_id"`
+	Pose       string    `json:"pose" gorm:"column:pose"`
+	GlassesOff bool      `json:"glasses_off" gorm:"column:glasses_off"`
+	FileName   string    `json:"file_name" gorm:"column:file_name"`
+	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
+	CreatedBy  string    `json:"created_by" gorm:"column:created_by"`
+}
+
+func (EnrollmentCapture) TableName() string {
+	return "enrollment_capture"
+}
+
+type EnrollmentRequirement struct {
+	Name     string `json:"name"`
+	Required int    `json:"required"`
+	Captured int    `json:"captured"`
+	IsMet    bool   `json:"is_met"`
+}
+
+type EnrollmentProgress struct {
+	Requirements []*EnrollmentRequirement `json:"requirements"`
+	Percent      int                      `json:"percent"`
+	IsComplete   bool                     `json:"is_complete"`
+}
+
+type RequestCreateEnrollment struct {
+	Username          string `json:"username" validate:"required"`
+	RequiredCount     int    `json:"required_count"`
+	RequiredFrontal   int    `json:"required_frontal"`
+	RequiredLeft      int    `json:"required_left"`
+	RequiredRight     int    `json:"required_right"`
+	RequireGlassesOff bool   `json:"require_glasses_off"`
+	ExpiresInHours    int    `json:"expires_in_hours"`
+}
+
+type RequestEnrollmentCapture struct {
+	SessionID  string  `json:"session_id"`
+	Pose       string  `json:"pose"`
+	GlassesOff bool    `json:"glasses_off"`
+	File       []*File `json:"file"`
+}
+
+type EnrollmentReadiness struct {
+	InstitutionID string   `json:"institution_id"`
+	IsReady       bool     `json:"is_ready"`
+	OpenSessions  int      `json:"open_sessions"`
+	PendingUsers  []string `json:"pending_users"`
+}
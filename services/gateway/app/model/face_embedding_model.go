@@ -0,0 +1,79 @@
+package model
+
+import "time"
+
+// FaceEmbeddingMaxDimension bounds the length of a stored or searched vector, as does the train.embeddings schema.
+const FaceEmbeddingMaxDimension = 4096
+
+// TrainModelEmbeddings carries face embeddings computed by the training with ID. A training may report its
+// embeddings over several messages.
+type TrainModelEmbeddings struct {
+	ID         string              `json:"id"`
+	Embeddings []*TrainedEmbedding `json:"embeddings"`
+}
+
+// TrainedEmbedding is the embedding of one dataset image. ImageKey is the object key of the image.
+type TrainedEmbedding struct {
+	Username string    `json:"username"`
+	ImageKey string    `json:"image_key"`
+	Vector   []float64 `json:"vector"`
+}
+
+// FaceEmbedding is a stored template. Vector is L2 normalised and only set when writing.
+type FaceEmbedding struct {
+	ID              string    `json:"id" gorm:"column:id"`
+	InstitutionID   string    `json:"institution_id" gorm:"column:institution_id"`
+	ModelTrainingID string    `json:"model_training_id" gorm:"column:model_training_id"`
+	ModelVersion    int       `json:"model_version" gorm:"column:model_version"`
+	Username        string    `json:"username" gorm:"column:username"`
+	ImageKey        string    `json:"image_key" gorm:"column:image_key"`
+	Dimension       int       `json:"dimension" gorm:"column:dimension"`
+	Vector          []float64 `json:"-" gorm:"-"`
+	CreatedAt       time.Time `json:"created_at" gorm:"column:created_at;type:timestamp"`
+}
+
+// RequestEmbeddingSearch searches the templates of a model version, the active model when ModelVersion is nil.
+// TopK defaults to the institution's recognition policy.
+type RequestEmbeddingSearch struct {
+	InstitutionID string    `json:"institution_id"`
+	ModelVersion  *int      `json:"model_version"`
+	Embedding     []float64 `json:"embedding"`
+	TopK          int       `json:"top_k"`
+}
+
+type RequestEmbeddingCleanup struct {
+	Repair bool `json:"repair"`
+}
+
+// EmbeddingDimensionMismatch counts the templates of a training stored with another dimension than the training's.
+type EmbeddingDimensionMismatch struct {
+	ModelTrainingID   string `json:"model_training_id" gorm:"column:model_training_id"`
+	InstitutionID     string `json:"institution_id" gorm:"column:institution_id"`
+	TrainingDimension int    `json:"training_dimension" gorm:"column:training_dimension"`
+	Dimension         int    `json:"dimension" gorm:"column:dimension"`
+	Embeddings        int64  `json:"embeddings" gorm:"column:embeddings"`
+	Deleted           int64  `json:"deleted" gorm:"-"`
+}
+
+type EmbeddingCleanupReport struct {
+	Repair     bool                          `json:"repair"`
+	Mismatches []*EmbeddingDimensionMismatch `json:"mismatches"`
+	Deleted    int64                         `json:"deleted"`
+	Errors     []string                      `json:"errors"`
+}
+
+// EmbeddingMatch is the closest template of a user. Score is the cosine similarity.
+type EmbeddingMatch struct {
+	Username string  `json:"username" gorm:"column:username"`
+	ImageKey string  `json:"image_key" gorm:"column:image_key"`
+	Score    float64 `json:"score" gorm:"column:score"`
+}
+
+// EmbeddingSearchResult lists at most TopK users, ordered by descending score.
+type EmbeddingSearchResult struct {
+	ModelID      string            `json:"model_id"`
+	ModelVersion int               `json:"model_version"`
+	Dimension    int               `json:"dimension"`
+	Threshold    float64           `json:"threshold"`
+	Matches      []*EmbeddingMatch `json:"matches"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Lock is a distributed lock held in Redis. Token is a fencing token that increases with every acquisition of
+// the same key, so writes guarded by it can reject a holder whose lock has already expired. TTL is the remaining
+// time to live and is only set by LockClient.Get.
+type Lock struct {
+	Key   string        `json:"key"`
+	Token int64         `json:"token"`
+	Owner string        `json:"owner"`
+	TTL   time.Duration `json:"ttl,omitempty"`
+}
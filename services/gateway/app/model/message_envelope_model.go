@@ -0,0 +1,32 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	MessageSpecVersion     = "1.0"
+	MessageSource          = "/gateway"
+	MessageContentType     = "application/cloudevents+json"
+	MessageDataContentType = "application/json"
+
+	TrainModelCancelMessage    = "train.cancel"
+	TrainModelResultMessage    = "train.result"
+	TrainModelEmbeddingMessage = "train.embeddings"
+	ModelActivationMessage     = "model.activation"
+)
+
+// MessageEnvelope wraps every AMQP message body in a CloudEvents 1.0 structured event. Type selects the JSON
+// schema Data is validated against; DataSchema names that schema and its version.
+type MessageEnvelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Institution     string          `json:"institution,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+const (
+	ModelTrainingStatusStarted    = "STARTED"
+	ModelTrainingStatusRunning    = "RUNNING"
+	ModelTrainingStatusSucceeded  = "SUCCEEDED"
+	ModelTrainingStatusFailed     = "FAILED"
+	ModelTrainingStatusCancelling = "CANCELLING"
+	ModelTrainingStatusCancelled  = "CANCELLED"
+
+	ModelActivationActionActivate = "ACTIVATE"
+	ModelActivationActionRollback = "ROLLBACK"
+)
+
+type ModelActivation struct {
+	ID                      string    `json:"id" gorm:"column:id"`
+	InstitutionID           string    `json:"institution_id" gorm:"column:institution_id"`
+	ModelTrainingID         string    `json:"model_training_id" gorm:"column:model_training_id"`
+	PreviousModelTrainingID *string   `json:"previous_model_training_id" gorm:"column:previous_model_training_id"`
+	Action                  string    `json:"action" gorm:"column:action"`
+	CreatedAt               time.Time `json:"created_at" gorm:"column:created_at"`
+	CreatedBy               string    `json:"created_by" gorm:"column:created_by"`
+}
+
+func (ModelActivation) TableName() string {
+	return "model_activation"
+}
+
+type RequestRollbackModel struct {
+	ModelID string `json:"model_id"`
+}
+
+// ModelActivationEvent is broadcast on the ModelActivation exchange so recognition workers can hot-swap the served model.
+type ModelActivationEvent struct {
+	InstitutionID   string    `json:"institution_id"`
+	ModelID         string    `json:"model_id"`
+	Version         int       `json:"version"`
+	ModelPath       *string   `json:"model_path"`
+	BucketName      string    `json:"bucket_name"`
+	PreviousModelID *string   `json:"previous_model_id"`
+	Action          string    `json:"action"`
+	ActivatedBy     string    `json:"activated_by"`
+	ActivatedAt     time.Time `json:"activated_at"`
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	OutboxStatusPending   = "PENDING"
+	OutboxStatusSent      = "SENT"
+	OutboxStatusFailed    = "FAILED"
+	OutboxStatusDiscarded = "DISCARDED"
+
+	OutboxTopicTrainModel = "TrainModel"
+)
+
+type OutboxMessage struct {
+	ID            string          `json:"id" gorm:"column:id"`
+	Topic         string          `json:"topic" gorm:"column:topic"`
+	AggregateID   string          `json:"aggregate_id" gorm:"column:aggregate_id"`
+	Payload       json.RawMessage `json:"payload" gorm:"column:payload;type:jsonb"`
+	Status        string          `json:"status" gorm:"column:status"`
+	Attempts      int             `json:"attempts" gorm:"column:attempts"`
+	LastError     *string         `json:"last_error" gorm:"column:last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" gorm:"column:next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at" gorm:"column:created_at"`
+	SentAt        *time.Time      `json:"sent_at" gorm:"column:sent_at"`
+	// ClaimedUntil is set while a relay publishes the message; other relays skip it until then.
+	ClaimedUntil *time.Time `json:"claimed_until" gorm:"column:claimed_until"`
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
+
+type OutboxMetrics struct {
+	Pending              int64   `json:"pending" gorm:"column:pending"`
+	Failed               int64   `json:"failed" gorm:"column:failed"`
+	OldestPendingSeconds float64 `json:"oldest_pending_seconds" gorm:"column:oldest_pending_seconds"`
+	SentLastHour         int64   `json:"sent_last_hour" gorm:"column:sent_last_hour"`
+	AvgLagSeconds        float64 `json:"avg_lag_seconds" gorm:"column:avg_lag_seconds"`
+}
+
+type OutboxRelayResult struct {
+	Sent    int `json:"sent"`
+	Retried int `json:"retried"`
+	Failed  int `json:"failed"`
+	// Deferred counts training messages held back because training.maxInFlight jobs were already dispatched.
+	Deferred int `json:"deferred"`
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RecognitionKindIdentify = "IDENTIFY"
+	RecognitionKindVerify   = "VERIFY"
+
+	RecognitionDecisionMatch   = "MATCH"
+	RecognitionDecisionNoMatch = "NO_MATCH"
+	RecognitionDecisionError   = "ERROR"
+
+	// RecognitionQueue receives identify and verify requests; the processing service replies to ReplyTo. Each
+	// request carries its own expiration, so the queue has no TTL of its own.
+	RecognitionQueue = "Recognition.v2"
+
+	RecognitionIdentifyMessage = "recognition.identify"
+	RecognitionVerifyMessage   = "recognition.verify"
+	RecognitionResultMessage   = "recognition.result"
+)
+
+// RequestRecognition is a probe image submitted to the identify or verify endpoint. Username is the claimed
+// identity and is required to verify. JSON requests carry the probe as base64 in ImageData.
+type RequestRecognition struct {
+	Username  string `json:"username"`
+	DeviceID  string `json:"device_id"`
+	ImageData string `json:"image"`
+	Image     *File  `json:"-"`
+}
+
+// RecognitionRequest is sent on the RecognitionQueue. Image is the base64 encoded probe.
+type RecognitionRequest struct {
+	InstitutionID string  `json:"institution_id"`
+	ModelID       string  `json:"model_id"`
+	ModelVersion  int     `json:"model_version"`
+	ModelPath     *string `json:"model_path"`
+	BucketName    string  `json:"bucket_name"`
+	Username      string  `json:"username,omitempty"`
+	TopK          int     `json:"top_k"`
+	Image         string  `json:"image"`
+}
+
+// RecognitionReply is the processing service's answer, with candidates ordered by descending score.
+type RecognitionReply struct {
+	FaceDetected bool                    `json:"face_detected"`
+	Candidates   []*RecognitionCandidate `json:"candidates"`
+	Message      string                  `json:"message"`
+}
+
+type RecognitionCandidate struct {
+	Username string  `json:"username"`
+	Score    float64 `json:"score"`
+}
+
+type RecognitionResult struct {
+	EventID      string                  `json:"event_id"`
+	Kind         string                  `json:"kind"`
+	Decision     string                  `json:"decision"`
+	Username     *string                 `json:"username"`
+	Score        *float64                `json:"score"`
+	Threshold    float64                 `json:"threshold"`
+	Margin       *float64                `json:"margin"`
+	MinMargin    float64                 `json:"min_margin"`
+	Candidates   []*RecognitionCandidate `json:"candidates"`
+	ModelID      string                  `json:"model_id"`
+	ModelVersion int                     `json:"model_version"`
+	LatencyMs    int64                   `json:"latency_ms"`
+	Message      *string                 `json:"message"`
+}
+
+type RecognitionEvent struct {
+	ID            string          `json:"id" gorm:"column:id"`
+	InstitutionID string          `json:"institution_id" gorm:"column:institution_id"`
+	Kind          string          `json:"kind" gorm:"column:kind"`
+	Caller        string          `json:"caller" gorm:"column:caller"`
+	DeviceID      *string         `json:"device_id" gorm:"column:device_id"`
+	ClaimedUser   *string         `json:"claimed_user" gorm:"column:claimed_user"`
+	MatchedUser   *string         `json:"matched_user" gorm:"column:matched_user"`
+	Score         *float64        `json:"score" gorm:"column:score"`
+	ModelID       *string         `json:"model_id" gorm:"column:model_id"`
+	ModelVersion  *int            `json:"model_version" gorm:"column:model_version"`
+	LatencyMs     int64           `json:"latency_ms" gorm:"column:latency_ms"`
+	Decision      string          `json:"decision" gorm:"column:decision"`
+	ProbePath     *string         `json:"probe_path" gorm:"column:probe_path"`
+	Message       *string         `json:"message" gorm:"column:message"`
+	BatchID       *string         `json:"batch_id" gorm:"column:batch_id"`
+	FaceBox       json.RawMessage `json:"face_box" gorm:"column:face_box;type:jsonb"`
+	CreatedAt     time.Time       `json:"created_at" gorm:"column:created_at;type:timestamp"`
+}
+
+// FilterRecognitionEvent narrows event queries. From is inclusive and To exclusive.
+type FilterRecognitionEvent struct {
+	InstitutionID string     `json:"institution_id"`
+	From          *time.Time `json:"from"`
+	To            *time.Time `json:"to"`
+	Username      string     `json:"username"`
+	DeviceID      string     `json:"device_id"`
+	Decision      string     `json:"decision"`
+	Kind          string     `json:"kind"`
+	BatchID       string     `json:"batch_id"`
+}
+
+// RecognitionStats aggregates events. MatchRate is matches over matches and no-matches; errors are excluded.
+type RecognitionStats struct {
+	Hour              *time.Time `json:"hour,omitempty" gorm:"column:hour"`
+	Total             int64      `json:"total" gorm:"column:total"`
+	Matches           int64      `json:"matches" gorm:"column:matches"`
+	NoMatches         int64      `json:"no_matches" gorm:"column:no_matches"`
+	Errors            int64      `json:"errors" gorm:"column:errors"`
+	MatchRate         *float64   `json:"match_rate" gorm:"column:match_rate"`
+	AverageScore      *float64   `json:"average_score" gorm:"column:average_score"`
+	AverageMatchScore *float64   `json:"average_match_score" gorm:"column:average_match_score"`
+	AverageLatencyMs  *float64   `json:"average_latency_ms" gorm:"column:average_latency_ms"`
+}
+
+// RecognitionUnknown is reported for a face of a group photo that matched nobody.
+const RecognitionUnknown = "unknown"
+
+// FaceBox is a face region in pixels of the original image, from its top left corner.
+type FaceBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// DetectedFace is a face found in a group photo with its crop, which is sent for recognition as a probe.
+type DetectedFace struct {
+	Box        FaceBox `json:"box"`
+	Confidence float64 `json:"confidence"`
+	Image      *File   `json:"-"`
+}
+
+type RecognitionFace struct {
+	Box        FaceBox  `json:"box"`
+	Confidence float64  `json:"confidence"`
+	EventID    string   `json:"event_id"`
+	Decision   string   `json:"decision"`
+	Username   string   `json:"username"`
+	Score      *float64 `json:"score"`
+	Message    string   `json:"message,omitempty"`
+}
+
+// RecognitionGroupResult lists the faces of a group photo, from left to right.
+type RecognitionGroupResult struct {
+	BatchID      string             `json:"batch_id"`
+	FaceCount    int                `json:"face_count"`
+	Truncated    bool               `json:"truncated"`
+	Faces        []*RecognitionFace `json:"faces"`
+	Threshold    float64            `json:"threshold"`
+	ModelID      string             `json:"model_id"`
+	ModelVersion int                `json:"model_version"`
+	LatencyMs    int64              `json:"latency_ms"`
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// RecognitionPolicy decides how the gateway turns candidate scores into a match for one institution.
+// MinMargin is the lead the matched user needs over the next best candidate; MaxModelAgeDays refuses recognition
+// with an active model trained longer ago. IsDefault is set when the institution has no stored policy and the
+// configured defaults apply.
+type RecognitionPolicy struct {
+	ID              string     `json:"id" gorm:"column:id"`
+	InstitutionID   string     `json:"institution_id" gorm:"column:institution_id"`
+	MatchThreshold  float64    `json:"match_threshold" gorm:"column:match_threshold"`
+	TopK            int        `json:"top_k" gorm:"column:top_k"`
+	MinMargin       float64    `json:"min_margin" gorm:"column:min_margin"`
+	MaxModelAgeDays *int       `json:"max_model_age_days" gorm:"column:max_model_age_days"`
+	IsDefault       bool       `json:"is_default" gorm:"-"`
+	CreatedAt       *time.Time `json:"created_at" gorm:"column:created_at"`
+	CreatedBy       string     `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt       *time.Time `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy       string     `json:"updated_by" gorm:"column:updated_by"`
+}
+
+func (RecognitionPolicy) TableName() string {
+	return "recognition_policy"
+}
+
+// RequestRecognitionPolicy updates a policy. Omitted fields keep their current value.
+type RequestRecognitionPolicy struct {
+	MatchThreshold  *float64 `json:"match_threshold"`
+	TopK            *int     `json:"top_k"`
+	MinMargin       *float64 `json:"min_margin"`
+	MaxModelAgeDays *int     `json:"max_model_age_days"`
+}
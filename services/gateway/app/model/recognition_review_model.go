@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+const (
+	RecognitionReviewPending   = "PENDING"
+	RecognitionReviewLabeled   = "LABELED"
+	RecognitionReviewDiscarded = "DISCARDED"
+	RecognitionReviewImpostor  = "IMPOSTOR"
+)
+
+// RecognitionReview is an unmatched probe waiting for an admin to label, discard or flag it. ProbePath is the
+// object key in the probe bucket; the image is kept until ExpiresAt.
+type RecognitionReview struct {
+	ID            string     `json:"id" gorm:"column:id"`
+	InstitutionID string     `json:"institution_id" gorm:"column:institution_id"`
+	EventID       *string    `json:"event_id" gorm:"column:event_id"`
+	Kind          string     `json:"kind" gorm:"column:kind"`
+	DeviceID      *string    `json:"device_id" gorm:"column:device_id"`
+	ClaimedUser   *string    `json:"claimed_user" gorm:"column:claimed_user"`
+	BestCandidate *string    `json:"best_candidate" gorm:"column:best_candidate"`
+	BestScore     *float64   `json:"best_score" gorm:"column:best_score"`
+	ProbePath     string     `json:"probe_path" gorm:"column:probe_path"`
+	Status        string     `json:"status" gorm:"column:status"`
+	LabeledUser   *string    `json:"labeled_user" gorm:"column:labeled_user"`
+	Note          *string    `json:"note" gorm:"column:note"`
+	DecidedAt     *time.Time `json:"decided_at" gorm:"column:decided_at;type:timestamp"`
+	DecidedBy     *string    `json:"decided_by" gorm:"column:decided_by"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"column:expires_at;type:timestamp"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp"`
+}
+
+func (RecognitionReview) TableName() string {
+	return "recognition_review"
+}
+
+type FilterRecognitionReview struct {
+	InstitutionID string `json:"institution_id"`
+	Status        string `json:"status"`
+}
+
+// RequestRecognitionReviewDecision labels, discards or flags a review. Username is required to label.
+type RequestRecognitionReviewDecision struct {
+	Username string `json:"username"`
+	Note     string `json:"note"`
+}
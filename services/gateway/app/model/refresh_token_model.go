@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// Lengths of the refresh_token columns that store request headers.
+const (
+	RefreshTokenIPAddressLength = 64
+	RefreshTokenUserAgentLength = 512
+)
+
+// RefreshToken is one refresh token of a login. Every use replaces it with a new token of the same family; only the
+// hash of the token is stored.
+type RefreshToken struct {
+	ID            string     `json:"id" gorm:"column:id"`
+	FamilyID      string     `json:"family_id" gorm:"column:family_id"`
+	UserID        string     `json:"user_id" gorm:"column:user_id"`
+	Username      string     `json:"username" gorm:"column:username;->"`
+	InstitutionID string     `json:"institution_id" gorm:"column:institution_id"`
+	TokenHash     string     `json:"-" gorm:"column:token_hash"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"column:expires_at;type:timestamp"`
+	UsedAt        *time.Time `json:"used_at" gorm:"column:used_at;type:timestamp"`
+	ReplacedBy    *string    `json:"replaced_by" gorm:"column:replaced_by"`
+	RevokedAt     *time.Time `json:"revoked_at" gorm:"column:revoked_at;type:timestamp"`
+	IPAddress     string     `json:"ip_address" gorm:"column:ip_address"`
+	UserAgent     string     `json:"user_agent" gorm:"column:user_agent"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"column:created_at;type:timestamp"`
+}
+
+type RequestRefreshToken struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// TrainingEventChannel is the Redis pub/sub channel of an institution's training events.
+const TrainingEventChannel = "training:events:%s"
+
+// TrainingEvent is a status or progress change of a training job, streamed to the admin UI.
+type TrainingEvent struct {
+	ID            string    `json:"id"`
+	InstitutionID string    `json:"institution_id"`
+	Status        string    `json:"status"`
+	Mode          string    `json:"mode,omitempty"`
+	Progress      *float64  `json:"progress"`
+	Message       *string   `json:"message"`
+	At            time.Time `json:"at"`
+}
+
+func NewTrainingEvent(training *ModelTraining) *TrainingEvent {
+	return &TrainingEvent{
+		ID:            training.ID,
+		InstitutionID: training.InstitutionID,
+		Status:        training.Status,
+		Mode:          training.Mode,
+		Progress:      training.Progress,
+		Message:       training.StatusMessage,
+		At:            time.Now(),
+	}
+}
+
+// TrainingEventStream is a subscription to training events. Snapshot holds the current state of the subscribed
+// trainings and is sent before any event from Events. TrainingID is set when a single training is followed.
+type TrainingEventStream struct {
+	TrainingID string
+	Snapshot   []*TrainingEvent
+	Events     <-chan *TrainingEvent
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	TrainingModeFull        = "full"
+	TrainingModeIncremental = "incremental"
+)
+
+// Message types of TrainModel messages, also set as the AMQP type property.
+const (
+	TrainModelMessageFull        = "train.full"
+	TrainModelMessageIncremental = "train.incremental"
+)
+
+// TrainingManifest is the snapshot of dataset objects a training was queued with. The next incremental training
+// is computed against the manifest of the last successful one.
+type TrainingManifest struct {
+	ModelTrainingID string          `json:"model_training_id" gorm:"column:model_training_id"`
+	Objects         json.RawMessage `json:"objects" gorm:"column:objects;type:jsonb"`
+	ObjectCount     int             `json:"object_count" gorm:"column:object_count"`
+	CreatedAt       time.Time       `json:"created_at" gorm:"column:created_at"`
+}
+
+func (TrainingManifest) TableName() string {
+	return "model_training_manifest"
+}
+
+type ManifestObject struct {
+	Key  string `json:"key"`
+	ETag string `json:"etag"`
+	Size int64  `json:"size"`
+}
+
+// TrainingDelta lists what changed in the dataset since the base model. Users are usernames; images are object keys.
+type TrainingDelta struct {
+	AddedUsers    []string `json:"added_users"`
+	RemovedUsers  []string `json:"removed_users"`
+	ChangedImages []string `json:"changed_images"`
+	RemovedImages []string `json:"removed_images"`
+}
+
+func (d *TrainingDelta) IsEmpty() bool {
+	return len(d.AddedUsers) == 0 && len(d.RemovedUsers) == 0 && len(d.ChangedImages) == 0 && len(d.RemovedImages) == 0
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ThresholdMetric is the false accept and false reject rate measured at one recognition threshold.
+type ThresholdMetric struct {
+	Threshold float64 `json:"threshold"`
+	FAR       float64 `json:"far"`
+	FRR       float64 `json:"frr"`
+}
+
+// TrainingMetrics is reported by the processing service with a SUCCEEDED training result.
+type TrainingMetrics struct {
+	Identities         *int              `json:"identities"`
+	Images             *int              `json:"images"`
+	ValidationAccuracy *float64          `json:"validation_accuracy"`
+	Thresholds         []ThresholdMetric `json:"thresholds"`
+	DurationSeconds    *float64          `json:"duration_seconds"`
+	ArtifactSizeBytes  *int64            `json:"artifact_size_bytes"`
+}
+
+type ModelTrainingMetric struct {
+	ModelTrainingID    string          `json:"model_training_id" gorm:"column:model_training_id"`
+	Identities         *int            `json:"identities" gorm:"column:identities"`
+	Images             *int            `json:"images" gorm:"column:images"`
+	ValidationAccuracy *float64        `json:"validation_accuracy" gorm:"column:validation_accuracy"`
+	Thresholds         json.RawMessage `json:"thresholds" gorm:"column:thresholds;type:jsonb"`
+	DurationSeconds    *float64        `json:"duration_seconds" gorm:"column:duration_seconds"`
+	ArtifactSizeBytes  *int64          `json:"artifact_size_bytes" gorm:"column:artifact_size_bytes"`
+	CreatedAt          time.Time       `json:"created_at" gorm:"column:created_at"`
+}
+
+func (ModelTrainingMetric) TableName() string {
+	return "model_training_metric"
+}
+
+// ModelComparison shows two models side by side. Diff holds candidate minus base for every metric both report.
+type ModelComparison struct {
+	Base      *ModelTraining   `json:"base"`
+	Candidate *ModelTraining   `json:"candidate"`
+	Diff      *ModelMetricDiff `json:"diff"`
+}
+
+type ModelMetricDiff struct {
+	Identities         *int              `json:"identities"`
+	Images             *int              `json:"images"`
+	ValidationAccuracy *float64          `json:"validation_accuracy"`
+	Thresholds         []ThresholdMetric `json:"thresholds"`
+	DurationSeconds    *float64          `json:"duration_seconds"`
+	ArtifactSizeBytes  *int64            `json:"artifact_size_bytes"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+const (
+	TrainingTriggerCron      = "cron"
+	TrainingTriggerThreshold = "threshold"
+)
+
+type TrainingSchedule struct {
+	ID              string     `json:"id" gorm:"column:id"`
+	InstitutionID   string     `json:"institution_id" gorm:"column:institution_id"`
+	CronExpr        *string    `json:"cron_expr" gorm:"column:cron_expr"`
+	MinNewImages    *int       `json:"min_new_images" gorm:"column:min_new_images"`
+	MinNewUsers     *int       `json:"min_new_users" gorm:"column:min_new_users"`
+	IsActive        bool       `json:"is_active" gorm:"column:is_active"`
+	LastRunAt       *time.Time `json:"last_run_at" gorm:"column:last_run_at"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at" gorm:"column:last_triggered_at"`
+	LastTrainingID  *string    `json:"last_training_id" gorm:"column:last_training_id"`
+	LastError       *string    `json:"last_error" gorm:"column:last_error"`
+	NextRunAt       *time.Time `json:"next_run_at" gorm:"-"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"column:created_at"`
+	CreatedBy       string     `json:"created_by" gorm:"column:created_by"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"column:updated_at"`
+	UpdatedBy       string     `json:"updated_by" gorm:"column:updated_by"`
+}
+
+func (TrainingSchedule) TableName() string {
+	return "training_schedule"
+}
+
+type RequestTrainingSchedule struct {
+	CronExpr     *string `json:"cron_expr"`
+	MinNewImages *int    `json:"min_new_images"`
+	MinNewUsers  *int    `json:"min_new_users"`
+	IsActive     *bool   `json:"is_active"`
+}
+
+// TrainingScheduleRun records the outcome of one evaluation of a schedule.
+type TrainingScheduleRun struct {
+	InstitutionID string `json:"institution_id"`
+	Trigger       string `json:"trigger"`
+	TrainingID    string `json:"training_id"`
+	Coalesced     bool   `json:"coalesced"`
+	Error         string `json:"error,omitempty"`
+}
@@ -6,12 +6,17 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// TokenTypeAccess marks the JWTs issued as access tokens, so no other token signed with the access secret can
+// stand in for one.
+const TokenTypeAccess = "access"
+
 type JwtCustomClaims struct {
 	UserID        string   `json:"user_id"`
 	Username      string   `json:"username"`
 	RoleIDs       []string `json:"role_ids"`
 	Permissions   []string `json:"permissions"`
 	InstitutionID string   `json:"institution_id"`
+	TokenType     string   `json:"token_type"`
 	jwt.RegisteredClaims
 }
 
@@ -21,6 +26,9 @@ type MetadataUser struct {
 	RoleIDs       []string `json:"role_ids"`
 	Permissions   []string `json:"permissions"`
 	InstitutionID string   `json:"institution_id"`
+	IPAddress     string   `json:"ip_address"`
+	UserAgent     string   `json:"user_agent"`
+	DeviceID      string   `json:"device_id"`
 }
 
 type User struct {
@@ -81,6 +89,18 @@ type RequestLogin struct {
 	InstitutionID string `json:"institution_id"`
 }
 
+// FeatureFaceLogin is the institution feature that enables RequestFaceLogin; it is disabled by default.
+const FeatureFaceLogin = "face_login"
+
+// RequestFaceLogin signs in with a photo instead of a password. Without a username the face is identified among
+// the users of the institution.
+type RequestFaceLogin struct {
+	Username      string `json:"username"`
+	InstitutionID string `json:"institution_id"`
+	ImageData     string `json:"image"`
+	Image         *File  `json:"-"`
+}
+
 type ResponseLogin struct {
 	UserID          string             `json:"user_id"`
 	Username        string             `json:"username"`
@@ -88,6 +108,8 @@ type ResponseLogin struct {
 	Shortname       string             `json:"shortname"`
 	RoleIDs         []string           `json:"role_ids"`
 	Token           string             `json:"token"`
+	ExpiresAt       int64              `json:"expires_at,omitempty"`
+	RefreshToken    string             `json:"refresh_token,omitempty"`
 	InstitutionID   string             `json:"institution_id"`
 	InstitutionName string             `json:"institution_name"`
 	MenuMapping     []*MenuRoleMapping `json:"menu_mapping"`
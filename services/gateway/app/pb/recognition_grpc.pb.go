@@ -0,0 +1,203 @@
+// Recognition API for edge devices. It mirrors the REST identify and verify routes; see section 3.17 of
+// docs/api_frontend_guide.md. Regenerate app/pb after changing this file.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: recognition.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RecognitionService_Identify_FullMethodName       = "/recognition.v1.RecognitionService/Identify"
+	RecognitionService_Verify_FullMethodName         = "/recognition.v1.RecognitionService/Verify"
+	RecognitionService_StreamIdentify_FullMethodName = "/recognition.v1.RecognitionService/StreamIdentify"
+)
+
+// RecognitionServiceClient is the client API for RecognitionService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RecognitionServiceClient interface {
+	// Identify finds the user of the probe among the institution's enrolled users.
+	Identify(ctx context.Context, in *IdentifyRequest, opts ...grpc.CallOption) (*RecognitionResult, error)
+	// Verify checks the probe against a claimed user.
+	Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*RecognitionResult, error)
+	// StreamIdentify identifies video frames. Every frame is answered, in order; frames arriving faster than
+	// grpc.maxFramesPerSecond are answered as skipped without being recognized.
+	StreamIdentify(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Frame, FrameResult], error)
+}
+
+type recognitionServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRecognitionServiceClient(cc grpc.ClientConnInterface) RecognitionServiceClient {
+	return &recognitionServiceClient{cc}
+}
+
+func (c *recognitionServiceClient) Identify(ctx context.Context, in *IdentifyRequest, opts ...grpc.CallOption) (*RecognitionResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecognitionResult)
+	err := c.cc.Invoke(ctx, RecognitionService_Identify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recognitionServiceClient) Verify(ctx context.Context, in *VerifyRequest, opts ...grpc.CallOption) (*RecognitionResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecognitionResult)
+	err := c.cc.Invoke(ctx, RecognitionService_Verify_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recognitionServiceClient) StreamIdentify(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Frame, FrameResult], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RecognitionService_ServiceDesc.Streams[0], RecognitionService_StreamIdentify_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Frame, FrameResult]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RecognitionService_StreamIdentifyClient = grpc.BidiStreamingClient[Frame, FrameResult]
+
+// RecognitionServiceServer is the server API for RecognitionService service.
+// All implementations must embed UnimplementedRecognitionServiceServer
+// for forward compatibility.
+type RecognitionServiceServer interface {
+	// Identify finds the user of the probe among the institution's enrolled users.
+	Identify(context.Context, *IdentifyRequest) (*RecognitionResult, error)
+	// Verify checks the probe against a claimed user.
+	Verify(context.Context, *VerifyRequest) (*RecognitionResult, error)
+	// StreamIdentify identifies video frames. Every frame is answered, in order; frames arriving faster than
+	// grpc.maxFramesPerSecond are answered as skipped without being recognized.
+	StreamIdentify(grpc.BidiStreamingServer[Frame, FrameResult]) error
+	mustEmbedUnimplementedRecognitionServiceServer()
+}
+
+// UnimplementedRecognitionServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRecognitionServiceServer struct{}
+
+func (UnimplementedRecognitionServiceServer) Identify(context.Context, *IdentifyRequest) (*RecognitionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Identify not implemented")
+}
+func (UnimplementedRecognitionServiceServer) Verify(context.Context, *VerifyRequest) (*RecognitionResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Verify not implemented")
+}
+func (UnimplementedRecognitionServiceServer) StreamIdentify(grpc.BidiStreamingServer[Frame, FrameResult]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamIdentify not implemented")
+}
+func (UnimplementedRecognitionServiceServer) mustEmbedUnimplementedRecognitionServiceServer() {}
+func (UnimplementedRecognitionServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafeRecognitionServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RecognitionServiceServer will
+// result in compilation errors.
+type UnsafeRecognitionServiceServer interface {
+	mustEmbedUnimplementedRecognitionServiceServer()
+}
+
+func RegisterRecognitionServiceServer(s grpc.ServiceRegistrar, srv RecognitionServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRecognitionServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RecognitionService_ServiceDesc, srv)
+}
+
+func _RecognitionService_Identify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IdentifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecognitionServiceServer).Identify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RecognitionService_Identify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecognitionServiceServer).Identify(ctx, req.(*IdentifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RecognitionService_Verify_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecognitionServiceServer).Verify(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RecognitionService_Verify_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecognitionServiceServer).Verify(ctx, req.(*VerifyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RecognitionService_StreamIdentify_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RecognitionServiceServer).StreamIdentify(&grpc.GenericServerStream[Frame, FrameResult]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RecognitionService_StreamIdentifyServer = grpc.BidiStreamingServer[Frame, FrameResult]
+
+// RecognitionService_ServiceDesc is the grpc.ServiceDesc for RecognitionService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RecognitionService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "recognition.v1.RecognitionService",
+	HandlerType: (*RecognitionServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Identify",
+			Handler:    _RecognitionService_Identify_Handler,
+		},
+		{
+			MethodName: "Verify",
+			Handler:    _RecognitionService_Verify_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamIdentify",
+			Handler:       _RecognitionService_StreamIdentify_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "recognition.proto",
+}
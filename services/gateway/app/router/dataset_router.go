@@ -1,13 +1,18 @@
 package router
 
-import "github.com/labstack/echo/v4"
+import (
+	"face-recognition-svc/gateway/app/config"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
 
 func InitDatasetRoute(prefix string, e *echo.Group) {
 	route := e.Group(prefix)
 	service := factory.Service.dataset
 
 	route.GET("", service.GetDatasetList)
-	route.POST("", service.UploadUserDataset)
+	route.POST("", service.UploadUserDataset, middleware.BodyLimit(config.GetConfig().Dataset.UploadBodyLimit()))
 	route.DELETE("/:id", service.DeleteDataset)
 
 	route.POST("/train-model/:id", service.TrainModel)
@@ -15,5 +20,15 @@ func InitDatasetRoute(prefix string, e *echo.Group) {
 
 	route.POST("/model-training-history", service.GetModelTrainingHistory)
 
+	route.GET("/training/:id", service.GetTraining)
+	route.POST("/training/:id/cancel", service.CancelTraining)
+
+	route.GET("/dead-letter", service.GetDeadLetters)
+	route.POST("/dead-letter/:id/replay", service.ReplayDeadLetter)
+	route.DELETE("/dead-letter/:id", service.DiscardDeadLetter)
+
+	route.GET("/reconcile", service.GetLastReconcileReport)
+	route.POST("/reconcile", service.ReconcileDatasets)
+
 	route.GET("/:institution-id/:id", service.GetDatasetsByUsername)
 }
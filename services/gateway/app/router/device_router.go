@@ -0,0 +1,19 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitDeviceRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.device
+
+	route.GET("", service.GetDevices)
+	route.POST("", service.CreateDevice)
+	route.GET("/:id", service.GetDevice)
+	route.PUT("/:id", service.UpdateDevice)
+	route.DELETE("/:id", service.DeleteDevice)
+	route.POST("/:id/rotate-key", service.RotateDeviceKey)
+
+	factory.Middleware.Device.Allow(
+		route.POST("/heartbeat", service.Heartbeat),
+	)
+}
@@ -0,0 +1,16 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitEnrollmentRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.enrollment
+
+	route.GET("", service.GetSessions)
+	route.POST("", service.CreateSession)
+	route.GET("/:id", service.GetSession)
+	route.DELETE("/:id", service.CancelSession)
+	route.POST("/:id/capture", service.AddCapture)
+
+	route.GET("/readiness/:institution-id", service.GetReadiness)
+}
@@ -3,13 +3,18 @@ package router
 import (
 	"face-recognition-svc/gateway/app/client"
 	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/connection"
 	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/pb"
 	"face-recognition-svc/gateway/app/service"
 	"face-recognition-svc/gateway/app/utils"
+	"face-recognition-svc/gateway/app/worker"
+	"time"
 
 	"github.com/aws/aws-sdk-go/service/s3"
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
 
 	"gorm.io/gorm"
 )
@@ -22,6 +27,19 @@ type ServiceFactory struct {
 	institution service.InterfaceInstitutionService
 	permission  service.InterfacePermissionService
 	feature     service.InterfaceFeatureService
+	enrollment  service.InterfaceEnrollmentService
+	model       service.InterfaceModelService
+	outbox      service.InterfaceOutboxService
+	health      service.InterfaceHealthService
+
+	trainingSchedule  service.InterfaceTrainingScheduleService
+	trainingEvent     service.InterfaceTrainingEventService
+	recognition       service.InterfaceRecognitionService
+	recognitionPolicy service.InterfaceRecognitionPolicyService
+	recognitionReview service.InterfaceRecognitionReviewService
+	faceEmbedding     service.InterfaceFaceEmbeddingService
+	device            service.InterfaceDeviceService
+	recognitionGrpc   pb.RecognitionServiceServer
 }
 
 type ControllerFactory struct {
@@ -32,6 +50,17 @@ type ControllerFactory struct {
 	institution controller.InterfaceInstitutionController
 	permission  controller.InterfacePermissionController
 	feature     controller.InterfaceFeatureController
+	enrollment  controller.InterfaceEnrollmentController
+	model       controller.InterfaceModelController
+	outbox      controller.InterfaceOutboxController
+
+	trainingSchedule  controller.InterfaceTrainingScheduleController
+	trainingEvent     controller.InterfaceTrainingEventController
+	recognition       controller.InterfaceRecognitionController
+	recognitionPolicy controller.InterfaceRecognitionPolicyController
+	recognitionReview controller.InterfaceRecognitionReviewController
+	faceEmbedding     controller.InterfaceFaceEmbeddingController
+	device            controller.InterfaceDeviceController
 }
 
 type ClientFactory struct {
@@ -43,10 +72,38 @@ type ClientFactory struct {
 	dataset     client.InterfaceDatasetClient
 	param       client.InterfaceParamClient
 	institution client.InterfaceInstitutionClient
+	image       client.InterfaceImageClient
+	enrollment  client.InterfaceEnrollmentClient
+	model       client.InterfaceModelClient
+	lock        client.InterfaceLockClient
+	outbox      client.InterfaceOutboxClient
+	deadLetter  client.InterfaceDeadLetterClient
+
+	trainingSchedule  client.InterfaceTrainingScheduleClient
+	trainingMetric    client.InterfaceTrainingMetricClient
+	trainingEvent     client.InterfaceTrainingEventClient
+	recognition       client.InterfaceRecognitionClient
+	recognitionEvent  client.InterfaceRecognitionEventClient
+	faceDetector      client.InterfaceFaceDetectorClient
+	recognitionPolicy client.InterfaceRecognitionPolicyClient
+	recognitionReview client.InterfaceRecognitionReviewClient
+	audit             client.InterfaceAuditClient
+	faceEmbedding     client.InterfaceFaceEmbeddingClient
+	device            client.InterfaceDeviceClient
+	refreshToken      client.InterfaceRefreshTokenClient
 }
 
 type MiddlewareFactory struct {
-	Auth utils.InterfaceAuthMiddleware
+	Auth   utils.InterfaceAuthMiddleware
+	Device utils.InterfaceDeviceMiddleware
+	Grpc   utils.InterfaceGrpcAuth
+}
+
+type WorkerFactory struct {
+	Scheduler          *worker.Scheduler
+	TrainingResult     *worker.Consumer
+	TrainingDeadLetter *worker.Consumer
+	TrainingEmbedding  *worker.Consumer
 }
 
 type Factory struct {
@@ -54,29 +111,64 @@ type Factory struct {
 	Controller ControllerFactory
 	Client     ClientFactory
 	Middleware MiddlewareFactory
+	Worker     WorkerFactory
 }
 
 var factory *Factory
 
-func InitFactory(cfg *config.Config, db *gorm.DB, s3 *s3.S3, redis *redis.Client, mq *amqp.Channel) {
+func InitFactory(cfg *config.Config, db *gorm.DB, s3 *s3.S3, redis *redis.Client, bus connection.InterfaceMessageBus) {
 	client := ClientFactory{
 		user:        client.NewUserClient(db, cfg),
 		storage:     client.NewStorageClient(s3, db),
 		role:        client.NewRoleClient(db),
 		permission:  client.NewPermissionClient(db),
 		feature:     client.NewFeatureClient(db),
-		dataset:     client.NewDatasetClient(db, cfg, mq),
+		dataset:     client.NewDatasetClient(db, cfg, bus),
 		param:       client.NewParamClient(db),
 		institution: client.NewInstitutionClient(db),
+		image:       client.NewImageClient(cfg),
+		enrollment:  client.NewEnrollmentClient(db),
+		model:       client.NewModelClient(db, bus),
+		lock:        client.NewLockClient(redis),
+		outbox:      client.NewOutboxClient(db),
+		deadLetter:  client.NewDeadLetterClient(db),
+
+		trainingSchedule:  client.NewTrainingScheduleClient(db),
+		trainingMetric:    client.NewTrainingMetricClient(db),
+		trainingEvent:     client.NewTrainingEventClient(redis),
+		recognition:       client.NewRecognitionClient(bus, cfg),
+		recognitionEvent:  client.NewRecognitionEventClient(db),
+		faceDetector:      client.NewFaceDetectorClient(cfg),
+		recognitionPolicy: client.NewRecognitionPolicyClient(db),
+		recognitionReview: client.NewRecognitionReviewClient(db),
+		audit:             client.NewAuditClient(db),
+		faceEmbedding:     client.NewFaceEmbeddingClient(db),
+		device:            client.NewDeviceClient(db),
+		refreshToken:      client.NewRefreshTokenClient(db),
 	}
+	recognitionPolicyController := controller.NewRecognitionPolicyController(redis, client.recognitionPolicy, client.institution, client.role, cfg)
+	datasetController := controller.NewDatasetController(client.storage, db, client.user, cfg, client.dataset, redis, client.image, client.enrollment, client.lock, client.outbox, client.deadLetter, client.trainingMetric, client.trainingEvent, client.institution, client.role, client.model)
+	recognitionReviewController := controller.NewRecognitionReviewController(client.recognitionReview, client.storage, client.audit, client.role, datasetController, db, cfg)
+	recognitionController := controller.NewRecognitionController(client.recognition, client.recognitionEvent, client.model, client.storage, client.role, client.faceDetector, recognitionPolicyController, recognitionReviewController, cfg)
 	controller := ControllerFactory{
-		user:        controller.NewUserController(client.user, client.role, client.param, client.storage, cfg, redis),
-		dataset:     controller.NewDatasetController(client.storage, db, client.user, cfg, client.dataset),
+		user:        controller.NewUserController(client.user, client.role, client.param, client.storage, client.feature, client.audit, client.refreshToken, recognitionController, db, cfg, redis),
+		dataset:     datasetController,
 		role:        controller.NewRoleController(client.role),
 		permission:  controller.NewPermissionController(client.permission),
 		feature:     controller.NewFeatureController(client.feature),
 		param:       controller.NewParamController(redis, client.param),
-		institution: controller.NewInstitutionController(client.institution),
+		institution: controller.NewInstitutionController(client.institution, cfg),
+		enrollment:  controller.NewEnrollmentController(client.enrollment, client.user, client.role, datasetController, db),
+		model:       controller.NewModelController(client.model, client.trainingMetric, client.role, cfg, db),
+		outbox:      controller.NewOutboxController(client.outbox, client.dataset, client.lock, client.trainingEvent, client.role, cfg, db),
+
+		trainingSchedule:  controller.NewTrainingScheduleController(client.trainingSchedule, client.dataset, client.storage, client.institution, client.role, datasetController, cfg),
+		trainingEvent:     controller.NewTrainingEventController(client.trainingEvent, client.dataset, client.role),
+		recognition:       recognitionController,
+		recognitionPolicy: recognitionPolicyController,
+		recognitionReview: recognitionReviewController,
+		faceEmbedding:     controller.NewFaceEmbeddingController(client.faceEmbedding, client.dataset, client.model, client.role, client.audit, recognitionPolicyController, db),
+		device:            controller.NewDeviceController(client.device, client.institution, client.role, client.audit, db, cfg),
 	}
 	service := ServiceFactory{
 		user:        service.NewUserService(controller.user),
@@ -86,15 +178,85 @@ func InitFactory(cfg *config.Config, db *gorm.DB, s3 *s3.S3, redis *redis.Client
 		feature:     service.NewFeatureService(controller.feature),
 		param:       service.NewParamService(controller.param),
 		institution: service.NewInstitutionService(controller.institution),
+		enrollment:  service.NewEnrollmentService(controller.enrollment),
+		model:       service.NewModelService(controller.model),
+		outbox:      service.NewOutboxService(controller.outbox),
+		health:      service.NewHealthService(bus),
+
+		trainingSchedule:  service.NewTrainingScheduleService(controller.trainingSchedule),
+		trainingEvent:     service.NewTrainingEventService(controller.trainingEvent),
+		recognition:       service.NewRecognitionService(controller.recognition),
+		recognitionPolicy: service.NewRecognitionPolicyService(controller.recognitionPolicy),
+		recognitionReview: service.NewRecognitionReviewService(controller.recognitionReview),
+		faceEmbedding:     service.NewFaceEmbeddingService(controller.faceEmbedding),
+		device:            service.NewDeviceService(controller.device),
+		recognitionGrpc:   service.NewRecognitionGrpcService(controller.recognition, cfg),
 	}
+	authMiddleware := utils.NewAuthMiddleware(db, redis)
+	deviceMiddleware := utils.NewDeviceMiddleware(db)
 	middleware := MiddlewareFactory{
-		Auth: utils.NewAuthMiddleware(db, redis),
+		Auth:   authMiddleware,
+		Device: deviceMiddleware,
+		Grpc:   utils.NewGrpcAuth(cfg.Auth.AccessSecret, cfg.Grpc.RequiredPermission(), authMiddleware, deviceMiddleware),
+	}
+	scheduler := worker.NewScheduler()
+	if cfg.Job.DatasetReconcile.Enabled {
+		interval, err := time.ParseDuration(cfg.Job.DatasetReconcile.Interval)
+		if err != nil {
+			log.Warn().Err(err).Str("interval", cfg.Job.DatasetReconcile.Interval).Msg("Invalid dataset reconcile interval, using 24h")
+			interval = 24 * time.Hour
+		}
+		scheduler.Every("dataset-reconcile", interval, worker.NewDatasetReconcileTask(controller.dataset, cfg.Job.DatasetReconcile.Repair))
+	}
+	if cfg.Job.OutboxRelay.Enabled {
+		interval, err := time.ParseDuration(cfg.Job.OutboxRelay.Interval)
+		if err != nil {
+			log.Warn().Err(err).Str("interval", cfg.Job.OutboxRelay.Interval).Msg("Invalid outbox relay interval, using 2s")
+			interval = 2 * time.Second
+		}
+		scheduler.Every("outbox-relay", interval, worker.NewOutboxRelayTask(controller.outbox))
+	}
+	if cfg.Job.TrainingSchedule.Enabled {
+		interval, err := time.ParseDuration(cfg.Job.TrainingSchedule.Interval)
+		if err != nil {
+			log.Warn().Err(err).Str("interval", cfg.Job.TrainingSchedule.Interval).Msg("Invalid training schedule interval, using 1m")
+			interval = time.Minute
+		}
+		leaderTTL, err := time.ParseDuration(cfg.Job.TrainingSchedule.LeaderTTL)
+		if err != nil || leaderTTL <= interval {
+			log.Warn().Str("leaderTTL", cfg.Job.TrainingSchedule.LeaderTTL).Msg("Invalid training schedule leader TTL, using twice the interval")
+			leaderTTL = 2 * interval
+		}
+		leader := worker.NewLeader(client.lock, worker.TrainingScheduleLeaderKey, leaderTTL)
+		scheduler.Every("training-schedule", interval, worker.NewTrainingScheduleTask(leader, controller.trainingSchedule))
+	}
+	if cfg.Job.TrainingReaper.Enabled {
+		interval, err := time.ParseDuration(cfg.Job.TrainingReaper.Interval)
+		if err != nil {
+			log.Warn().Err(err).Str("interval", cfg.Job.TrainingReaper.Interval).Msg("Invalid training reaper interval, using 10m")
+			interval = 10 * time.Minute
+		}
+		scheduler.Every("training-reaper", interval, worker.NewTrainingReaperTask(controller.dataset))
+	}
+	if cfg.Job.RecognitionReview.Enabled {
+		interval, err := time.ParseDuration(cfg.Job.RecognitionReview.Interval)
+		if err != nil {
+			log.Warn().Err(err).Str("interval", cfg.Job.RecognitionReview.Interval).Msg("Invalid recognition review interval, using 1h")
+			interval = time.Hour
+		}
+		scheduler.Every("recognition-review-retention", interval, worker.NewRecognitionReviewRetentionTask(controller.recognitionReview))
 	}
 	factory = &Factory{
 		Service:    service,
 		Controller: controller,
 		Client:     client,
 		Middleware: middleware,
+		Worker: WorkerFactory{
+			Scheduler:          scheduler,
+			TrainingResult:     worker.NewConsumer(bus, worker.TrainingResultQueue, worker.NewTrainingResultHandler(controller.dataset)),
+			TrainingDeadLetter: worker.NewConsumer(bus, model.TrainModelDeadLetterQueue, worker.NewTrainingDeadLetterHandler(controller.dataset)),
+			TrainingEmbedding:  worker.NewConsumer(bus, worker.TrainingEmbeddingQueue, worker.NewTrainingEmbeddingHandler(controller.faceEmbedding)),
+		},
 	}
 }
 
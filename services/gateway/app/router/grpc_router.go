@@ -0,0 +1,41 @@
+package router
+
+import (
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/pb"
+	"face-recognition-svc/gateway/app/utils"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// NewGrpcServer builds the gRPC server. It refuses to serve plaintext unless grpc.insecure is set.
+func NewGrpcServer(cfg *config.Config) (*grpc.Server, error) {
+	options := []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(cfg.Grpc.MessageLimit()),
+		// Recovery comes first so it also covers a panic in authentication.
+		grpc.ChainUnaryInterceptor(utils.GrpcRecoveryUnaryInterceptor(), factory.Middleware.Grpc.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(utils.GrpcRecoveryStreamInterceptor(), factory.Middleware.Grpc.StreamInterceptor()),
+	}
+
+	switch {
+	case cfg.Grpc.CertFile != "" || cfg.Grpc.KeyFile != "":
+		creds, err := credentials.NewServerTLSFromFile(cfg.Grpc.CertFile, cfg.Grpc.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.Creds(creds))
+	case cfg.Grpc.Insecure:
+		log.Warn().Msg("gRPC server runs without TLS")
+	default:
+		return nil, errors.New("grpc.certFile and grpc.keyFile are required unless grpc.insecure is set")
+	}
+
+	server := grpc.NewServer(options...)
+
+	pb.RegisterRecognitionServiceServer(server, factory.Service.recognitionGrpc)
+
+	return server, nil
+}
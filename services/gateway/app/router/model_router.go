@@ -0,0 +1,16 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitModelRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.model
+
+	route.GET("/:institution-id", service.GetModels)
+	route.GET("/:institution-id/active", service.GetActiveModel)
+	route.GET("/:institution-id/activation", service.GetActivationHistory)
+	route.POST("/:institution-id/rollback", service.RollbackModel)
+
+	route.POST("/activate/:id", service.ActivateModel)
+	route.GET("/compare", service.CompareModels)
+}
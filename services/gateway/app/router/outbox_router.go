@@ -0,0 +1,10 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitOutboxRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.outbox
+
+	route.GET("/metrics", service.GetMetrics)
+}
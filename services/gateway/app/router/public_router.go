@@ -19,6 +19,10 @@ func InitPublicRoute(prefix string, e *echo.Group) {
 		})
 	})
 
+	route.GET("/health", factory.Service.health.GetHealth)
+
 	route.POST("/register", service.CreateNewUser)
 	route.POST("/login", service.Login)
+	route.POST("/login/face", service.FaceLogin)
+	route.POST("/token/refresh", service.RefreshToken)
 }
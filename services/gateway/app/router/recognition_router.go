@@ -0,0 +1,36 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitRecognitionRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.recognition
+
+	factory.Middleware.Device.Allow(
+		route.POST("/identify", service.Identify),
+		route.POST("/verify", service.Verify),
+		route.POST("/identify/group", service.IdentifyGroup),
+	)
+
+	route.GET("/event", service.GetEvents)
+	route.GET("/event/summary", service.GetSummary)
+	route.GET("/event/hourly", service.GetHourlyStats)
+	route.GET("/event/:id", service.GetEvent)
+
+	policy := factory.Service.recognitionPolicy
+	route.GET("/policy/:institution-id", policy.GetPolicy)
+	route.PUT("/policy/:institution-id", policy.SavePolicy)
+	route.DELETE("/policy/:institution-id", policy.DeletePolicy)
+
+	review := factory.Service.recognitionReview
+	route.GET("/review", review.GetReviews)
+	route.GET("/review/:id", review.GetReview)
+	route.GET("/review/:id/image", review.GetReviewImage)
+	route.POST("/review/:id/label", review.LabelReview)
+	route.POST("/review/:id/discard", review.DiscardReview)
+	route.POST("/review/:id/impostor", review.FlagImpostor)
+
+	embedding := factory.Service.faceEmbedding
+	route.POST("/embedding/search", embedding.SearchEmbeddings)
+	route.POST("/embedding/cleanup", embedding.CleanupEmbeddings)
+}
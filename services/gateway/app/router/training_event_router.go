@@ -0,0 +1,11 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitTrainingEventRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.trainingEvent
+
+	route.GET("/:id", service.StreamTraining)
+	route.GET("/institution/:institution-id", service.StreamInstitution)
+}
@@ -0,0 +1,13 @@
+package router
+
+import "github.com/labstack/echo/v4"
+
+func InitTrainingScheduleRoute(prefix string, e *echo.Group) {
+	route := e.Group(prefix)
+	service := factory.Service.trainingSchedule
+
+	route.GET("", service.GetSchedules)
+	route.GET("/:institution-id", service.GetSchedule)
+	route.PUT("/:institution-id", service.SaveSchedule)
+	route.DELETE("/:institution-id", service.DeleteSchedule)
+}
@@ -1,14 +1,14 @@
 package service
 
 import (
-	"bytes"
 	"errors"
 	"face-recognition-svc/gateway/app/controller"
 	"face-recognition-svc/gateway/app/model"
 	"face-recognition-svc/gateway/app/utils"
 	"fmt"
-	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -21,6 +21,13 @@ type InterfaceDatasetService interface {
 	GetLastTrainModel(e echo.Context) error
 	GetModelTrainingHistory(e echo.Context) error
 	GetDatasetsByUsername(e echo.Context) error
+	ReconcileDatasets(e echo.Context) error
+	GetLastReconcileReport(e echo.Context) error
+	GetTraining(e echo.Context) error
+	CancelTraining(e echo.Context) error
+	GetDeadLetters(e echo.Context) error
+	ReplayDeadLetter(e echo.Context) error
+	DiscardDeadLetter(e echo.Context) error
 }
 
 type DatasetService struct {
@@ -37,41 +44,20 @@ func (s *DatasetService) UploadUserDataset(e echo.Context) error {
 	ctx, span := utils.StartSpan(e, "UploadUserDataset")
 	defer span.Finish()
 
-	form, err := e.MultipartForm()
+	var request *model.Dataset
+	var err error
+
+	if strings.HasPrefix(e.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		request, err = s.parseJSONDataset(e)
+	} else {
+		request, err = s.parseMultipartDataset(e)
+	}
 	if err != nil {
 		utils.LogEventError(span, err)
-		return err
-	}
-
-	files := form.File["file"]
-
-	utils.LogEvent(span, "Request", "")
-
-	var attach []*model.File
-	for _, file := range files {
-		// Open the file
-		src, err := file.Open()
-		if err != nil {
-			return err
-		}
-		defer src.Close()
-		var buffer bytes.Buffer
-		_, err = io.Copy(&buffer, src)
-		if err != nil {
-			utils.LogEventError(span, err)
-			return utils.LogError(e, err, nil)
-		}
-		imageBytes := buffer.Bytes()
-		attach = append(attach, &model.File{
-			FileName:    file.Filename,
-			BytesObject: imageBytes,
-		})
+		return utils.LogError(e, err, nil)
 	}
 
-	request := &model.Dataset{
-		Username: e.FormValue("username"),
-		File:     attach,
-	}
+	utils.LogEvent(span, "Request", request.Username)
 
 	err = s.uc.UploadUserDataset(ctx, request)
 	if err != nil {
@@ -88,6 +74,45 @@ func (s *DatasetService) UploadUserDataset(e echo.Context) error {
 	})
 }
 
+func (s *DatasetService) parseMultipartDataset(e echo.Context) (*model.Dataset, error) {
+	attach, err := utils.ReadFormFiles(e, "file")
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Dataset{
+		Username: e.FormValue("username"),
+		File:     attach,
+	}, nil
+}
+
+func (s *DatasetService) parseJSONDataset(e echo.Context) (*model.Dataset, error) {
+	var request model.RequestUploadDataset
+
+	if err := e.Bind(&request); err != nil {
+		return nil, err
+	}
+
+	if request.Username == "" {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("username shouldn't be empty"))
+	}
+
+	var attach []*model.File
+	for _, image := range request.Images {
+		file, err := utils.DecodeBase64Image(image.FileName, image.Data)
+		if err != nil {
+			return nil, err
+		}
+		attach = append(attach, file)
+	}
+
+	return &model.Dataset{
+		Username:   request.Username,
+		File:       attach,
+		SourceURLs: request.URLs,
+	}, nil
+}
+
 func (s *DatasetService) GetDatasetList(e echo.Context) error {
 	ctx, span := utils.StartSpan(e, "GetDatasetList")
 	defer span.Finish()
@@ -143,7 +168,9 @@ func (s *DatasetService) TrainModel(e echo.Context) error {
 
 	utils.LogEvent(span, "Request", institutionID)
 
-	res, err := s.uc.TrainModel(ctx, institutionID)
+	coalesce, _ := strconv.ParseBool(e.QueryParam("coalesce"))
+
+	res, err := s.uc.TrainModel(ctx, institutionID, e.QueryParam("mode"), coalesce)
 	if err != nil {
 		utils.LogEventError(span, err)
 		return utils.LogError(e, err, nil)
@@ -248,3 +275,162 @@ func (s *DatasetService) GetDatasetsByUsername(e echo.Context) error {
 		Data:    res,
 	})
 }
+
+func (s *DatasetService) ReconcileDatasets(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ReconcileDatasets")
+	defer span.Finish()
+
+	var request model.RequestReconcileDataset
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.ReconcileDatasets(ctx, request.Repair)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Reconcile Datasets",
+		Data:    res,
+	})
+}
+
+func (s *DatasetService) GetLastReconcileReport(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetLastReconcileReport")
+	defer span.Finish()
+
+	res, err := s.uc.GetLastReconcileReport(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Last Reconcile Report",
+		Data:    res,
+	})
+}
+
+func (s *DatasetService) GetTraining(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetTraining")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.GetTraining(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Training",
+		Data:    res,
+	})
+}
+
+func (s *DatasetService) CancelTraining(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CancelTraining")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.CancelTraining(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Cancel Training",
+		Data:    res,
+	})
+}
+
+func (s *DatasetService) GetDeadLetters(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetDeadLetters")
+	defer span.Finish()
+
+	institutionID := e.QueryParam("institution_id")
+	status := e.QueryParam("status")
+
+	utils.LogEvent(span, "Request", map[string]string{"institution_id": institutionID, "status": status})
+
+	res, err := s.uc.GetDeadLetters(ctx, institutionID, status)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Dead Letters",
+		Data:    res,
+	})
+}
+
+func (s *DatasetService) ReplayDeadLetter(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ReplayDeadLetter")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.ReplayDeadLetter(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Replay Dead Letter",
+		Data:    res,
+	})
+}
+
+func (s *DatasetService) DiscardDeadLetter(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DiscardDeadLetter")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DiscardDeadLetter(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Discard Dead Letter",
+		Data:    nil,
+	})
+}
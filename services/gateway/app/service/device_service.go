@@ -0,0 +1,200 @@
+package service
+
+import (
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceDeviceService interface {
+	GetDevices(e echo.Context) error
+	GetDevice(e echo.Context) error
+	CreateDevice(e echo.Context) error
+	UpdateDevice(e echo.Context) error
+	RotateDeviceKey(e echo.Context) error
+	DeleteDevice(e echo.Context) error
+	Heartbeat(e echo.Context) error
+}
+
+type DeviceService struct {
+	uc controller.InterfaceDeviceController
+}
+
+func NewDeviceService(uc controller.InterfaceDeviceController) InterfaceDeviceService {
+	return &DeviceService{uc: uc}
+}
+
+func (s *DeviceService) GetDevices(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetDevices")
+	defer span.Finish()
+
+	filter := &model.FilterDevice{
+		InstitutionID: e.QueryParam("institution_id"),
+		Status:        strings.ToUpper(e.QueryParam("status")),
+	}
+
+	pagination := utils.ParsePaginationFromQuery(e)
+
+	res, pagination, err := s.uc.GetDevices(ctx, filter, pagination)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:       200,
+		Message:    "Success Get Devices",
+		Data:       res,
+		Pagination: pagination,
+	})
+}
+
+func (s *DeviceService) GetDevice(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetDevice")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.GetDevice(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Device",
+		Data:    res,
+	})
+}
+
+func (s *DeviceService) CreateDevice(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CreateDevice")
+	defer span.Finish()
+
+	var request model.RequestDevice
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.CreateDevice(ctx, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Create Device",
+		Data:    res,
+	})
+}
+
+func (s *DeviceService) UpdateDevice(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "UpdateDevice")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	var request model.RequestDevice
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.UpdateDevice(ctx, id, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Update Device",
+		Data:    res,
+	})
+}
+
+func (s *DeviceService) RotateDeviceKey(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RotateDeviceKey")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.RotateDeviceKey(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Rotate Device Key",
+		Data:    res,
+	})
+}
+
+func (s *DeviceService) DeleteDevice(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteDevice")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	err := s.uc.DeleteDevice(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Device",
+	})
+}
+
+func (s *DeviceService) Heartbeat(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeviceHeartbeat")
+	defer span.Finish()
+
+	var request model.RequestDeviceHeartbeat
+
+	if e.Request().ContentLength != 0 {
+		if err := e.Bind(&request); err != nil {
+			utils.LogEventError(span, err)
+			return utils.LogError(e, err, nil)
+		}
+	}
+
+	res, err := s.uc.Heartbeat(ctx, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Device Heartbeat",
+		Data:    res,
+	})
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceEnrollmentService interface {
+	CreateSession(e echo.Context) error
+	GetSession(e echo.Context) error
+	GetSessions(e echo.Context) error
+	AddCapture(e echo.Context) error
+	CancelSession(e echo.Context) error
+	GetReadiness(e echo.Context) error
+}
+
+type EnrollmentService struct {
+	uc controller.InterfaceEnrollmentController
+}
+
+func NewEnrollmentService(uc controller.InterfaceEnrollmentController) InterfaceEnrollmentService {
+	return &EnrollmentService{uc: uc}
+}
+
+func (s *EnrollmentService) CreateSession(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CreateEnrollmentSession")
+	defer span.Finish()
+
+	var request model.RequestCreateEnrollment
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.CreateSession(ctx, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Create Enrollment Session",
+		Data:    res,
+	})
+}
+
+func (s *EnrollmentService) GetSession(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetEnrollmentSession")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	res, err := s.uc.GetSession(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Enrollment Session",
+		Data:    res,
+	})
+}
+
+func (s *EnrollmentService) GetSessions(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetEnrollmentSessions")
+	defer span.Finish()
+
+	status := e.QueryParam("status")
+
+	utils.LogEvent(span, "Request", status)
+
+	res, err := s.uc.GetSessions(ctx, status)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Enrollment Sessions",
+		Data:    res,
+	})
+}
+
+func (s *EnrollmentService) AddCapture(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "AddEnrollmentCapture")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	files, err := utils.ReadFormFiles(e, "file")
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	glassesOff, _ := strconv.ParseBool(e.FormValue("glasses_off"))
+
+	request := &model.RequestEnrollmentCapture{
+		SessionID:  id,
+		Pose:       e.FormValue("pose"),
+		GlassesOff: glassesOff,
+		File:       files,
+	}
+
+	res, err := s.uc.AddCapture(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Add Enrollment Capture",
+		Data:    res,
+	})
+}
+
+func (s *EnrollmentService) CancelSession(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CancelEnrollmentSession")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	err := s.uc.CancelSession(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Cancel Enrollment Session",
+		Data:    nil,
+	})
+}
+
+func (s *EnrollmentService) GetReadiness(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetEnrollmentReadiness")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	if institutionID == "" {
+		utils.LogEventError(span, errors.New("institutionID shouldn't be empty"))
+		return utils.LogError(e, errors.New("institutionID shouldn't be empty"), nil)
+	}
+
+	res, err := s.uc.GetReadiness(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Enrollment Readiness",
+		Data:    res,
+	})
+}
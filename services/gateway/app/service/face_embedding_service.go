@@ -0,0 +1,77 @@
+package service
+
+import (
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceFaceEmbeddingService interface {
+	SearchEmbeddings(e echo.Context) error
+	CleanupEmbeddings(e echo.Context) error
+}
+
+type FaceEmbeddingService struct {
+	uc controller.InterfaceFaceEmbeddingController
+}
+
+func NewFaceEmbeddingService(uc controller.InterfaceFaceEmbeddingController) InterfaceFaceEmbeddingService {
+	return &FaceEmbeddingService{uc: uc}
+}
+
+func (s *FaceEmbeddingService) SearchEmbeddings(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "SearchFaceEmbeddings")
+	defer span.Finish()
+
+	var request model.RequestEmbeddingSearch
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	res, err := s.uc.SearchEmbeddings(ctx, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Search Face Embeddings",
+		Data:    res,
+	})
+}
+
+func (s *FaceEmbeddingService) CleanupEmbeddings(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CleanupFaceEmbeddings")
+	defer span.Finish()
+
+	var request model.RequestEmbeddingCleanup
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.CleanupEmbeddings(ctx, request.Repair)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Cleanup Face Embeddings",
+		Data:    res,
+	})
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/model"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceHealthService interface {
+	GetHealth(e echo.Context) error
+}
+
+type HealthService struct {
+	bus connection.InterfaceMessageBus
+}
+
+func NewHealthService(bus connection.InterfaceMessageBus) InterfaceHealthService {
+	return &HealthService{bus: bus}
+}
+
+// GetHealth reports 503 while the RabbitMQ publisher is reconnecting so load balancers can take the replica out.
+func (s *HealthService) GetHealth(e echo.Context) error {
+	rabbitmq := s.bus.Health()
+
+	code := http.StatusOK
+	message := "healthy"
+	if !rabbitmq.Connected {
+		code = http.StatusServiceUnavailable
+		message = "rabbitmq publisher is not connected"
+	}
+
+	return e.JSON(code, model.Response{
+		Code:    code,
+		Message: message,
+		Data: map[string]interface{}{
+			"rabbitmq": rabbitmq,
+		},
+	})
+}
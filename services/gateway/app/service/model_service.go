@@ -0,0 +1,184 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceModelService interface {
+	GetModels(e echo.Context) error
+	GetActiveModel(e echo.Context) error
+	GetActivationHistory(e echo.Context) error
+	ActivateModel(e echo.Context) error
+	RollbackModel(e echo.Context) error
+	CompareModels(e echo.Context) error
+}
+
+type ModelService struct {
+	uc controller.InterfaceModelController
+}
+
+func NewModelService(uc controller.InterfaceModelController) InterfaceModelService {
+	return &ModelService{uc: uc}
+}
+
+func (s *ModelService) GetModels(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetModels")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	res, err := s.uc.GetModels(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Models",
+		Data:    res,
+	})
+}
+
+func (s *ModelService) GetActiveModel(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetActiveModel")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	res, err := s.uc.GetActiveModel(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Active Model",
+		Data:    res,
+	})
+}
+
+func (s *ModelService) GetActivationHistory(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetActivationHistory")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	res, err := s.uc.GetActivationHistory(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Activation History",
+		Data:    res,
+	})
+}
+
+func (s *ModelService) ActivateModel(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "ActivateModel")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	res, err := s.uc.ActivateModel(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Activate Model",
+		Data:    res,
+	})
+}
+
+func (s *ModelService) RollbackModel(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RollbackModel")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	var request model.RequestRollbackModel
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.RollbackModel(ctx, institutionID, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Rollback Model",
+		Data:    res,
+	})
+}
+
+func (s *ModelService) CompareModels(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "CompareModels")
+	defer span.Finish()
+
+	baseID := e.QueryParam("base")
+	candidateID := e.QueryParam("candidate")
+
+	utils.LogEvent(span, "Request", map[string]string{"base": baseID, "candidate": candidateID})
+
+	if baseID == "" || candidateID == "" {
+		utils.LogEventError(span, errors.New("base and candidate are required"))
+		return utils.LogError(e, model.ThrowError(http.StatusBadRequest, errors.New("base and candidate are required")), nil)
+	}
+
+	res, err := s.uc.CompareModels(ctx, baseID, candidateID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Compare Models",
+		Data:    res,
+	})
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceOutboxService interface {
+	GetMetrics(e echo.Context) error
+}
+
+type OutboxService struct {
+	uc controller.InterfaceOutboxController
+}
+
+func NewOutboxService(uc controller.InterfaceOutboxController) InterfaceOutboxService {
+	return &OutboxService{uc: uc}
+}
+
+func (s *OutboxService) GetMetrics(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetOutboxMetrics")
+	defer span.Finish()
+
+	res, err := s.uc.GetMetrics(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Outbox Metrics",
+		Data:    res,
+	})
+}
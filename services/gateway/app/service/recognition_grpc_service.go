@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/pb"
+	"face-recognition-svc/gateway/app/utils"
+	"io"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// RecognitionGrpcService serves the recognition controller over gRPC for edge devices. Callers are authenticated by
+// the GrpcAuth interceptors, so the controller reads the same session as on the REST routes.
+type RecognitionGrpcService struct {
+	pb.UnimplementedRecognitionServiceServer
+	uc  controller.InterfaceRecognitionController
+	cfg *config.Config
+}
+
+func NewRecognitionGrpcService(uc controller.InterfaceRecognitionController, cfg *config.Config) pb.RecognitionServiceServer {
+	return &RecognitionGrpcService{uc: uc, cfg: cfg}
+}
+
+func (s *RecognitionGrpcService) Identify(ctx context.Context, req *pb.IdentifyRequest) (*pb.RecognitionResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "gRPC: Identify")
+	defer span.Finish()
+
+	request := &model.RequestRecognition{
+		DeviceID: req.GetDeviceId(),
+		Image:    probeFile(req.GetImage()),
+	}
+
+	utils.LogEvent(span, "Request", map[string]any{"device_id": request.DeviceID, "size": len(req.GetImage())})
+
+	res, err := s.uc.Identify(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, utils.GrpcError(err)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return toRecognitionResult(res), nil
+}
+
+func (s *RecognitionGrpcService) Verify(ctx context.Context, req *pb.VerifyRequest) (*pb.RecognitionResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "gRPC: Verify")
+	defer span.Finish()
+
+	request := &model.RequestRecognition{
+		Username: req.GetUsername(),
+		DeviceID: req.GetDeviceId(),
+		Image:    probeFile(req.GetImage()),
+	}
+
+	utils.LogEvent(span, "Request", map[string]any{"username": request.Username, "device_id": request.DeviceID, "size": len(req.GetImage())})
+
+	res, err := s.uc.Verify(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, utils.GrpcError(err)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return toRecognitionResult(res), nil
+}
+
+// StreamIdentify identifies the frames of a camera. Frames arriving sooner than the configured frame interval after
+// the last identified one are answered as skipped, and a failed frame is reported in its result without closing the
+// stream.
+func (s *RecognitionGrpcService) StreamIdentify(stream grpc.BidiStreamingServer[pb.Frame, pb.FrameResult]) error {
+	interval := s.cfg.Grpc.FrameInterval()
+
+	var last time.Time
+	for {
+		frame, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := &pb.FrameResult{Sequence: frame.GetSequence()}
+
+		if now := time.Now(); now.Sub(last) < interval {
+			result.Skipped = true
+		} else {
+			last = now
+			res, err := s.identifyFrame(stream.Context(), frame)
+			if err != nil {
+				result.Error = &pb.Error{Code: int32(utils.HTTPStatus(err)), Message: err.Error()}
+			} else {
+				result.Result = toRecognitionResult(res)
+			}
+		}
+
+		if err := stream.Send(result); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *RecognitionGrpcService) identifyFrame(ctx context.Context, frame *pb.Frame) (*model.RecognitionResult, error) {
+	span, ctx := utils.SpanFromContext(ctx, "gRPC: StreamIdentify")
+	defer span.Finish()
+
+	utils.LogEvent(span, "Request", map[string]any{"sequence": frame.GetSequence(), "device_id": frame.GetDeviceId(), "size": len(frame.GetImage())})
+
+	res, err := s.uc.Identify(ctx, &model.RequestRecognition{
+		DeviceID: frame.GetDeviceId(),
+		Image:    probeFile(frame.GetImage()),
+	})
+	if err != nil {
+		utils.LogEventError(span, err)
+		return nil, err
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return res, nil
+}
+
+// probeFile returns nil for an empty image so the controller rejects it like a request without one.
+func probeFile(image []byte) *model.File {
+	if len(image) == 0 {
+		return nil
+	}
+	return &model.File{FileName: "probe", BytesObject: image}
+}
+
+func toRecognitionResult(res *model.RecognitionResult) *pb.RecognitionResult {
+	candidates := make([]*pb.Candidate, 0, len(res.Candidates))
+	for _, candidate := range res.Candidates {
+		candidates = append(candidates, &pb.Candidate{Username: candidate.Username, Score: candidate.Score})
+	}
+
+	return &pb.RecognitionResult{
+		EventId:      res.EventID,
+		Kind:         res.Kind,
+		Decision:     res.Decision,
+		Username:     res.Username,
+		Score:        res.Score,
+		Threshold:    res.Threshold,
+		Margin:       res.Margin,
+		MinMargin:    res.MinMargin,
+		Candidates:   candidates,
+		ModelId:      res.ModelID,
+		ModelVersion: int32(res.ModelVersion),
+		LatencyMs:    res.LatencyMs,
+		Message:      res.Message,
+	}
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceRecognitionPolicyService interface {
+	GetPolicy(e echo.Context) error
+	SavePolicy(e echo.Context) error
+	DeletePolicy(e echo.Context) error
+}
+
+type RecognitionPolicyService struct {
+	uc controller.InterfaceRecognitionPolicyController
+}
+
+func NewRecognitionPolicyService(uc controller.InterfaceRecognitionPolicyController) InterfaceRecognitionPolicyService {
+	return &RecognitionPolicyService{uc: uc}
+}
+
+func (s *RecognitionPolicyService) GetPolicy(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionPolicy")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	res, err := s.uc.GetPolicy(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Recognition Policy",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionPolicyService) SavePolicy(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "SaveRecognitionPolicy")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	var request model.RequestRecognitionPolicy
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.SavePolicy(ctx, institutionID, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Save Recognition Policy",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionPolicyService) DeletePolicy(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteRecognitionPolicy")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := s.uc.DeletePolicy(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Recognition Policy",
+		Data:    nil,
+	})
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceRecognitionReviewService interface {
+	GetReviews(e echo.Context) error
+	GetReview(e echo.Context) error
+	GetReviewImage(e echo.Context) error
+	LabelReview(e echo.Context) error
+	DiscardReview(e echo.Context) error
+	FlagImpostor(e echo.Context) error
+}
+
+type RecognitionReviewService struct {
+	uc controller.InterfaceRecognitionReviewController
+}
+
+func NewRecognitionReviewService(uc controller.InterfaceRecognitionReviewController) InterfaceRecognitionReviewService {
+	return &RecognitionReviewService{uc: uc}
+}
+
+func (s *RecognitionReviewService) GetReviews(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionReviews")
+	defer span.Finish()
+
+	filter := &model.FilterRecognitionReview{
+		InstitutionID: e.QueryParam("institution_id"),
+		Status:        strings.ToUpper(e.QueryParam("status")),
+	}
+
+	pagination := utils.ParsePaginationFromQuery(e)
+
+	res, pagination, err := s.uc.GetReviews(ctx, filter, pagination)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:       200,
+		Message:    "Success Get Recognition Reviews",
+		Data:       res,
+		Pagination: pagination,
+	})
+}
+
+func (s *RecognitionReviewService) GetReview(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionReview")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.GetReview(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Recognition Review",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionReviewService) GetReviewImage(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionReviewImage")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	res, err := s.uc.GetReviewImage(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.Blob(http.StatusOK, http.DetectContentType(res.BytesObject), res.BytesObject)
+}
+
+func (s *RecognitionReviewService) LabelReview(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "LabelRecognitionReview")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	request, err := bindReviewDecision(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.LabelReview(ctx, id, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Label Recognition Review",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionReviewService) DiscardReview(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DiscardRecognitionReview")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	request, err := bindReviewDecision(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.DiscardReview(ctx, id, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Discard Recognition Review",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionReviewService) FlagImpostor(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "FlagRecognitionImpostor")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	request, err := bindReviewDecision(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.FlagImpostor(ctx, id, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Flag Recognition Impostor",
+		Data:    res,
+	})
+}
+
+// bindReviewDecision accepts an empty body, since only labeling needs a username.
+func bindReviewDecision(e echo.Context) (*model.RequestRecognitionReviewDecision, error) {
+	var request model.RequestRecognitionReviewDecision
+
+	if e.Request().ContentLength == 0 {
+		return &request, nil
+	}
+
+	if err := e.Bind(&request); err != nil {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("invalid request body"))
+	}
+
+	return &request, nil
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceRecognitionService interface {
+	Identify(e echo.Context) error
+	Verify(e echo.Context) error
+	IdentifyGroup(e echo.Context) error
+	GetEvents(e echo.Context) error
+	GetEvent(e echo.Context) error
+	GetHourlyStats(e echo.Context) error
+	GetSummary(e echo.Context) error
+}
+
+type RecognitionService struct {
+	uc controller.InterfaceRecognitionController
+}
+
+func NewRecognitionService(uc controller.InterfaceRecognitionController) InterfaceRecognitionService {
+	return &RecognitionService{uc: uc}
+}
+
+func (s *RecognitionService) Identify(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "Identify")
+	defer span.Finish()
+
+	request, err := s.parseRecognitionRequest(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.Identify(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Identify",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionService) Verify(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "Verify")
+	defer span.Finish()
+
+	request, err := s.parseRecognitionRequest(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.Verify(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Verify",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionService) IdentifyGroup(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "IdentifyGroup")
+	defer span.Finish()
+
+	request, err := s.parseRecognitionRequest(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.IdentifyGroup(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Identify Group",
+		Data:    res,
+	})
+}
+
+// parseRecognitionRequest accepts the probe either as the multipart field "image" or as base64 in a JSON body.
+func (s *RecognitionService) parseRecognitionRequest(e echo.Context) (*model.RequestRecognition, error) {
+	if strings.HasPrefix(e.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var request model.RequestRecognition
+		if err := e.Bind(&request); err != nil {
+			return nil, err
+		}
+
+		if request.ImageData != "" {
+			file, err := utils.DecodeBase64Image("probe", request.ImageData)
+			if err != nil {
+				return nil, err
+			}
+			request.Image = file
+			request.ImageData = ""
+		}
+
+		return &request, nil
+	}
+
+	files, err := utils.ReadFormFiles(e, "image")
+	if err != nil {
+		return nil, err
+	}
+
+	request := &model.RequestRecognition{
+		Username: e.FormValue("username"),
+		DeviceID: e.FormValue("device_id"),
+	}
+	if len(files) > 0 {
+		request.Image = files[0]
+	}
+
+	return request, nil
+}
+
+func (s *RecognitionService) GetEvents(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionEvents")
+	defer span.Finish()
+
+	filter, err := parseRecognitionFilter(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	pagination := utils.ParsePaginationFromQuery(e)
+
+	res, pagination, err := s.uc.GetEvents(ctx, filter, pagination)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:       200,
+		Message:    "Success Get Recognition Events",
+		Data:       res,
+		Pagination: pagination,
+	})
+}
+
+func (s *RecognitionService) GetEvent(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionEvent")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	if id == "" {
+		utils.LogEventError(span, errors.New("id shouldn't be empty"))
+		return utils.LogError(e, errors.New("id shouldn't be empty"), nil)
+	}
+
+	res, err := s.uc.GetEvent(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Recognition Event",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionService) GetHourlyStats(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionHourlyStats")
+	defer span.Finish()
+
+	filter, err := parseRecognitionFilter(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	res, err := s.uc.GetHourlyStats(ctx, filter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Recognition Hourly Stats",
+		Data:    res,
+	})
+}
+
+func (s *RecognitionService) GetSummary(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetRecognitionSummary")
+	defer span.Finish()
+
+	filter, err := parseRecognitionFilter(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	res, err := s.uc.GetSummary(ctx, filter)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Recognition Summary",
+		Data:    res,
+	})
+}
+
+func parseRecognitionFilter(e echo.Context) (*model.FilterRecognitionEvent, error) {
+	filter := &model.FilterRecognitionEvent{
+		InstitutionID: e.QueryParam("institution_id"),
+		Username:      e.QueryParam("username"),
+		DeviceID:      e.QueryParam("device_id"),
+		Decision:      strings.ToUpper(e.QueryParam("decision")),
+		Kind:          strings.ToUpper(e.QueryParam("kind")),
+		BatchID:       e.QueryParam("batch_id"),
+	}
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		value := e.QueryParam(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, model.ThrowError(http.StatusBadRequest, fmt.Errorf("%s must be an RFC3339 timestamp", name))
+		}
+		parsed = parsed.UTC()
+		*target = &parsed
+	}
+
+	return filter, nil
+}
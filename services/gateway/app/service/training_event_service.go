@@ -0,0 +1,127 @@
+package service
+
+import (
+	"encoding/json"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// trainingEventKeepAlive is how often an idle stream sends a comment so proxies do not close it.
+const trainingEventKeepAlive = 15 * time.Second
+
+type InterfaceTrainingEventService interface {
+	StreamTraining(e echo.Context) error
+	StreamInstitution(e echo.Context) error
+}
+
+type TrainingEventService struct {
+	uc controller.InterfaceTrainingEventController
+}
+
+func NewTrainingEventService(uc controller.InterfaceTrainingEventController) InterfaceTrainingEventService {
+	return &TrainingEventService{uc: uc}
+}
+
+func (s *TrainingEventService) StreamTraining(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "StreamTraining")
+	defer span.Finish()
+
+	id := e.Param("id")
+
+	utils.LogEvent(span, "Request", id)
+
+	stream, err := s.uc.SubscribeTraining(ctx, id)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return writeTrainingEvents(e, stream)
+}
+
+func (s *TrainingEventService) StreamInstitution(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "StreamInstitutionTrainings")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	stream, err := s.uc.SubscribeInstitution(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return writeTrainingEvents(e, stream)
+}
+
+// writeTrainingEvents sends the stream as Server-Sent Events until the client disconnects. A stream that follows
+// a single training ends after that training reaches a final state.
+func writeTrainingEvents(e echo.Context, stream *model.TrainingEventStream) error {
+	res := e.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	for _, event := range stream.Snapshot {
+		done, err := writeTrainingEvent(res, stream, event)
+		if err != nil || done {
+			return err
+		}
+	}
+	res.Flush()
+
+	keepAlive := time.NewTicker(trainingEventKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-e.Request().Context().Done():
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil
+			}
+			res.Flush()
+		case event, ok := <-stream.Events:
+			if !ok {
+				return nil
+			}
+			done, err := writeTrainingEvent(res, stream, event)
+			if err != nil || done {
+				return nil
+			}
+		}
+	}
+}
+
+func writeTrainingEvent(res *echo.Response, stream *model.TrainingEventStream, event *model.TrainingEvent) (bool, error) {
+	if stream.TrainingID != "" && event.ID != stream.TrainingID {
+		return false, nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := fmt.Fprintf(res, "event: training\ndata: %s\n\n", data); err != nil {
+		return false, err
+	}
+	res.Flush()
+
+	finished := event.Status == model.ModelTrainingStatusSucceeded ||
+		event.Status == model.ModelTrainingStatusFailed ||
+		event.Status == model.ModelTrainingStatusCancelled
+
+	return stream.TrainingID != "" && finished, nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type InterfaceTrainingScheduleService interface {
+	GetSchedules(e echo.Context) error
+	GetSchedule(e echo.Context) error
+	SaveSchedule(e echo.Context) error
+	DeleteSchedule(e echo.Context) error
+}
+
+type TrainingScheduleService struct {
+	uc controller.InterfaceTrainingScheduleController
+}
+
+func NewTrainingScheduleService(uc controller.InterfaceTrainingScheduleController) InterfaceTrainingScheduleService {
+	return &TrainingScheduleService{uc: uc}
+}
+
+func (s *TrainingScheduleService) GetSchedules(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetTrainingSchedules")
+	defer span.Finish()
+
+	res, err := s.uc.GetSchedules(ctx)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Training Schedules",
+		Data:    res,
+	})
+}
+
+func (s *TrainingScheduleService) GetSchedule(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "GetTrainingSchedule")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	res, err := s.uc.GetSchedule(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Get Training Schedule",
+		Data:    res,
+	})
+}
+
+func (s *TrainingScheduleService) SaveSchedule(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "SaveTrainingSchedule")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	var request model.RequestTrainingSchedule
+
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", request)
+
+	res, err := s.uc.SaveSchedule(ctx, institutionID, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Response", res)
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Save Training Schedule",
+		Data:    res,
+	})
+}
+
+func (s *TrainingScheduleService) DeleteSchedule(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "DeleteTrainingSchedule")
+	defer span.Finish()
+
+	institutionID := e.Param("institution-id")
+
+	utils.LogEvent(span, "Request", institutionID)
+
+	err := s.uc.DeleteSchedule(ctx, institutionID)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Delete Training Schedule",
+		Data:    nil,
+	})
+}
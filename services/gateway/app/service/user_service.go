@@ -21,6 +21,8 @@ type InterfaceUserService interface {
 	UpdateUser(e echo.Context) error
 	DeleteUser(e echo.Context) error
 	Login(e echo.Context) error
+	FaceLogin(e echo.Context) error
+	RefreshToken(e echo.Context) error
 	GetAllUser(e echo.Context) error
 	GetInstitutionList(e echo.Context) error
 	EmbedMetabase(e echo.Context) error
@@ -145,7 +147,9 @@ func (s *UserService) Login(e echo.Context) error {
 		return utils.LogError(e, err, nil)
 	}
 
-	utils.LogEvent(span, "Request", request)
+	utils.LogEvent(span, "Request", map[string]any{"username": request.Username, "institution_id": request.InstitutionID})
+
+	ctx = utils.WithPublicSession(ctx, "login", request.InstitutionID, e.RealIP(), e.Request().UserAgent())
 
 	response, err := s.uc.Login(ctx, request)
 	if err != nil {
@@ -160,6 +164,94 @@ func (s *UserService) Login(e echo.Context) error {
 	})
 }
 
+func (s *UserService) FaceLogin(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "FaceLogin")
+	defer span.Finish()
+
+	request, err := parseFaceLoginRequest(e)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	utils.LogEvent(span, "Request", map[string]any{"username": request.Username, "institution_id": request.InstitutionID})
+
+	ctx = utils.WithPublicSession(ctx, "face-login", request.InstitutionID, e.RealIP(), e.Request().UserAgent())
+
+	response, err := s.uc.FaceLogin(ctx, request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Login",
+		Data:    response,
+	})
+}
+
+func (s *UserService) RefreshToken(e echo.Context) error {
+	ctx, span := utils.StartSpan(e, "RefreshToken")
+	defer span.Finish()
+
+	var request model.RequestRefreshToken
+	if err := e.Bind(&request); err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	ctx = utils.WithPublicSession(ctx, "token-refresh", "", e.RealIP(), e.Request().UserAgent())
+
+	response, err := s.uc.RefreshToken(ctx, &request)
+	if err != nil {
+		utils.LogEventError(span, err)
+		return utils.LogError(e, err, nil)
+	}
+
+	return e.JSON(http.StatusOK, model.Response{
+		Code:    200,
+		Message: "Success Refresh Token",
+		Data:    response,
+	})
+}
+
+// parseFaceLoginRequest accepts the photo either as the multipart field "image" or as base64 in a JSON body.
+func parseFaceLoginRequest(e echo.Context) (*model.RequestFaceLogin, error) {
+	if strings.HasPrefix(e.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		var request model.RequestFaceLogin
+		if err := e.Bind(&request); err != nil {
+			return nil, err
+		}
+
+		if request.ImageData != "" {
+			file, err := utils.DecodeBase64Image("probe", request.ImageData)
+			if err != nil {
+				return nil, err
+			}
+			request.Image = file
+			request.ImageData = ""
+		}
+
+		return &request, nil
+	}
+
+	files, err := utils.ReadFormFiles(e, "image")
+	if err != nil {
+		return nil, err
+	}
+
+	request := &model.RequestFaceLogin{
+		Username:      e.FormValue("username"),
+		InstitutionID: e.FormValue("institution_id"),
+	}
+	if len(files) > 0 {
+		request.Image = files[0]
+	}
+
+	return request, nil
+}
+
 func (s *UserService) GetAllUser(e echo.Context) error {
 	ctx, span := utils.StartSpan(e, "GetAlluser")
 	defer span.Finish()
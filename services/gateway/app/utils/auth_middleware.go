@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
@@ -33,37 +34,28 @@ func NewAuthMiddleware(db *gorm.DB, redis *redis.Client) *AuthMiddleware {
 func (m *AuthMiddleware) IsAuthorized() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			if IsDeviceRequest(c) {
+				return next(c)
+			}
+
 			token := c.Get("user").(*jwt.Token)
 			claims := token.Claims.(*model.JwtCustomClaims)
 
+			// Tokens issued before token_type existed, and any other token signed with the access secret, are
+			// not access tokens.
+			if claims.TokenType != model.TokenTypeAccess {
+				return LogError(c, model.ThrowError(http.StatusUnauthorized, errors.New("not an access token")), nil)
+			}
+
 			ctx := c.Request().Context()
 			requiredPermission := strings.TrimSpace(c.Request().Header.Get("app-permission"))
 			if requiredPermission != "" {
-				if len(claims.RoleIDs) == 0 {
-					return LogError(c, model.ThrowError(http.StatusForbidden, errors.New("missing role assignment")), nil)
-				}
-				var permissions []string
-				query := `
-					SELECT p.name
-					FROM permission p
-					JOIN role_permission rp ON rp.permission_id = p.id
-					WHERE rp.role_id IN ?
-					AND p.is_active = TRUE`
-				err := m.db.WithContext(ctx).Raw(query, claims.RoleIDs).Scan(&permissions).Error
-				if err != nil {
-					return LogError(c, model.ThrowError(http.StatusInternalServerError, err), nil)
-				}
-				if !Contains(permissions, requiredPermission) {
-					return LogError(c, model.ThrowError(http.StatusForbidden, errors.New("permission denied")), nil)
+				if err := m.checkPermission(ctx, claims.RoleIDs, requiredPermission); err != nil {
+					return LogError(c, err, nil)
 				}
 			}
 
-			md := metadata.New(map[string]string{
-				"user_id":        claims.UserID,
-				"username":       claims.Username,
-				"role_ids":       strings.Join(claims.RoleIDs, ","),
-				"institution_id": claims.InstitutionID,
-			})
+			md := userMetadata(claims, c.RealIP(), c.Request().UserAgent())
 
 			c.SetRequest(c.Request().WithContext(metadata.NewIncomingContext(c.Request().Context(), md)))
 
@@ -71,3 +63,39 @@ func (m *AuthMiddleware) IsAuthorized() echo.MiddlewareFunc {
 		}
 	}
 }
+
+// checkPermission fails unless one of the roles grants the active permission.
+func (m *AuthMiddleware) checkPermission(ctx context.Context, roleIDs []string, permission string) error {
+	if len(roleIDs) == 0 {
+		return model.ThrowError(http.StatusForbidden, errors.New("missing role assignment"))
+	}
+
+	var permissions []string
+	query := `
+		SELECT p.name
+		FROM permission p
+		JOIN role_permission rp ON rp.permission_id = p.id
+		WHERE rp.role_id IN ?
+		AND p.is_active = TRUE`
+	err := m.db.WithContext(ctx).Raw(query, roleIDs).Scan(&permissions).Error
+	if err != nil {
+		return model.ThrowError(http.StatusInternalServerError, err)
+	}
+	if !Contains(permissions, permission) {
+		return model.ThrowError(http.StatusForbidden, errors.New("permission denied"))
+	}
+
+	return nil
+}
+
+// userMetadata is the session GetMetadata reads for a user authenticated with an access token.
+func userMetadata(claims *model.JwtCustomClaims, ipAddress string, userAgent string) metadata.MD {
+	return metadata.New(map[string]string{
+		"user_id":        claims.UserID,
+		"username":       claims.Username,
+		"role_ids":       strings.Join(claims.RoleIDs, ","),
+		"institution_id": claims.InstitutionID,
+		"ip_address":     ipAddress,
+		"user_agent":     userAgent,
+	})
+}
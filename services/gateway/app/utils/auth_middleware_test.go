@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"face-recognition-svc/gateway/app/model"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestIsAuthorizedRequiresAccessToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		tokenType string
+		wantCode  int
+	}{
+		{name: "access token", tokenType: model.TokenTypeAccess, wantCode: http.StatusOK},
+		{name: "token without token_type", tokenType: "", wantCode: http.StatusUnauthorized},
+		{name: "other token type", tokenType: "refresh", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/service/user", nil), rec)
+			c.Set("user", &jwt.Token{Claims: &model.JwtCustomClaims{
+				UserID:    "user-1",
+				Username:  "alice",
+				TokenType: tt.tokenType,
+			}})
+
+			var username string
+			next := func(c echo.Context) error {
+				md, _ := metadata.FromIncomingContext(c.Request().Context())
+				username = firstMetadata(md, "username")
+				return c.NoContent(http.StatusOK)
+			}
+
+			if err := (&AuthMiddleware{}).IsAuthorized()(next)(c); err != nil {
+				t.Fatalf("IsAuthorized() error = %v", err)
+			}
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && username != "alice" {
+				t.Errorf("username metadata = %q, want %q", username, "alice")
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Cascade is a pixel intensity comparison (pico) cascade of decision trees. It is loaded from a cascade file
+// such as pico's "facefinder" and runs without cgo.
+type Cascade struct {
+	treeDepth     int
+	treeNum       int
+	treeCodes     []int8
+	treePred      []float32
+	treeThreshold []float32
+}
+
+// CascadeDetection is a square region, centred on Row and Col with side Scale, that the cascade accepted.
+type CascadeDetection struct {
+	Row   int
+	Col   int
+	Scale int
+	Score float64
+}
+
+// CascadeParams controls the sliding window. Sizes are in pixels of the searched image.
+type CascadeParams struct {
+	MinSize     int
+	MaxSize     int
+	ShiftFactor float64
+	ScaleFactor float64
+}
+
+// UnpackCascade parses a pico cascade file: an 8 byte header, the tree depth and tree count, then per tree its
+// node codes, leaf predictions and threshold, all little endian.
+func UnpackCascade(data []byte) (*Cascade, error) {
+	if len(data) < 16 {
+		return nil, errors.New("cascade file is truncated")
+	}
+
+	pos := 8
+	depth := int(binary.LittleEndian.Uint32(data[pos:]))
+	pos += 4
+	count := int(binary.LittleEndian.Uint32(data[pos:]))
+	pos += 4
+
+	if depth <= 0 || depth > 16 || count <= 0 {
+		return nil, errors.New("cascade file has an invalid header")
+	}
+
+	leaves := 1 << depth
+	codeLen := 4*leaves - 4
+	if len(data) < pos+count*(codeLen+4*leaves+4) {
+		return nil, errors.New("cascade file is truncated")
+	}
+
+	c := &Cascade{treeDepth: depth, treeNum: count}
+	for t := 0; t < count; t++ {
+		// The root node is implicit; pad it so node indexes start at 1.
+		c.treeCodes = append(c.treeCodes, 0, 0, 0, 0)
+		for _, b := range data[pos : pos+codeLen] {
+			c.treeCodes = append(c.treeCodes, int8(b))
+		}
+		pos += codeLen
+
+		for i := 0; i < leaves; i++ {
+			c.treePred = append(c.treePred, math.Float32frombits(binary.LittleEndian.Uint32(data[pos:])))
+			pos += 4
+		}
+
+		c.treeThreshold = append(c.treeThreshold, math.Float32frombits(binary.LittleEndian.Uint32(data[pos:])))
+		pos += 4
+	}
+
+	return c, nil
+}
+
+// Detect slides the cascade over a grayscale image stored row by row and returns the clustered detections.
+func (c *Cascade) Detect(pixels []uint8, rows, cols int, params CascadeParams) []CascadeDetection {
+	maxSize := params.MaxSize
+	if maxSize <= 0 || maxSize > min(rows, cols) {
+		maxSize = min(rows, cols)
+	}
+
+	var detections []CascadeDetection
+	for scale := params.MinSize; scale <= maxSize; {
+		step := max(int(params.ShiftFactor*float64(scale)), 1)
+		offset := scale/2 + 1
+
+		for row := offset; row <= rows-offset; row += step {
+			for col := offset; col <= cols-offset; col += step {
+				score := c.classifyRegion(row, col, scale, pixels, cols)
+				if score > 0 {
+					detections = append(detections, CascadeDetection{Row: row, Col: col, Scale: scale, Score: float64(score)})
+				}
+			}
+		}
+
+		next := int(float64(scale) * params.ScaleFactor)
+		if next <= scale {
+			next = scale + 1
+		}
+		scale = next
+	}
+
+	return clusterDetections(detections, 0.2)
+}
+
+func (c *Cascade) classifyRegion(row, col, scale int, pixels []uint8, dim int) float32 {
+	leaves := 1 << c.treeDepth
+	root := 0
+	var out float32
+
+	row *= 256
+	col *= 256
+	for i := 0; i < c.treeNum; i++ {
+		idx := 1
+		for j := 0; j < c.treeDepth; j++ {
+			p1 := ((row+int(c.treeCodes[root+4*idx+0])*scale)>>8)*dim + ((col + int(c.treeCodes[root+4*idx+1])*scale) >> 8)
+			p2 := ((row+int(c.treeCodes[root+4*idx+2])*scale)>>8)*dim + ((col + int(c.treeCodes[root+4*idx+3])*scale) >> 8)
+
+			idx *= 2
+			if pixels[p1] <= pixels[p2] {
+				idx++
+			}
+		}
+
+		out += c.treePred[leaves*i+idx-leaves]
+		if out <= c.treeThreshold[i] {
+			return -1
+		}
+		root += 4 * leaves
+	}
+
+	return out - c.treeThreshold[c.treeNum-1]
+}
+
+// clusterDetections merges detections that overlap by more than iouThreshold into their average region. The
+// scores of merged detections add up, so a face found at several positions and scales scores higher.
+func clusterDetections(detections []CascadeDetection, iouThreshold float64) []CascadeDetection {
+	assigned := make([]bool, len(detections))
+
+	var clusters []CascadeDetection
+	for i := range detections {
+		if assigned[i] {
+			continue
+		}
+
+		var row, col, scale, score float64
+		n := 0
+		for j := i; j < len(detections); j++ {
+			if assigned[j] || intersectionOverUnion(detections[i], detections[j]) <= iouThreshold {
+				continue
+			}
+			assigned[j] = true
+			row += float64(detections[j].Row)
+			col += float64(detections[j].Col)
+			scale += float64(detections[j].Scale)
+			score += detections[j].Score
+			n++
+		}
+
+		clusters = append(clusters, CascadeDetection{
+			Row:   int(row / float64(n)),
+			Col:   int(col / float64(n)),
+			Scale: int(scale / float64(n)),
+			Score: score,
+		})
+	}
+
+	return clusters
+}
+
+func intersectionOverUnion(a, b CascadeDetection) float64 {
+	ar, ac, as := float64(a.Row), float64(a.Col), float64(a.Scale)
+	br, bc, bs := float64(b.Row), float64(b.Col), float64(b.Scale)
+
+	overRow := math.Max(0, math.Min(ar+as/2, br+bs/2)-math.Max(ar-as/2, br-bs/2))
+	overCol := math.Max(0, math.Min(ac+as/2, bc+bs/2)-math.Max(ac-as/2, bc-bs/2))
+	overlap := overRow * overCol
+
+	return overlap / (as*as + bs*bs - overlap)
+}
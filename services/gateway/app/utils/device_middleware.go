@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+
+	"face-recognition-svc/gateway/app/model"
+
+	"github.com/labstack/echo/v4"
+	"google.golang.org/grpc/metadata"
+	"gorm.io/gorm"
+)
+
+// deviceContextKey marks a request authenticated with a device key, so the JWT middlewares skip it.
+const deviceContextKey = "device"
+
+type InterfaceDeviceMiddleware interface {
+	Authenticate() echo.MiddlewareFunc
+	Allow(routes ...*echo.Route)
+}
+
+// DeviceMiddleware authenticates kiosks and cameras by the API key in the X-Device-Key header. A device may only
+// call the routes passed to Allow; requests without the header are left to the JWT middlewares.
+type DeviceMiddleware struct {
+	db      *gorm.DB
+	allowed map[string]bool
+}
+
+func NewDeviceMiddleware(db *gorm.DB) *DeviceMiddleware {
+	return &DeviceMiddleware{
+		db:      db,
+		allowed: make(map[string]bool),
+	}
+}
+
+// Allow lets devices call routes. It must be called while routes are registered, before the server starts.
+func (m *DeviceMiddleware) Allow(routes ...*echo.Route) {
+	for _, route := range routes {
+		m.allowed[route.Method+" "+route.Path] = true
+	}
+}
+
+func (m *DeviceMiddleware) Authenticate() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := strings.TrimSpace(c.Request().Header.Get(model.DeviceKeyHeader))
+			if key == "" {
+				return next(c)
+			}
+
+			device, err := m.lookupDevice(c.Request().Context(), key)
+			if err != nil {
+				return LogError(c, err, nil)
+			}
+
+			if !m.allowed[c.Request().Method+" "+c.Path()] {
+				return LogError(c, model.ThrowError(http.StatusForbidden, errors.New("devices are not allowed to call this route")), nil)
+			}
+
+			md := deviceMetadata(device, c.RealIP(), c.Request().UserAgent())
+
+			c.Set(deviceContextKey, device.ID)
+			c.SetRequest(c.Request().WithContext(metadata.NewIncomingContext(c.Request().Context(), md)))
+
+			return next(c)
+		}
+	}
+}
+
+// lookupDevice returns the active device the key belongs to.
+func (m *DeviceMiddleware) lookupDevice(ctx context.Context, key string) (*model.Device, error) {
+	prefix, _, ok := strings.Cut(key, ".")
+	if !ok || prefix == "" {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid device key"))
+	}
+
+	var devices []*model.Device
+	err := m.db.WithContext(ctx).Raw("SELECT * FROM device WHERE key_prefix = ?", prefix).Scan(&devices).Error
+	if err != nil {
+		return nil, model.ThrowError(http.StatusInternalServerError, err)
+	}
+	if len(devices) == 0 || subtle.ConstantTimeCompare([]byte(HashDeviceKey(key)), []byte(devices[0].KeyHash)) != 1 {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid device key"))
+	}
+
+	if devices[0].Status != model.DeviceStatusActive {
+		return nil, model.ThrowError(http.StatusForbidden, errors.New("device is disabled"))
+	}
+
+	return devices[0], nil
+}
+
+// deviceMetadata is the session GetMetadata reads for a device. It acts for its institution without roles.
+func deviceMetadata(device *model.Device, ipAddress string, userAgent string) metadata.MD {
+	return metadata.New(map[string]string{
+		"username":       "device:" + device.Name,
+		"institution_id": device.InstitutionID,
+		"device_id":      device.ID,
+		"ip_address":     ipAddress,
+		"user_agent":     userAgent,
+	})
+}
+
+// IsDeviceRequest reports whether the request was authenticated with a device key.
+func IsDeviceRequest(c echo.Context) bool {
+	return c.Get(deviceContextKey) != nil
+}
+
+// GenerateDeviceKey returns a new API key and its prefix. The key is "<prefix>.<secret>"; only its hash is stored.
+func GenerateDeviceKey() (key string, prefix string, err error) {
+	prefixBytes := make([]byte, 6)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+
+	return prefix + "." + base64.RawURLEncoding.EncodeToString(secret), prefix, nil
+}
+
+func HashDeviceKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
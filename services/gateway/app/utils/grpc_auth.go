@@ -0,0 +1,185 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"face-recognition-svc/gateway/app/model"
+
+	"github.com/golang-jwt/jwt/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type InterfaceGrpcAuth interface {
+	UnaryInterceptor() grpc.UnaryServerInterceptor
+	StreamInterceptor() grpc.StreamServerInterceptor
+}
+
+// GrpcAuth authenticates gRPC calls like the HTTP middlewares: an x-device-key, or an authorization bearer access
+// token whose roles grant permission, plus an optional app-permission. The caller's metadata is replaced by the
+// session GetMetadata reads, so a client cannot send its own user_id or institution_id.
+type GrpcAuth struct {
+	secret     []byte
+	permission string
+	auth       *AuthMiddleware
+	device     *DeviceMiddleware
+}
+
+func NewGrpcAuth(secret string, permission string, auth *AuthMiddleware, device *DeviceMiddleware) *GrpcAuth {
+	return &GrpcAuth{
+		secret:     []byte(secret),
+		permission: permission,
+		auth:       auth,
+		device:     device,
+	}
+}
+
+func (a *GrpcAuth) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx)
+		if err != nil {
+			return nil, GrpcError(err)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (a *GrpcAuth) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(stream.Context())
+		if err != nil {
+			return GrpcError(err)
+		}
+
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (a *GrpcAuth) authenticate(ctx context.Context) (context.Context, error) {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+
+	var ipAddress string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ipAddress = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ipAddress); err == nil {
+			ipAddress = host
+		}
+	}
+	userAgent := firstMetadata(incoming, "user-agent")
+
+	if key := firstMetadata(incoming, strings.ToLower(model.DeviceKeyHeader)); key != "" {
+		device, err := a.device.lookupDevice(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return metadata.NewIncomingContext(ctx, deviceMetadata(device, ipAddress, userAgent)), nil
+	}
+
+	bearer, ok := strings.CutPrefix(firstMetadata(incoming, "authorization"), "Bearer ")
+	if !ok || bearer == "" {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("missing bearer token or device key"))
+	}
+
+	claims := new(model.JwtCustomClaims)
+	_, err := jwt.ParseWithClaims(bearer, claims, func(token *jwt.Token) (any, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("invalid or expired token"))
+	}
+
+	if claims.TokenType != model.TokenTypeAccess {
+		return nil, model.ThrowError(http.StatusUnauthorized, errors.New("not an access token"))
+	}
+
+	// Enforced here rather than left to app-permission, which only the client decides to send.
+	err = a.auth.checkPermission(ctx, claims.RoleIDs, a.permission)
+	if err != nil {
+		return nil, err
+	}
+
+	if permission := firstMetadata(incoming, "app-permission"); permission != "" {
+		if err := a.auth.checkPermission(ctx, claims.RoleIDs, permission); err != nil {
+			return nil, err
+		}
+	}
+
+	return metadata.NewIncomingContext(ctx, userMetadata(claims, ipAddress, userAgent)), nil
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// authenticatedStream swaps the context of a stream for the authenticated one.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusGone:                  codes.NotFound,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusUnsupportedMediaType:  codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+	http.StatusGatewayTimeout:        codes.DeadlineExceeded,
+}
+
+// GrpcError converts an error of the controllers into a gRPC status, mapping the HTTP code of a ThrowError.
+func GrpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var response *model.ErrorResponse
+	if errors.As(err, &response) {
+		if code, ok := grpcCodes[response.Code]; ok {
+			return status.Error(code, response.Error())
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	if errors.Is(err, context.Canceled) {
+		return status.Error(codes.Canceled, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// HTTPStatus is the HTTP code the REST routes would answer err with.
+func HTTPStatus(err error) int {
+	var response *model.ErrorResponse
+	if errors.As(err, &response) {
+		return response.Code
+	}
+	return http.StatusInternalServerError
+}
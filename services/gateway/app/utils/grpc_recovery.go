@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GrpcRecoveryUnaryInterceptor turns a panic in a handler into an Internal error, like the HTTP recover
+// middleware, instead of letting it stop the gateway.
+func GrpcRecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverGrpcPanic(info.FullMethod, r)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// GrpcRecoveryStreamInterceptor is GrpcRecoveryUnaryInterceptor for streams.
+func GrpcRecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recoverGrpcPanic(info.FullMethod, r)
+			}
+		}()
+
+		return handler(srv, stream)
+	}
+}
+
+func recoverGrpcPanic(method string, r any) error {
+	log.Error().Err(fmt.Errorf("%v", r)).Str("method", method).Bytes("stack", debug.Stack()).Msg("Recovered from gRPC handler panic")
+
+	return status.Error(codes.Internal, "internal error")
+}
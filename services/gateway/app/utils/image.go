@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"face-recognition-svc/gateway/app/model"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/webp": "webp",
+}
+
+// ValidateImage checks the size and sniffed content type of an uploaded image and
+// normalizes its file name so it is safe to use as an object key.
+func ValidateImage(file *model.File, maxBytes int64) error {
+	if len(file.BytesObject) == 0 {
+		return model.ThrowError(http.StatusBadRequest, fmt.Errorf("image %s is empty", file.FileName))
+	}
+
+	if maxBytes > 0 && int64(len(file.BytesObject)) > maxBytes {
+		return model.ThrowError(http.StatusRequestEntityTooLarge, fmt.Errorf("image %s exceeds %d bytes", file.FileName, maxBytes))
+	}
+
+	extension, ok := imageExtensions[http.DetectContentType(file.BytesObject)]
+	if !ok {
+		return model.ThrowError(http.StatusUnsupportedMediaType, fmt.Errorf("image %s is not a jpeg, png or webp file", file.FileName))
+	}
+
+	name := path.Base(strings.ReplaceAll(file.FileName, "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		name = uuid.New().String()
+	}
+	if path.Ext(name) == "" {
+		name = name + "." + extension
+	}
+
+	file.FileName = name
+	file.Extension = extension
+
+	return nil
+}
+
+// DecodeBase64Image decodes a standard or data-URI base64 payload into a dataset file.
+func DecodeBase64Image(fileName string, data string) (*model.File, error) {
+	if idx := strings.Index(data, ","); strings.HasPrefix(data, "data:") && idx > 0 {
+		data = data[idx+1:]
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+	if err != nil {
+		return nil, model.ThrowError(http.StatusBadRequest, errors.New("image data is not valid base64"))
+	}
+
+	return &model.File{
+		FileName:    fileName,
+		BytesObject: decoded,
+	}, nil
+}
+
+// ReadFormFiles reads every file of a multipart form field into memory.
+func ReadFormFiles(e echo.Context, field string) ([]*model.File, error) {
+	form, err := e.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*model.File
+	for _, file := range form.File[field] {
+		src, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		var buffer bytes.Buffer
+		_, err = io.Copy(&buffer, src)
+		src.Close()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, &model.File{
+			FileName:    file.Filename,
+			BytesObject: buffer.Bytes(),
+		})
+	}
+
+	return files, nil
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"errors"
+	"face-recognition-svc/gateway/app/model"
+	"net/http"
+	"testing"
+)
+
+func TestValidateImage(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00\x01")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	webp := []byte("RIFF\x24\x00\x00\x00WEBPVP8 ")
+	gif := []byte("GIF89a\x01\x00\x01\x00")
+
+	tests := []struct {
+		name          string
+		file          *model.File
+		maxBytes      int64
+		wantCode      int
+		wantName      string
+		wantExtension string
+	}{
+		{
+			name:          "jpeg",
+			file:          &model.File{FileName: "face.jpg", BytesObject: jpeg},
+			maxBytes:      1024,
+			wantName:      "face.jpg",
+			wantExtension: "jpg",
+		},
+		{
+			name:          "png without extension gets the sniffed one",
+			file:          &model.File{FileName: "face", BytesObject: png},
+			wantName:      "face.png",
+			wantExtension: "png",
+		},
+		{
+			name:          "webp with a path keeps only the base name",
+			file:          &model.File{FileName: `..\..\etc/face.webp`, BytesObject: webp},
+			wantName:      "face.webp",
+			wantExtension: "webp",
+		},
+		{
+			name:          "sniffing ignores a misleading extension",
+			file:          &model.File{FileName: "face.png", BytesObject: jpeg},
+			wantName:      "face.png",
+			wantExtension: "jpg",
+		},
+		{
+			name:     "empty input",
+			file:     &model.File{FileName: "face.jpg"},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "unsupported type",
+			file:     &model.File{FileName: "face.gif", BytesObject: gif},
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "text is not an image",
+			file:     &model.File{FileName: "face.jpg", BytesObject: []byte("hello")},
+			wantCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:     "over the size cap",
+			file:     &model.File{FileName: "face.jpg", BytesObject: jpeg},
+			maxBytes: int64(len(jpeg)) - 1,
+			wantCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "exactly the size cap",
+			file:          &model.File{FileName: "face.jpg", BytesObject: jpeg},
+			maxBytes:      int64(len(jpeg)),
+			wantName:      "face.jpg",
+			wantExtension: "jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateImage(tt.file, tt.maxBytes)
+
+			if tt.wantCode != 0 {
+				var errResponse *model.ErrorResponse
+				if !errors.As(err, &errResponse) || errResponse.Code != tt.wantCode {
+					t.Fatalf("ValidateImage() error = %v, want code %d", err, tt.wantCode)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ValidateImage() error = %v", err)
+			}
+			if tt.file.FileName != tt.wantName {
+				t.Errorf("FileName = %q, want %q", tt.file.FileName, tt.wantName)
+			}
+			if tt.file.Extension != tt.wantExtension {
+				t.Errorf("Extension = %q, want %q", tt.file.Extension, tt.wantExtension)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"face-recognition-svc/gateway/app/config"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/model"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ErrInvalidMessage marks a message whose envelope or data does not match its schema. Redelivering it cannot help.
+var ErrInvalidMessage = errors.New("invalid message")
+
+//go:embed schema/*.json
+var messageSchemaFiles embed.FS
+
+// messageSchemaID maps each message type to the $id of the schema in schema/ its data must match. Bump the
+// version in the $id whenever a change to a schema is not backwards compatible.
+var messageSchemaID = map[string]string{
+	model.TrainModelMessageFull:        "urn:face-recognition-svc:schema:train.request:1",
+	model.TrainModelMessageIncremental: "urn:face-recognition-svc:schema:train.request:1",
+	model.TrainModelCancelMessage:      "urn:face-recognition-svc:schema:train.cancel:1",
+	model.TrainModelResultMessage:      "urn:face-recognition-svc:schema:train.result:1",
+	model.TrainModelEmbeddingMessage:   "urn:face-recognition-svc:schema:train.embeddings:1",
+	model.ModelActivationMessage:       "urn:face-recognition-svc:schema:model.activation:1",
+	model.RecognitionIdentifyMessage:   "urn:face-recognition-svc:schema:recognition.identify:1",
+	model.RecognitionVerifyMessage:     "urn:face-recognition-svc:schema:recognition.verify:1",
+	model.RecognitionResultMessage:     "urn:face-recognition-svc:schema:recognition.result:1",
+}
+
+var (
+	messageSchemasOnce sync.Once
+	messageSchemas     map[string]*jsonschema.Schema
+	messageSchemasErr  error
+)
+
+func loadMessageSchemas() (map[string]*jsonschema.Schema, error) {
+	messageSchemasOnce.Do(func() {
+		compiler := jsonschema.NewCompiler()
+		compiler.Draft = jsonschema.Draft2020
+		compiler.AssertFormat = true
+
+		files, err := messageSchemaFiles.ReadDir("schema")
+		if err != nil {
+			messageSchemasErr = err
+			return
+		}
+
+		for _, file := range files {
+			data, err := messageSchemaFiles.ReadFile("schema/" + file.Name())
+			if err != nil {
+				messageSchemasErr = err
+				return
+			}
+
+			var header struct {
+				ID string `json:"$id"`
+			}
+			if err := json.Unmarshal(data, &header); err != nil {
+				messageSchemasErr = fmt.Errorf("schema %s: %w", file.Name(), err)
+				return
+			}
+
+			if err := compiler.AddResource(header.ID, bytes.NewReader(data)); err != nil {
+				messageSchemasErr = fmt.Errorf("schema %s: %w", file.Name(), err)
+				return
+			}
+		}
+
+		schemas := make(map[string]*jsonschema.Schema, len(messageSchemaID))
+		for messageType, id := range messageSchemaID {
+			schema, err := compiler.Compile(id)
+			if err != nil {
+				messageSchemasErr = fmt.Errorf("schema %s: %w", id, err)
+				return
+			}
+			schemas[messageType] = schema
+		}
+		messageSchemas = schemas
+	})
+
+	return messageSchemas, messageSchemasErr
+}
+
+// ValidateMessageData checks data against the schema registered for messageType.
+func ValidateMessageData(messageType string, data []byte) error {
+	schemas, err := loadMessageSchemas()
+	if err != nil {
+		return err
+	}
+
+	schema, ok := schemas[messageType]
+	if !ok {
+		return fmt.Errorf("%w: unknown message type %q", ErrInvalidMessage, messageType)
+	}
+
+	// Numbers are decoded as json.Number so integer keywords can tell 1 from 1.5.
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidMessage, messageType, err)
+	}
+
+	if err := schema.Validate(value); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidMessage, messageType, err)
+	}
+
+	return nil
+}
+
+// NewMessage validates data against the schema of messageType and wraps it in a MessageEnvelope, unless
+// messageBus.envelope is turned off; then the body is the bare data. The span in ctx is injected into the message headers so the
+// consumer's span continues the same trace.
+func NewMessage(ctx context.Context, messageType string, institution string, data any) (connection.Message, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return connection.Message{}, err
+	}
+
+	if err := ValidateMessageData(messageType, raw); err != nil {
+		return connection.Message{}, err
+	}
+
+	envelope := &model.MessageEnvelope{
+		SpecVersion:     model.MessageSpecVersion,
+		ID:              uuid.New().String(),
+		Type:            messageType,
+		Source:          model.MessageSource,
+		Time:            time.Now().UTC(),
+		DataContentType: model.MessageDataContentType,
+		DataSchema:      messageSchemaID[messageType],
+		Institution:     institution,
+		Data:            raw,
+	}
+
+	body, contentType := raw, model.MessageDataContentType
+	if cfg := config.GetConfig(); cfg == nil || cfg.MessageBus.Envelope {
+		body, err = json.Marshal(envelope)
+		if err != nil {
+			return connection.Message{}, err
+		}
+		contentType = model.MessageContentType
+	}
+
+	headers := map[string]any{}
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, messageHeadersCarrier(headers)); err != nil {
+			LogEventError(span, err)
+		}
+	}
+
+	return connection.Message{
+		ID:          envelope.ID,
+		Type:        messageType,
+		ContentType: contentType,
+		Timestamp:   envelope.Time,
+		Headers:     headers,
+		Body:        body,
+	}, nil
+}
+
+// ReadMessage opens and validates the envelope of msg, whose type must be one of types. A bare JSON body from a
+// publisher that predates the envelope is read as data of msg.Type, or of the first of types.
+func ReadMessage(msg connection.Message, types ...string) (*model.MessageEnvelope, error) {
+	var envelope model.MessageEnvelope
+	if err := json.Unmarshal(msg.Body, &envelope); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+
+	if envelope.SpecVersion == "" {
+		envelope = model.MessageEnvelope{
+			Type:            msg.Type,
+			Time:            msg.Timestamp,
+			DataContentType: model.MessageDataContentType,
+			Data:            msg.Body,
+		}
+		if envelope.Type == "" && len(types) > 0 {
+			envelope.Type = types[0]
+		}
+	} else if err := validateEnvelope(&envelope); err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(types, envelope.Type) {
+		return nil, fmt.Errorf("%w: unexpected message type %q", ErrInvalidMessage, envelope.Type)
+	}
+
+	if err := ValidateMessageData(envelope.Type, envelope.Data); err != nil {
+		return nil, err
+	}
+
+	return &envelope, nil
+}
+
+func validateEnvelope(envelope *model.MessageEnvelope) error {
+	if envelope.SpecVersion != model.MessageSpecVersion {
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidMessage, envelope.SpecVersion)
+	}
+
+	if envelope.ID == "" || envelope.Type == "" || envelope.Source == "" || envelope.Time.IsZero() {
+		return fmt.Errorf("%w: envelope requires id, type, source and time", ErrInvalidMessage)
+	}
+
+	if envelope.DataContentType != "" && envelope.DataContentType != model.MessageDataContentType {
+		return fmt.Errorf("%w: unsupported datacontenttype %q", ErrInvalidMessage, envelope.DataContentType)
+	}
+
+	if len(envelope.Data) == 0 || string(envelope.Data) == "null" {
+		return fmt.Errorf("%w: envelope has no data", ErrInvalidMessage)
+	}
+
+	return nil
+}
+
+// StartSpanFromMessage starts a consumer span that follows the publisher's span carried in headers, or a new
+// trace when the message has none.
+func StartSpanFromMessage(headers map[string]any, funcDesc string) opentracing.Span {
+	tracer := opentracing.GlobalTracer()
+
+	opts := []opentracing.StartSpanOption{ext.SpanKindConsumer}
+	if spanCtx, err := tracer.Extract(opentracing.TextMap, messageHeadersCarrier(headers)); err == nil {
+		opts = append(opts, opentracing.FollowsFrom(spanCtx))
+	}
+
+	return tracer.StartSpan(funcDesc, opts...)
+}
+
+// messageHeadersCarrier adapts message headers to the OpenTracing text map carrier.
+type messageHeadersCarrier map[string]any
+
+func (c messageHeadersCarrier) Set(key, val string) {
+	c[key] = val
+}
+
+func (c messageHeadersCarrier) ForeachKey(handler func(key, val string) error) error {
+	for key, val := range c {
+		str, ok := val.(string)
+		if !ok {
+			continue
+		}
+		if err := handler(key, str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
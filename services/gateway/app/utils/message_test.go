@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/model"
+)
+
+func TestMessageEnvelopeRoundTrip(t *testing.T) {
+	cancel := model.TrainModelCancel{
+		ID:            "training-1",
+		InstitutionID: "institution-1",
+		RequestedBy:   "alice",
+		RequestedAt:   time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC),
+	}
+
+	msg, err := NewMessage(context.Background(), model.TrainModelCancelMessage, "institution-1", cancel)
+	if err != nil {
+		t.Fatalf("NewMessage() error = %v", err)
+	}
+
+	if msg.ContentType != model.MessageContentType {
+		t.Errorf("ContentType = %q, want %q", msg.ContentType, model.MessageContentType)
+	}
+
+	envelope, err := ReadMessage(msg, model.TrainModelCancelMessage)
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	if envelope.SpecVersion != model.MessageSpecVersion || envelope.Source != model.MessageSource {
+		t.Errorf("specversion, source = %q, %q", envelope.SpecVersion, envelope.Source)
+	}
+	if envelope.ID != msg.ID || envelope.Type != msg.Type {
+		t.Errorf("envelope id, type = %q, %q; message has %q, %q", envelope.ID, envelope.Type, msg.ID, msg.Type)
+	}
+	if envelope.DataSchema != "urn:face-recognition-svc:schema:train.cancel:1" {
+		t.Errorf("DataSchema = %q", envelope.DataSchema)
+	}
+	if envelope.Institution != "institution-1" {
+		t.Errorf("Institution = %q", envelope.Institution)
+	}
+
+	var got model.TrainModelCancel
+	if err := json.Unmarshal(envelope.Data, &got); err != nil {
+		t.Fatalf("unmarshal data: %v", err)
+	}
+	if got != cancel {
+		t.Errorf("data = %+v, want %+v", got, cancel)
+	}
+
+	// A consumer expecting another type must not accept it.
+	if _, err := ReadMessage(msg, model.TrainModelResultMessage); !errors.Is(err, ErrInvalidMessage) {
+		t.Errorf("ReadMessage() with another type error = %v, want ErrInvalidMessage", err)
+	}
+}
+
+func TestReadMessageAcceptsBareData(t *testing.T) {
+	body := []byte(`{"id":"training-1","institution_id":"institution-1","requested_by":"alice","requested_at":"2026-10-18T09:30:00Z"}`)
+
+	envelope, err := ReadMessage(connection.Message{Body: body}, model.TrainModelCancelMessage)
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	if envelope.Type != model.TrainModelCancelMessage || string(envelope.Data) != string(body) {
+		t.Errorf("envelope = %+v", envelope)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"face-recognition-svc/gateway/app/model"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
 	"github.com/microcosm-cc/bluemonday"
@@ -66,9 +68,32 @@ func GetMetadata(c context.Context) (*model.MetadataUser, error) {
 		metaData.InstitutionID = sanitizer(t[0])
 	}
 
+	if t, ok := md["ip_address"]; ok {
+		metaData.IPAddress = sanitizer(t[0])
+	}
+
+	if t, ok := md["user_agent"]; ok {
+		metaData.UserAgent = sanitizer(t[0])
+	}
+
+	if t, ok := md["device_id"]; ok {
+		metaData.DeviceID = sanitizer(t[0])
+	}
+
 	return metaData, nil
 }
 
+// WithPublicSession gives a request of a public route the session GetMetadata reads: it acts for the institution as
+// caller, without a user or roles.
+func WithPublicSession(ctx context.Context, caller string, institutionID string, ipAddress string, userAgent string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.New(map[string]string{
+		"username":       caller,
+		"institution_id": institutionID,
+		"ip_address":     ipAddress,
+		"user_agent":     userAgent,
+	}))
+}
+
 var sanitize = bluemonday.NewPolicy()
 
 func sanitizer(s string) string {
@@ -88,6 +113,33 @@ func sanitizer(s string) string {
 	return sanitize.Sanitize(out)
 }
 
+// RedactedURI hides the token query parameter, which carries the access token of event streams, before a request
+// URI is logged.
+func RedactedURI(uri string) string {
+	u, err := url.ParseRequestURI(uri)
+	if err != nil {
+		return uri
+	}
+
+	query := u.Query()
+	if !query.Has("token") {
+		return uri
+	}
+
+	query.Set("token", "REDACTED")
+	u.RawQuery = query.Encode()
+
+	return u.RequestURI()
+}
+
+// Truncate cuts s to at most n characters, the unit Postgres measures VARCHAR columns in.
+func Truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 func Contains(arr []string, str string) bool {
 	for _, v := range arr {
 		if v == str {
@@ -200,3 +252,14 @@ func BuildOrderByClause(filter *model.Filter, allowedFields map[string]string, d
 
 	return fmt.Sprintf(" ORDER BY %s %s", actualField, sortOrder)
 }
+
+// NewSystemContext returns a context carrying the same session metadata as an authenticated request, for work
+// started by the gateway itself on behalf of an institution.
+func NewSystemContext(ctx context.Context, username string, institutionID string) context.Context {
+	md := metadata.New(map[string]string{
+		"username":       username,
+		"institution_id": institutionID,
+	})
+
+	return metadata.NewIncomingContext(ctx, md)
+}
@@ -0,0 +1,22 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("short", 512); got != "short" {
+		t.Errorf("Truncate(short) = %q", got)
+	}
+
+	long := strings.Repeat("a", 600)
+	if got := Truncate(long, 512); len(got) != 512 {
+		t.Errorf("len(Truncate(600 bytes, 512)) = %d, want 512", len(got))
+	}
+
+	// Multi-byte characters count once, like in a VARCHAR, and are never split.
+	if got := Truncate("ééééé", 3); got != "ééé" {
+		t.Errorf("Truncate(ééééé, 3) = %q, want %q", got, "ééé")
+	}
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/utils"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	consumerMinBackoff = time.Second
+	consumerMaxBackoff = 30 * time.Second
+)
+
+type Handler func(ctx context.Context, delivery *connection.Delivery) error
+
+// Consumer delivers messages of a durable queue to a Handler. Failed messages are requeued once and dropped on the
+// second failure so a poison message cannot block the queue. A message that fails schema validation is dropped at
+// once because redelivering it cannot help. Each message is handled in a span that continues the publisher's trace.
+type Consumer struct {
+	bus     connection.InterfaceMessageBus
+	queue   string
+	handler Handler
+}
+
+func NewConsumer(bus connection.InterfaceMessageBus, queue string, handler Handler) *Consumer {
+	return &Consumer{
+		bus:     bus,
+		queue:   queue,
+		handler: handler,
+	}
+}
+
+// Start consumes the queue in the background until ctx is done. When the queue cannot be declared or consumed, or
+// the broker closes the delivery channel, e.g. on a restart, it subscribes again after a backoff that doubles up to
+// consumerMaxBackoff.
+func (c *Consumer) Start(ctx context.Context) {
+	go c.run(ctx)
+}
+
+func (c *Consumer) run(ctx context.Context) {
+	backoff := consumerMinBackoff
+	for {
+		consumed, err := c.subscribe(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if consumed {
+			backoff = consumerMinBackoff
+		}
+		if err != nil {
+			log.Error().Err(err).Str("queue", c.queue).Dur("retry_in", backoff).Msg("Consumer cannot subscribe")
+		} else {
+			log.Warn().Str("queue", c.queue).Dur("retry_in", backoff).Msg("Consumer channel closed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, consumerMaxBackoff)
+	}
+}
+
+// subscribe declares the queue and handles its messages until the delivery channel closes. It reports whether it
+// got as far as consuming.
+func (c *Consumer) subscribe(ctx context.Context) (bool, error) {
+	err := c.bus.Declare(ctx, connection.Topology{
+		Queues: []connection.Queue{{Name: c.queue}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	deliveries, err := c.bus.Consume(ctx, c.queue)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info().Str("queue", c.queue).Msg("Consumer started")
+
+	c.loop(ctx, deliveries)
+
+	return true, nil
+}
+
+func (c *Consumer) loop(ctx context.Context, deliveries <-chan *connection.Delivery) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case d, ok := <-deliveries:
+			if !ok {
+				return
+			}
+
+			span := utils.StartSpanFromMessage(d.Headers, "Consumer: "+c.queue)
+			err := c.handler(opentracing.ContextWithSpan(ctx, span), d)
+			if err != nil {
+				utils.LogEventError(span, err)
+				span.Finish()
+
+				requeue := !d.Redelivered && !errors.Is(err, utils.ErrInvalidMessage)
+				log.Error().Err(err).Str("queue", c.queue).Bool("redelivered", d.Redelivered).Bool("requeue", requeue).Msg("Message handling failed")
+				d.Nack(requeue)
+				continue
+			}
+
+			span.Finish()
+			d.Ack()
+		}
+	}
+}
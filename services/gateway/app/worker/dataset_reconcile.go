@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/controller"
+
+	"github.com/rs/zerolog/log"
+)
+
+func NewDatasetReconcileTask(datasetController controller.InterfaceDatasetController, repair bool) Task {
+	return func(ctx context.Context) error {
+		report, err := datasetController.RunDatasetReconcile(ctx, repair)
+		if err != nil {
+			return err
+		}
+
+		log.Info().
+			Int("orphan_objects", len(report.OrphanObjects)).
+			Int("missing_objects", len(report.MissingObjects)).
+			Int("stale_rows", len(report.StaleRows)).
+			Int("recent", len(report.Recent)).
+			Int("repaired", len(report.Repaired)).
+			Int("errors", len(report.Errors)).
+			Msg("Dataset reconciliation report")
+
+		return nil
+	}
+}
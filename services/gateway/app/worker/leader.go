@@ -0,0 +1,64 @@
+package worker
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/client"
+	"face-recognition-svc/gateway/app/model"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Leader elects a single gateway instance through a Redis lock. The holder renews the lock on every check, so
+// checks must run more often than ttl; if the holder stops, another instance takes over once the lock expires.
+type Leader struct {
+	lockClient client.InterfaceLockClient
+	key        string
+	owner      string
+	ttl        time.Duration
+	lock       *model.Lock
+}
+
+func NewLeader(lockClient client.InterfaceLockClient, key string, ttl time.Duration) *Leader {
+	hostname, _ := os.Hostname()
+
+	return &Leader{
+		lockClient: lockClient,
+		key:        key,
+		owner:      fmt.Sprintf("%s-%s", hostname, uuid.New().String()),
+		ttl:        ttl,
+	}
+}
+
+// IsLeader renews leadership if this instance holds it, otherwise tries to acquire it. It is not safe for
+// concurrent use; each Leader should be checked from a single task.
+func (l *Leader) IsLeader(ctx context.Context) (bool, error) {
+	if l.lock != nil {
+		extended, err := l.lockClient.Extend(ctx, l.key, l.lock.Token, l.ttl)
+		if err != nil {
+			return false, err
+		}
+		if extended {
+			return true, nil
+		}
+
+		log.Warn().Str("key", l.key).Msg("Leadership lost")
+		l.lock = nil
+	}
+
+	lock, err := l.lockClient.Acquire(ctx, l.key, l.owner, l.ttl)
+	if err != nil {
+		return false, err
+	}
+	if lock == nil {
+		return false, nil
+	}
+
+	log.Info().Str("key", l.key).Str("owner", l.owner).Msg("Leadership acquired")
+	l.lock = lock
+
+	return true, nil
+}
@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/controller"
+
+	"github.com/rs/zerolog/log"
+)
+
+func NewOutboxRelayTask(outboxController controller.InterfaceOutboxController) Task {
+	return func(ctx context.Context) error {
+		result, err := outboxController.Relay(ctx)
+		if err != nil {
+			return err
+		}
+
+		if result.Retried > 0 || result.Failed > 0 {
+			log.Warn().
+				Int("sent", result.Sent).
+				Int("retried", result.Retried).
+				Int("failed", result.Failed).
+				Msg("Outbox relay could not publish every message")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/controller"
+
+	"github.com/rs/zerolog/log"
+)
+
+func NewRecognitionReviewRetentionTask(reviewController controller.InterfaceRecognitionReviewController) Task {
+	return func(ctx context.Context) error {
+		purged, err := reviewController.PurgeExpiredReviews(ctx)
+		if err != nil {
+			return err
+		}
+
+		if purged > 0 {
+			log.Info().Int("purged", purged).Msg("Purged expired recognition reviews")
+		}
+
+		return nil
+	}
+}
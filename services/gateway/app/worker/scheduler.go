@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+type Task func(ctx context.Context) error
+
+type scheduledTask struct {
+	name     string
+	interval time.Duration
+	run      Task
+}
+
+// Scheduler runs registered tasks in the background at a fixed interval.
+type Scheduler struct {
+	tasks []*scheduledTask
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+func (s *Scheduler) Every(name string, interval time.Duration, task Task) {
+	s.tasks = append(s.tasks, &scheduledTask{
+		name:     name,
+		interval: interval,
+		run:      task,
+	})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, task := range s.tasks {
+		go s.loop(ctx, task)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, task *scheduledTask) {
+	log.Info().Str("task", task.name).Str("interval", task.interval.String()).Msg("Scheduled task registered")
+
+	ticker := time.NewTicker(task.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			if err := task.run(ctx); err != nil {
+				log.Error().Err(err).Str("task", task.name).Msg("Scheduled task failed")
+				continue
+			}
+			log.Debug().Str("task", task.name).Dur("duration", time.Since(start)).Msg("Scheduled task finished")
+		}
+	}
+}
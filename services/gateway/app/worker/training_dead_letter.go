@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+)
+
+func NewTrainingDeadLetterHandler(datasetController controller.InterfaceDatasetController) Handler {
+	return func(ctx context.Context, delivery *connection.Delivery) error {
+		envelope, err := utils.ReadMessage(delivery.Message, model.TrainModelMessageFull, model.TrainModelMessageIncremental)
+		if err != nil {
+			return err
+		}
+
+		var request model.RequestAPITrainModel
+		if err := json.Unmarshal(envelope.Data, &request); err != nil {
+			return err
+		}
+
+		reason, _ := delivery.Headers["x-first-death-reason"].(string)
+
+		// Payload is the request without its envelope; a retry or replay wraps it in a new one.
+		return datasetController.HandleDeadLetteredTraining(ctx, &model.DeadLetteredTraining{
+			Payload:    envelope.Data,
+			Request:    &request,
+			Reason:     reason,
+			RetryCount: retryCount(delivery.Headers),
+		})
+	}
+}
+
+func retryCount(headers map[string]any) int {
+	switch v := headers[model.TrainModelRetryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
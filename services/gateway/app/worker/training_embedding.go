@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+)
+
+const TrainingEmbeddingQueue = "TrainModelEmbedding"
+
+func NewTrainingEmbeddingHandler(embeddingController controller.InterfaceFaceEmbeddingController) Handler {
+	return func(ctx context.Context, delivery *connection.Delivery) error {
+		envelope, err := utils.ReadMessage(delivery.Message, model.TrainModelEmbeddingMessage)
+		if err != nil {
+			return err
+		}
+
+		var embeddings model.TrainModelEmbeddings
+		if err := json.Unmarshal(envelope.Data, &embeddings); err != nil {
+			return err
+		}
+
+		return embeddingController.HandleEmbeddings(ctx, &embeddings)
+	}
+}
@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/controller"
+
+	"github.com/rs/zerolog/log"
+)
+
+func NewTrainingReaperTask(datasetController controller.InterfaceDatasetController) Task {
+	return func(ctx context.Context) error {
+		reaped, err := datasetController.ReapStaleTrainings(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(reaped) > 0 {
+			log.Warn().Strs("training_ids", reaped).Msg("Failed trainings without a report from the worker")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"face-recognition-svc/gateway/app/connection"
+	"face-recognition-svc/gateway/app/controller"
+	"face-recognition-svc/gateway/app/model"
+	"face-recognition-svc/gateway/app/utils"
+)
+
+const TrainingResultQueue = "TrainModelResult"
+
+func NewTrainingResultHandler(datasetController controller.InterfaceDatasetController) Handler {
+	return func(ctx context.Context, delivery *connection.Delivery) error {
+		envelope, err := utils.ReadMessage(delivery.Message, model.TrainModelResultMessage)
+		if err != nil {
+			return err
+		}
+
+		var result model.TrainModelResult
+		if err := json.Unmarshal(envelope.Data, &result); err != nil {
+			return err
+		}
+
+		return datasetController.HandleTrainingResult(ctx, &result)
+	}
+}
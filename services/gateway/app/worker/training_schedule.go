@@ -0,0 +1,41 @@
+package worker
+
+import (
+	"context"
+	"face-recognition-svc/gateway/app/controller"
+
+	"github.com/rs/zerolog/log"
+)
+
+const TrainingScheduleLeaderKey = "training:scheduler:leader"
+
+// NewTrainingScheduleTask evaluates training schedules on the elected instance only, so a cron slot starts a
+// single training however many gateways are running.
+func NewTrainingScheduleTask(leader *Leader, scheduleController controller.InterfaceTrainingScheduleController) Task {
+	return func(ctx context.Context) error {
+		isLeader, err := leader.IsLeader(ctx)
+		if err != nil || !isLeader {
+			return err
+		}
+
+		runs, err := scheduleController.EvaluateSchedules(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, run := range runs {
+			event := log.Info()
+			if run.Error != "" {
+				event = log.Warn().Str("error", run.Error)
+			}
+			event.
+				Str("institution_id", run.InstitutionID).
+				Str("trigger", run.Trigger).
+				Str("training_id", run.TrainingID).
+				Bool("coalesced", run.Coalesced).
+				Msg("Scheduled training triggered")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,6 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_enrollment_session_updated_at ON enrollment_session;
+DROP TABLE IF EXISTS enrollment_capture;
+DROP TABLE IF EXISTS enrollment_session;
+-- +goose StatementEnd
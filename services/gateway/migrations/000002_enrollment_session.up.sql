@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS enrollment_session (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    institution_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    username VARCHAR(150) NOT NULL,
+    status VARCHAR(30) NOT NULL DEFAULT 'OPEN',
+    required_count INT NOT NULL DEFAULT 5,
+    required_frontal INT NOT NULL DEFAULT 3,
+    required_left INT NOT NULL DEFAULT 1,
+    required_right INT NOT NULL DEFAULT 1,
+    require_glasses_off BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at TIMESTAMP DEFAULT NULL,
+    completed_at TIMESTAMP DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255) DEFAULT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT fk_enrollment_session_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_enrollment_session_user FOREIGN KEY (user_id) REFERENCES "user"(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_enrollment_session_status CHECK (status IN ('OPEN', 'COMPLETED', 'CANCELLED', 'EXPIRED'))
+);
+
+CREATE TABLE IF NOT EXISTS enrollment_capture (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    session_id UUID NOT NULL,
+    pose VARCHAR(20) NOT NULL,
+    glasses_off BOOLEAN NOT NULL DEFAULT FALSE,
+    file_name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT fk_enrollment_capture_session FOREIGN KEY (session_id) REFERENCES enrollment_session(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_enrollment_capture_pose CHECK (pose IN ('frontal', 'left', 'right'))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_enrollment_session_open ON enrollment_session(institution_id, user_id) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_enrollment_session_institution_status ON enrollment_session(institution_id, status);
+CREATE INDEX IF NOT EXISTS idx_enrollment_capture_session ON enrollment_capture(session_id);
+
+CREATE TRIGGER update_enrollment_session_updated_at
+    BEFORE UPDATE ON enrollment_session
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS model_activation;
+DROP INDEX IF EXISTS uq_model_training_active;
+DROP INDEX IF EXISTS uq_model_training_version;
+ALTER TABLE model_training DROP COLUMN IF EXISTS activated_by;
+ALTER TABLE model_training DROP COLUMN IF EXISTS activated_at;
+ALTER TABLE model_training DROP COLUMN IF EXISTS metadata;
+ALTER TABLE model_training DROP COLUMN IF EXISTS model_path;
+ALTER TABLE model_training DROP COLUMN IF EXISTS version;
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS version INT DEFAULT NULL;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS model_path VARCHAR(500) DEFAULT NULL;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS metadata JSONB DEFAULT NULL;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS activated_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS activated_by VARCHAR(255) DEFAULT NULL;
+
+UPDATE model_training mt
+SET version = v.version
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY institution_id ORDER BY created_at) AS version
+    FROM model_training
+) v
+WHERE mt.id = v.id AND mt.version IS NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uq_model_training_version ON model_training(institution_id, version);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_model_training_active ON model_training(institution_id) WHERE is_used = 'Y';
+
+CREATE TABLE IF NOT EXISTS model_activation (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    institution_id UUID NOT NULL,
+    model_training_id UUID NOT NULL,
+    previous_model_training_id UUID DEFAULT NULL,
+    action VARCHAR(20) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT chk_model_activation_action CHECK (action IN ('ACTIVATE', 'ROLLBACK'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_activation_institution ON model_activation(institution_id, created_at DESC);
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_model_training_institution_status;
+ALTER TABLE model_training DROP COLUMN IF EXISTS status_message;
+ALTER TABLE model_training DROP COLUMN IF EXISTS finished_at;
+ALTER TABLE model_training DROP COLUMN IF EXISTS started_at;
+-- +goose StatementEnd
@@ -0,0 +1,8 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS started_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS status_message TEXT DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_model_training_institution_status ON model_training(institution_id, status);
+-- +goose StatementEnd
@@ -0,0 +1,4 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS fencing_token BIGINT DEFAULT NULL;
+-- +goose StatementEnd
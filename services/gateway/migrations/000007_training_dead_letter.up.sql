@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS training_dead_letter (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    model_training_id VARCHAR(100) NOT NULL,
+    institution_id VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    reason VARCHAR(100) DEFAULT NULL,
+    retry_count INT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP DEFAULT NULL,
+    resolved_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT chk_training_dead_letter_status CHECK (status IN ('PENDING', 'REPLAYED', 'DISCARDED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_training_dead_letter_status ON training_dead_letter(status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_training_dead_letter_training ON training_dead_letter(model_training_id);
+-- +goose StatementEnd
@@ -0,0 +1,5 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_training_schedule_updated_at ON training_schedule;
+DROP TABLE IF EXISTS training_schedule;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS training_schedule (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    institution_id UUID NOT NULL,
+    cron_expr VARCHAR(100) DEFAULT NULL,
+    min_new_images INT DEFAULT NULL,
+    min_new_users INT DEFAULT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_run_at TIMESTAMP DEFAULT NULL,
+    last_triggered_at TIMESTAMP DEFAULT NULL,
+    last_training_id VARCHAR(100) DEFAULT NULL,
+    last_error TEXT DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255) DEFAULT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT uq_training_schedule_institution UNIQUE (institution_id),
+    CONSTRAINT fk_training_schedule_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_training_schedule_trigger CHECK (cron_expr IS NOT NULL OR min_new_images IS NOT NULL OR min_new_users IS NOT NULL)
+);
+
+CREATE TRIGGER update_training_schedule_updated_at
+    BEFORE UPDATE ON training_schedule
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
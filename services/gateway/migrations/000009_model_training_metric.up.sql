@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS model_training_metric (
+    model_training_id VARCHAR(255) PRIMARY KEY,
+    identities INT DEFAULT NULL,
+    images INT DEFAULT NULL,
+    validation_accuracy NUMERIC(6, 5) DEFAULT NULL,
+    thresholds JSONB NOT NULL DEFAULT '[]'::jsonb,
+    duration_seconds NUMERIC(12, 3) DEFAULT NULL,
+    artifact_size_bytes BIGINT DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_model_training_metric_training FOREIGN KEY (model_training_id) REFERENCES model_training(id) ON DELETE CASCADE,
+    CONSTRAINT chk_model_training_metric_accuracy CHECK (validation_accuracy IS NULL OR (validation_accuracy >= 0 AND validation_accuracy <= 1))
+);
+
+CREATE INDEX IF NOT EXISTS idx_model_training_metric_accuracy ON model_training_metric(validation_accuracy DESC);
+-- +goose StatementEnd
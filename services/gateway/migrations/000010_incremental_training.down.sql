@@ -0,0 +1,8 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS model_training_manifest;
+
+ALTER TABLE model_training DROP CONSTRAINT IF EXISTS chk_model_training_mode;
+ALTER TABLE model_training DROP COLUMN IF EXISTS base_model_id;
+ALTER TABLE model_training DROP COLUMN IF EXISTS training_mode;
+-- +goose StatementEnd
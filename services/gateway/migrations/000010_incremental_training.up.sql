@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS training_mode VARCHAR(20) NOT NULL DEFAULT 'full';
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS base_model_id VARCHAR(255) DEFAULT NULL;
+ALTER TABLE model_training DROP CONSTRAINT IF EXISTS chk_model_training_mode;
+ALTER TABLE model_training ADD CONSTRAINT chk_model_training_mode CHECK (training_mode IN ('full', 'incremental'));
+
+CREATE TABLE IF NOT EXISTS model_training_manifest (
+    model_training_id VARCHAR(255) PRIMARY KEY,
+    objects JSONB NOT NULL DEFAULT '[]'::jsonb,
+    object_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_model_training_manifest_training FOREIGN KEY (model_training_id) REFERENCES model_training(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
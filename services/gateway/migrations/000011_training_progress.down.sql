@@ -0,0 +1,4 @@
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE model_training DROP COLUMN IF EXISTS progress;
+-- +goose StatementEnd
@@ -0,0 +1,4 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS progress NUMERIC(5, 2) DEFAULT NULL;
+-- +goose StatementEnd
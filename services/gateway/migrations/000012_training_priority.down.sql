@@ -0,0 +1,11 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_model_training_queue;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS claimed_until;
+
+ALTER TABLE model_training DROP COLUMN IF EXISTS dispatched_at;
+ALTER TABLE model_training DROP COLUMN IF EXISTS priority;
+
+ALTER TABLE institution DROP COLUMN IF EXISTS training_tier;
+-- +goose StatementEnd
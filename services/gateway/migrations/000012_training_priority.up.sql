@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE institution ADD COLUMN IF NOT EXISTS training_tier VARCHAR(20) NOT NULL DEFAULT 'standard';
+
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS dispatched_at TIMESTAMP DEFAULT NULL;
+
+-- The relay claims the messages it publishes, so it does not hold row locks while talking to the broker.
+ALTER TABLE outbox ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMP DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_model_training_queue ON model_training(status, priority DESC, created_at) WHERE status IN ('STARTED', 'RUNNING', 'CANCELLING');
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recognition_event (
+    id UUID PRIMARY KEY,
+    institution_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    caller VARCHAR(255) NOT NULL,
+    device_id VARCHAR(255) DEFAULT NULL,
+    claimed_user VARCHAR(255) DEFAULT NULL,
+    matched_user VARCHAR(255) DEFAULT NULL,
+    score DOUBLE PRECISION DEFAULT NULL,
+    model_id VARCHAR(255) DEFAULT NULL,
+    model_version INT DEFAULT NULL,
+    latency_ms INT NOT NULL DEFAULT 0,
+    decision VARCHAR(20) NOT NULL,
+    probe_path VARCHAR(1024) DEFAULT NULL,
+    message TEXT DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_recognition_event_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE,
+    CONSTRAINT chk_recognition_event_kind CHECK (kind IN ('IDENTIFY', 'VERIFY')),
+    CONSTRAINT chk_recognition_event_decision CHECK (decision IN ('MATCH', 'NO_MATCH', 'ERROR'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_recognition_event_institution_time ON recognition_event(institution_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_recognition_event_matched_user ON recognition_event(institution_id, matched_user, created_at DESC) WHERE matched_user IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_recognition_event_device ON recognition_event(institution_id, device_id, created_at DESC) WHERE device_id IS NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,7 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_recognition_event_batch;
+
+ALTER TABLE recognition_event DROP COLUMN IF EXISTS face_box;
+ALTER TABLE recognition_event DROP COLUMN IF EXISTS batch_id;
+-- +goose StatementEnd
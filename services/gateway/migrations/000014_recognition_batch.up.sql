@@ -0,0 +1,7 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE recognition_event ADD COLUMN IF NOT EXISTS batch_id UUID DEFAULT NULL;
+ALTER TABLE recognition_event ADD COLUMN IF NOT EXISTS face_box JSONB DEFAULT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_recognition_event_batch ON recognition_event(batch_id) WHERE batch_id IS NOT NULL;
+-- +goose StatementEnd
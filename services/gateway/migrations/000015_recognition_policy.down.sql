@@ -0,0 +1,5 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_recognition_policy_updated_at ON recognition_policy;
+DROP TABLE IF EXISTS recognition_policy;
+-- +goose StatementEnd
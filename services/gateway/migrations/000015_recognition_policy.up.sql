@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recognition_policy (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    institution_id UUID NOT NULL,
+    match_threshold DOUBLE PRECISION NOT NULL,
+    top_k INT NOT NULL,
+    min_margin DOUBLE PRECISION NOT NULL DEFAULT 0,
+    max_model_age_days INT DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255) DEFAULT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT uq_recognition_policy_institution UNIQUE (institution_id),
+    CONSTRAINT fk_recognition_policy_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_recognition_policy_threshold CHECK (match_threshold > 0 AND match_threshold <= 1),
+    CONSTRAINT chk_recognition_policy_top_k CHECK (top_k BETWEEN 1 AND 100),
+    CONSTRAINT chk_recognition_policy_margin CHECK (min_margin >= 0 AND min_margin < 1),
+    CONSTRAINT chk_recognition_policy_model_age CHECK (max_model_age_days IS NULL OR max_model_age_days > 0)
+);
+
+CREATE TRIGGER update_recognition_policy_updated_at
+    BEFORE UPDATE ON recognition_policy
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS recognition_review (
+    id UUID PRIMARY KEY,
+    institution_id UUID NOT NULL,
+    event_id UUID DEFAULT NULL,
+    kind VARCHAR(20) NOT NULL,
+    device_id VARCHAR(255) DEFAULT NULL,
+    claimed_user VARCHAR(255) DEFAULT NULL,
+    best_candidate VARCHAR(255) DEFAULT NULL,
+    best_score DOUBLE PRECISION DEFAULT NULL,
+    probe_path VARCHAR(1024) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    labeled_user VARCHAR(255) DEFAULT NULL,
+    note TEXT DEFAULT NULL,
+    decided_at TIMESTAMP DEFAULT NULL,
+    decided_by VARCHAR(255) DEFAULT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_recognition_review_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE,
+    CONSTRAINT fk_recognition_review_event FOREIGN KEY (event_id) REFERENCES recognition_event(id) ON DELETE SET NULL,
+    CONSTRAINT chk_recognition_review_status CHECK (status IN ('PENDING', 'LABELED', 'DISCARDED', 'IMPOSTOR'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_recognition_review_queue ON recognition_review(institution_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_recognition_review_expires ON recognition_review(expires_at);
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS face_embedding (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    institution_id UUID NOT NULL,
+    model_training_id VARCHAR(255) NOT NULL,
+    model_version INT NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    image_key VARCHAR(500) NOT NULL,
+    dimension INT NOT NULL,
+    embedding REAL[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uq_face_embedding_image UNIQUE (model_training_id, username, image_key),
+    CONSTRAINT fk_face_embedding_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_face_embedding_model FOREIGN KEY (model_training_id) REFERENCES model_training(id) ON DELETE CASCADE,
+    CONSTRAINT chk_face_embedding_dimension CHECK (dimension > 0 AND cardinality(embedding) = dimension)
+);
+
+CREATE INDEX IF NOT EXISTS idx_face_embedding_model ON face_embedding(model_training_id, username);
+CREATE INDEX IF NOT EXISTS idx_face_embedding_version ON face_embedding(institution_id, model_version);
+-- +goose StatementEnd
@@ -0,0 +1,5 @@
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS update_device_updated_at ON device;
+DROP TABLE IF EXISTS device;
+-- +goose StatementEnd
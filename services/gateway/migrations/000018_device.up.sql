@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS device (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    institution_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    location VARCHAR(255) DEFAULT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE',
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    key_rotated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_heartbeat_at TIMESTAMP DEFAULT NULL,
+    last_ip_address VARCHAR(64) DEFAULT NULL,
+    metadata JSONB DEFAULT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_by VARCHAR(255) DEFAULT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_by VARCHAR(255) DEFAULT NULL,
+    CONSTRAINT uq_device_key_prefix UNIQUE (key_prefix),
+    CONSTRAINT uq_device_name UNIQUE (institution_id, name),
+    CONSTRAINT fk_device_institution FOREIGN KEY (institution_id) REFERENCES institution(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT chk_device_status CHECK (status IN ('ACTIVE', 'DISABLED'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_institution ON device(institution_id, name);
+
+CREATE TRIGGER update_device_updated_at
+    BEFORE UPDATE ON device
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+-- +goose StatementEnd
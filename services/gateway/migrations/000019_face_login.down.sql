@@ -0,0 +1,4 @@
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM feature WHERE feature_key = 'face_login';
+-- +goose StatementEnd
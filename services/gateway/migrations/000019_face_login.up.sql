@@ -0,0 +1,6 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO feature (feature_key, name, description, feature_type, default_enabled)
+VALUES ('face_login', 'Face Login', 'Users of the institution may sign in with a photo of their face', 'system', FALSE)
+ON CONFLICT (feature_key) DO NOTHING;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Down
+-- +goose StatementBegin
+DO $$
+DECLARE
+    idx TEXT;
+BEGIN
+    FOR idx IN SELECT indexname FROM pg_indexes WHERE tablename = 'face_embedding' AND indexname LIKE 'idx_face_embedding_vector_%' LOOP
+        EXECUTE format('DROP INDEX IF EXISTS %I', idx);
+    END LOOP;
+END $$;
+
+ALTER TABLE face_embedding DROP COLUMN IF EXISTS embedding_vector;
+ALTER TABLE model_training DROP COLUMN IF EXISTS embedding_dimension;
+-- +goose StatementEnd
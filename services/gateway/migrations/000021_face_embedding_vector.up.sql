@@ -0,0 +1,65 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE model_training ADD COLUMN IF NOT EXISTS embedding_dimension INT DEFAULT NULL;
+
+-- A training keeps the dimension of its first stored embeddings. Vectors of any other dimension are left in place
+-- and skipped by searches; POST /api/service/recognition/embedding/cleanup reports them and, with repair, deletes them.
+UPDATE model_training t
+SET embedding_dimension = e.dimension
+FROM (
+    SELECT DISTINCT ON (model_training_id) model_training_id, dimension
+    FROM face_embedding
+    ORDER BY model_training_id, created_at, id
+) e
+WHERE t.id = e.model_training_id;
+
+-- Incremental trainings only reported embeddings of new and changed images; give them their base model's
+-- templates of the images they kept, oldest training first so a chain of incremental trainings is complete.
+DO $$
+DECLARE
+    t RECORD;
+BEGIN
+    FOR t IN
+        SELECT id, version, base_model_id, embedding_dimension
+        FROM model_training
+        WHERE training_mode = 'incremental' AND base_model_id IS NOT NULL AND embedding_dimension IS NOT NULL
+        ORDER BY created_at
+    LOOP
+        WITH current_objects AS (
+            SELECT o->>'key' AS key, o->>'etag' AS etag
+            FROM model_training_manifest m, jsonb_array_elements(m.objects) o
+            WHERE m.model_training_id = t.id
+        ), base_objects AS (
+            SELECT o->>'key' AS key, o->>'etag' AS etag
+            FROM model_training_manifest m, jsonb_array_elements(m.objects) o
+            WHERE m.model_training_id = t.base_model_id
+        )
+        INSERT INTO face_embedding (id, institution_id, model_training_id, model_version, username, image_key, dimension, embedding, created_at)
+        SELECT gen_random_uuid(), e.institution_id, t.id, t.version, e.username, e.image_key, e.dimension, e.embedding, CURRENT_TIMESTAMP
+        FROM face_embedding e
+        JOIN current_objects co ON co.key = e.image_key
+        LEFT JOIN base_objects bo ON bo.key = e.image_key
+        WHERE e.model_training_id = t.base_model_id AND e.dimension = t.embedding_dimension AND (bo.key IS NULL OR bo.etag = co.etag)
+        ON CONFLICT (model_training_id, username, image_key) DO NOTHING;
+    END LOOP;
+END $$;
+
+-- pgvector is optional. Where it is installed, vectors are also kept as a vector column with an HNSW index per
+-- dimension; the gateway adds the index of a new dimension when its first embeddings arrive.
+DO $$
+DECLARE
+    dim INT;
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'vector') THEN
+        CREATE EXTENSION IF NOT EXISTS vector;
+        ALTER TABLE face_embedding ADD COLUMN IF NOT EXISTS embedding_vector vector;
+        UPDATE face_embedding SET embedding_vector = CAST(embedding AS vector);
+
+        FOR dim IN SELECT DISTINCT dimension FROM face_embedding WHERE dimension <= 2000 LOOP
+            EXECUTE format(
+                'CREATE INDEX IF NOT EXISTS idx_face_embedding_vector_%s ON face_embedding USING hnsw ((CAST(embedding_vector AS vector(%s))) vector_ip_ops) WHERE dimension = %s',
+                dim, dim, dim);
+        END LOOP;
+    END IF;
+END $$;
+-- +goose StatementEnd
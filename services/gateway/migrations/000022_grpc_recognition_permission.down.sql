@@ -0,0 +1,4 @@
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM permission WHERE name = 'gateway.recognition.identify';
+-- +goose StatementEnd
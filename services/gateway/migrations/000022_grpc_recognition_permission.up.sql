@@ -0,0 +1,15 @@
+-- +goose Up
+-- +goose StatementBegin
+INSERT INTO permission (name, service, resource, action, description)
+VALUES ('gateway.recognition.identify', 'gateway', 'recognition', 'identify', 'Call the gRPC recognition API with a user token')
+ON CONFLICT (name) DO NOTHING;
+
+-- Only system roles and institution administrator roles start with the permission; administrators grant it to
+-- other roles like any other permission. Kiosks authenticated with a device key do not need it.
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id
+FROM role r, permission p
+WHERE p.name = 'gateway.recognition.identify'
+AND (r.scope = 'system' OR r.is_administrator)
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+-- +goose StatementEnd